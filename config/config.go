@@ -10,13 +10,18 @@ import (
 
 // Config 配置结构体
 type Config struct {
-	Database DatabaseConfig `yaml:"database"`
-	Redis    RedisConfig    `yaml:"redis"`
-	JWT      JWTConfig      `yaml:"jwt"`
-	Server   ServerConfig   `yaml:"server"`
-	Upload   UploadConfig   `yaml:"upload"`
-	MinIO    MinIOConfig    `yaml:"minio"`
-	App      AppConfig      `yaml:"app"`
+	Database  DatabaseConfig  `yaml:"database"`
+	Redis     RedisConfig     `yaml:"redis"`
+	JWT       JWTConfig       `yaml:"jwt"`
+	Server    ServerConfig    `yaml:"server"`
+	Upload    UploadConfig    `yaml:"upload"`
+	MinIO     MinIOConfig     `yaml:"minio"`
+	App       AppConfig       `yaml:"app"`
+	LLM       LLMConfig       `yaml:"llm"`
+	Knowledge KnowledgeConfig `yaml:"knowledge"`
+	Mail      MailConfig      `yaml:"mail"`
+	Security  SecurityConfig  `yaml:"security"`
+	OIDC      OIDCConfig      `yaml:"oidc"`
 }
 
 // DatabaseConfig 数据库配置
@@ -45,16 +50,19 @@ type RedisConfig struct {
 
 // JWTConfig JWT配置
 type JWTConfig struct {
-	Secret      string `yaml:"secret"`
-	ExpireHours int    `yaml:"expire_hours"`
+	Secret             string `yaml:"secret"`
+	ExpireHours        int    `yaml:"expire_hours"`         // 兼容旧配置，未设置access_token_minutes时作为访问令牌有效时间
+	AccessTokenMinutes int    `yaml:"access_token_minutes"` // 访问令牌有效时间（分钟）
+	RefreshTokenDays   int    `yaml:"refresh_token_days"`   // 刷新令牌有效时间（天），每次刷新后重新计算
 }
 
 // ServerConfig 服务器配置
 type ServerConfig struct {
-	Port      int        `yaml:"port"`
-	Mode      string     `yaml:"mode"`
-	PprofPort int        `yaml:"pprof_port"`
-	CORS      CORSConfig `yaml:"cors"`
+	Port           int        `yaml:"port"`
+	Mode           string     `yaml:"mode"`
+	PprofPort      int        `yaml:"pprof_port"`
+	TrustedProxies []string   `yaml:"trusted_proxies"` // 可信反向代理的IP或网段，为空时不信任任何代理
+	CORS           CORSConfig `yaml:"cors"`
 }
 
 // CORSConfig CORS配置
@@ -88,6 +96,99 @@ type AppConfig struct {
 	Name        string `yaml:"name"`
 	Version     string `yaml:"version"`
 	Description string `yaml:"description"`
+	// FrontendURL 前端地址，用于生成邮件中的链接
+	FrontendURL string `yaml:"frontend_url"`
+	// PublicBaseURL 后端对外访问地址，用于生成对话窗口脚本、托管对话页与嵌入代码，为空时使用本机地址
+	PublicBaseURL     string `yaml:"public_base_url"`
+	InviteExpireHours int    `yaml:"invite_expire_hours"` // 成员邀请有效时间（小时）
+}
+
+// LLMConfig 大模型配置
+type LLMConfig struct {
+	DefaultProvider string              `yaml:"default_provider"`
+	TimeoutSeconds  int                 `yaml:"timeout_seconds"` // 非流式请求超时时间（秒）
+	Providers       []LLMProviderConfig `yaml:"providers"`
+}
+
+// LLMProviderConfig 大模型服务商配置
+type LLMProviderConfig struct {
+	Name           string   `yaml:"name"`
+	Type           string   `yaml:"type"` // openai, mock
+	BaseURL        string   `yaml:"base_url"`
+	APIKey         string   `yaml:"api_key"`
+	Models         []string `yaml:"models"`
+	DefaultModel   string   `yaml:"default_model"`
+	EmbeddingModel string   `yaml:"embedding_model"`
+}
+
+// KnowledgeConfig 知识库配置
+type KnowledgeConfig struct {
+	IngestWorkers  int            `yaml:"ingest_workers"`   // 文档解析并发数
+	ChunkSize      int            `yaml:"chunk_size"`       // 切片长度（字符数）
+	ChunkOverlap   int            `yaml:"chunk_overlap"`    // 相邻切片重叠的字符数
+	EmbedBatchSize int            `yaml:"embed_batch_size"` // 每次向量化请求的切片数
+	MaxDecodedSize int64          `yaml:"max_decoded_size"` // PDF解压后流数据的总大小上限（字节）
+	FAQMatch       FAQMatchConfig `yaml:"faq_match"`
+}
+
+// FAQMatchConfig 常见问答直接匹配配置
+type FAQMatchConfig struct {
+	Threshold          float64 `yaml:"threshold"`           // 文本相似度阈值
+	UseEmbedding       bool    `yaml:"use_embedding"`       // 文本未命中时是否使用向量相似度匹配
+	EmbeddingThreshold float64 `yaml:"embedding_threshold"` // 向量相似度阈值
+}
+
+// MailConfig 邮件配置
+type MailConfig struct {
+	Driver   string `yaml:"driver"` // smtp, log（仅记录日志，不实际发送，只能在debug模式下使用）
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	From     string `yaml:"from"`
+}
+
+// SecurityConfig 安全配置
+type SecurityConfig struct {
+	LoginGuard        LoginGuardConfig     `yaml:"login_guard"`
+	PasswordPolicy    PasswordPolicyConfig `yaml:"password_policy"`
+	ResetTokenMinutes int                  `yaml:"reset_token_minutes"` // 重置密码链接有效时间（分钟）
+	TwoFactorRoles    []uint8              `yaml:"two_factor_roles"`    // 默认要求启用双因素认证的角色，管理员可在运行时修改
+}
+
+// PasswordPolicyConfig 密码策略，注册、修改与重置密码时校验
+type PasswordPolicyConfig struct {
+	MinLength        int    `yaml:"min_length"`
+	RequireUpper     bool   `yaml:"require_upper"`      // 需要大写字母
+	RequireLower     bool   `yaml:"require_lower"`      // 需要小写字母
+	RequireDigit     bool   `yaml:"require_digit"`      // 需要数字
+	RequireSymbol    bool   `yaml:"require_symbol"`     // 需要特殊字符
+	BreachedListFile string `yaml:"breached_list_file"` // 已泄露密码列表，每行一个明文密码或SHA-1摘要
+}
+
+// LoginGuardConfig 登录防暴力破解配置，失败次数在统计窗口内累计
+type LoginGuardConfig struct {
+	MaxFailures    int `yaml:"max_failures"`    // 同一用户名连续失败该次数后锁定账号
+	IPMaxFailures  int `yaml:"ip_max_failures"` // 同一IP失败该次数后暂时禁止其登录
+	WindowMinutes  int `yaml:"window_minutes"`  // 失败次数统计窗口（分钟）
+	LockoutMinutes int `yaml:"lockout_minutes"` // 锁定时长（分钟）
+	BaseDelay      int `yaml:"base_delay"`      // 首次失败后的等待秒数，之后每次失败翻倍
+	MaxDelay       int `yaml:"max_delay"`       // 等待秒数上限
+}
+
+// OIDCConfig OIDC单点登录配置（授权码模式 + PKCE）
+type OIDCConfig struct {
+	Enabled      bool     `yaml:"enabled"`
+	Issuer       string   `yaml:"issuer"` // 身份提供方地址，从 <issuer>/.well-known/openid-configuration 获取端点
+	ClientID     string   `yaml:"client_id"`
+	ClientSecret string   `yaml:"client_secret"` // 为空时作为公共客户端，仅依赖PKCE
+	RedirectURL  string   `yaml:"redirect_url"`  // 登录完成后身份提供方回调的前端页面
+	Scopes       []string `yaml:"scopes"`
+	GroupsClaim  string   `yaml:"groups_claim"` // ID令牌中用户组的字段名，默认groups
+	// GroupRoles 用户组与角色的映射，用户属于多个组时取权限最高的角色
+	GroupRoles    map[string]uint8 `yaml:"group_roles"`
+	DefaultRole   uint8            `yaml:"default_role"`   // 未匹配任何用户组时新用户的角色
+	AutoProvision bool             `yaml:"auto_provision"` // 邮箱未注册时是否自动创建用户
 }
 
 // GlobalConfig 全局配置实例
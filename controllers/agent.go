@@ -1,66 +1,147 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
-	"strconv"
+	"strings"
 
 	"ai-assistant-backend/config"
 	"ai-assistant-backend/models"
+	"ai-assistant-backend/services"
 	"ai-assistant-backend/utils"
 
 	"github.com/gin-gonic/gin"
 )
 
 type CreateAgentRequest struct {
-	AppId          string   `json:"app_id" binding:"required"`
-	Name           string   `json:"name" binding:"required"`
-	Logo           string   `json:"logo"`
-	WelcomeMsg     string   `json:"welcome_msg"`
-	CarouselImages []string `json:"carousel_images"`
+	AppId             string   `json:"app_id" binding:"required"`
+	Name              string   `json:"name" binding:"required"`
+	Logo              string   `json:"logo"`
+	WelcomeMsg        string   `json:"welcome_msg"`
+	CarouselImages    []string `json:"carousel_images"`
+	AllowedDomains    []string `json:"allowed_domains" binding:"max=50"`
+	ThemeColor        string   `json:"theme_color" binding:"omitempty,hexcolor"`
+	ThemeTextColor    string   `json:"theme_text_color" binding:"omitempty,hexcolor"`
+	WidgetPosition    string   `json:"widget_position" binding:"omitempty,oneof=bottom-right bottom-left"`
+	RetrievalTopK     int      `json:"retrieval_top_k" binding:"omitempty,min=1,max=20"`
+	RetrievalMinScore *float64 `json:"retrieval_min_score" binding:"omitempty,min=0,max=1"`
+	NoAnswerMsg       string   `json:"no_answer_msg"`
+	Model             string   `json:"model"`
+	SystemPrompt      string   `json:"system_prompt" binding:"max=4000"`
+	Persona           string   `json:"persona" binding:"max=1000"`
+	Temperature       *float64 `json:"temperature" binding:"omitempty,min=0,max=2"`
+	MaxTokens         int      `json:"max_tokens" binding:"omitempty,min=1,max=32000"`
 }
 
 type UpdateAgentRequest struct {
-	Name           string   `json:"name"`
-	Logo           string   `json:"logo"`
-	WelcomeMsg     string   `json:"welcome_msg"`
-	CarouselImages []string `json:"carousel_images"`
+	Name              string   `json:"name"`
+	Logo              string   `json:"logo"`
+	WelcomeMsg        string   `json:"welcome_msg"`
+	CarouselImages    []string `json:"carousel_images"`
+	AllowedDomains    []string `json:"allowed_domains" binding:"max=50"`
+	ThemeColor        *string  `json:"theme_color" binding:"omitempty,hexcolor"`
+	ThemeTextColor    *string  `json:"theme_text_color" binding:"omitempty,hexcolor"`
+	WidgetPosition    *string  `json:"widget_position" binding:"omitempty,oneof=bottom-right bottom-left"`
+	RetrievalTopK     *int     `json:"retrieval_top_k" binding:"omitempty,min=1,max=20"`
+	RetrievalMinScore *float64 `json:"retrieval_min_score" binding:"omitempty,min=0,max=1"`
+	NoAnswerMsg       *string  `json:"no_answer_msg"`
+	Model             *string  `json:"model"`
+	SystemPrompt      *string  `json:"system_prompt" binding:"omitempty,max=4000"`
+	Persona           *string  `json:"persona" binding:"omitempty,max=1000"`
+	Temperature       *float64 `json:"temperature" binding:"omitempty,min=0,max=2"`
+	ClearTemperature  bool     `json:"clear_temperature"` // 清除采样温度，改为使用服务商默认值
+	MaxTokens         *int     `json:"max_tokens" binding:"omitempty,min=0,max=32000"`
+
+	// 知识范围，传空数组表示使用全部文档或常见问答
+	DocumentCategoryIDs []uint `json:"document_category_ids" binding:"max=100"`
+	FAQCategoryIDs      []uint `json:"faq_category_ids" binding:"max=100"`
 }
 
-// GetAgents 获取智能体列表
+type CloneAgentRequest struct {
+	AppID string `json:"app_id" binding:"max=64"`
+	Name  string `json:"name" binding:"max=100"`
+}
+
+type PreviewAgentPromptRequest struct {
+	Question string `json:"question" binding:"required"`
+}
+
+// validateAgentModel 校验模型是否已在大模型服务商中配置，为空表示使用默认模型
+func validateAgentModel(c *gin.Context, model string) bool {
+	if model == "" {
+		return true
+	}
+	if _, _, err := utils.ResolveLLMModel(model); err != nil {
+		utils.BadRequest(c, "模型未配置: "+model)
+		return false
+	}
+	return true
+}
+
+// normalizeAllowedDomains 校验并去重允许接入的域名，失败时已写入响应
+func normalizeAllowedDomains(c *gin.Context, domains []string) ([]string, bool) {
+	normalized := []string{}
+	seen := map[string]bool{}
+	for _, domain := range domains {
+		value, err := utils.NormalizeAllowedDomain(domain)
+		if err != nil {
+			utils.BadRequest(c, err.Error())
+			return nil, false
+		}
+		if !seen[value] {
+			seen[value] = true
+			normalized = append(normalized, value)
+		}
+	}
+	return normalized, true
+}
+
+// validateCategoryIDs 校验分类均属于智能体并去重，model为文档分类或问答分类，失败时已写入响应
+func validateCategoryIDs(c *gin.Context, model interface{}, agentID uint, ids []uint, resource string) ([]uint, bool) {
+	normalized := []uint{}
+	seen := map[uint]bool{}
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			normalized = append(normalized, id)
+		}
+	}
+	if len(normalized) == 0 {
+		return normalized, true
+	}
+
+	var count int64
+	config.DB.Model(model).Where("agent_id = ? AND id IN ?", agentID, normalized).Count(&count)
+	if int(count) != len(normalized) {
+		utils.BadRequest(c, resource+"不存在或不属于该智能体")
+		return nil, false
+	}
+	return normalized, true
+}
+
+// GetAgents 获取当前工作空间的智能体列表
 func GetAgents(c *gin.Context) {
-	user, err := utils.GetUserFromContext(c)
+	workspace, err := utils.GetWorkspaceFromContext(c)
 	if err != nil {
-		utils.Unauthorized(c, "用户未登录")
+		utils.Forbidden(c, "未选择工作空间")
 		return
 	}
 	var agents []models.Agent
-	if err := config.DB.Where("user_id = ?", user.UserID).Find(&agents).Error; err != nil {
+	if err := config.DB.Where("workspace_id = ?", workspace.WorkspaceID).Find(&agents).Error; err != nil {
 		utils.GetFailed(c, "智能体列表")
 		return
 	}
+	for i := range agents {
+		agents[i].Link = services.AgentChatLink(agents[i].AppID)
+	}
 	utils.Success(c, agents, "获取成功")
 }
 
 // GetAgent 获取单个智能体
 func GetAgent(c *gin.Context) {
-	id := c.Param("id")
-	agentID, err := strconv.ParseUint(id, 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"code":    400,
-			"message": "无效的智能体ID",
-		})
-		return
-	}
-
-	var agent models.Agent
-	if err := config.DB.First(&agent, agentID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"code":    404,
-			"message": "智能体不存在",
-		})
-		return
-	}
+	agent := currentAgent(c)
+	// 体验链接随对外访问地址的配置变化
+	agent.Link = services.AgentChatLink(agent.AppID)
 
 	// 加载轮播图
 	var carouselImages []models.AgentCarouselImage
@@ -72,6 +153,9 @@ func GetAgent(c *gin.Context) {
 	}
 	agent.CarouselImages = imageURLs
 
+	// 加载自助服务
+	agent.SelfServices = loadSelfServices(agent.ID)
+
 	c.JSON(http.StatusOK, gin.H{
 		"code":    200,
 		"message": "获取成功",
@@ -86,6 +170,11 @@ func CreateAgent(c *gin.Context) {
 		utils.Unauthorized(c, "用户未登录")
 		return
 	}
+	workspace, err := utils.GetWorkspaceFromContext(c)
+	if err != nil {
+		utils.Forbidden(c, "未选择工作空间")
+		return
+	}
 
 	var req CreateAgentRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -97,14 +186,45 @@ func CreateAgent(c *gin.Context) {
 		return
 	}
 
+	if !validateAgentModel(c, req.Model) {
+		return
+	}
+	allowedDomains, ok := normalizeAllowedDomains(c, req.AllowedDomains)
+	if !ok {
+		return
+	}
+
+	var count int64
+	if err := config.DB.Model(&models.Agent{}).Where("app_id = ?", req.AppId).Count(&count).Error; err != nil {
+		utils.CreateFailed(c, "智能体")
+		return
+	}
+	if count > 0 {
+		utils.BadRequest(c, services.ErrAppIDExists.Error())
+		return
+	}
+
 	// 创建智能体
 	agent := models.Agent{
-		AppID:      req.AppId,
-		UserID:     user.UserID,
-		Name:       req.Name,
-		Logo:       req.Logo,
-		WelcomeMsg: req.WelcomeMsg,
-		Status:     "offline",
+		AppID:          req.AppId,
+		UserID:         user.UserID,
+		WorkspaceID:    workspace.WorkspaceID,
+		Name:           req.Name,
+		Logo:           req.Logo,
+		WelcomeMsg:     req.WelcomeMsg,
+		Status:         "offline",
+		Link:           services.AgentChatLink(req.AppId),
+		AllowedDomains: allowedDomains,
+		ThemeColor:     strings.ToLower(req.ThemeColor),
+		ThemeTextColor: strings.ToLower(req.ThemeTextColor),
+		WidgetPosition: req.WidgetPosition,
+		RetrievalTopK:  req.RetrievalTopK,
+		NoAnswerMsg:    req.NoAnswerMsg,
+		Model:          req.Model,
+		SystemPrompt:   req.SystemPrompt,
+		Persona:        req.Persona,
+		Temperature:    req.Temperature,
+		MaxTokens:      req.MaxTokens,
 	}
 
 	if err := config.DB.Create(&agent).Error; err != nil {
@@ -115,9 +235,11 @@ func CreateAgent(c *gin.Context) {
 		return
 	}
 
-	// 生成链接
-	agent.Link = "https://example.com/agent/" + strconv.FormatUint(uint64(agent.ID), 10)
-	config.DB.Model(&agent).Update("link", agent.Link)
+	// 相似度阈值为零值时创建会使用默认值，需单独保存
+	if req.RetrievalMinScore != nil {
+		agent.RetrievalMinScore = *req.RetrievalMinScore
+		config.DB.Model(&agent).Update("retrieval_min_score", agent.RetrievalMinScore)
+	}
 
 	// 保存轮播图
 	for i, imageURL := range req.CarouselImages {
@@ -136,18 +258,8 @@ func CreateAgent(c *gin.Context) {
 	})
 }
 
-// UpdateAgent 更新智能体
+// UpdateAgent 更新智能体。提示词、欢迎语、轮播图与知识范围的修改保存为草稿，发布后才对访客生效
 func UpdateAgent(c *gin.Context) {
-	id := c.Param("id")
-	agentID, err := strconv.ParseUint(id, 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"code":    400,
-			"message": "无效的智能体ID",
-		})
-		return
-	}
-
 	var req UpdateAgentRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
@@ -158,14 +270,7 @@ func UpdateAgent(c *gin.Context) {
 		return
 	}
 
-	var agent models.Agent
-	if err := config.DB.First(&agent, agentID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"code":    404,
-			"message": "智能体不存在",
-		})
-		return
-	}
+	agent := currentAgent(c)
 
 	// 更新智能体信息
 	updates := make(map[string]interface{})
@@ -178,8 +283,71 @@ func UpdateAgent(c *gin.Context) {
 	if req.WelcomeMsg != "" {
 		updates["welcome_msg"] = req.WelcomeMsg
 	}
+	if req.ThemeColor != nil && *req.ThemeColor != "" {
+		updates["theme_color"] = strings.ToLower(*req.ThemeColor)
+	}
+	if req.ThemeTextColor != nil && *req.ThemeTextColor != "" {
+		updates["theme_text_color"] = strings.ToLower(*req.ThemeTextColor)
+	}
+	if req.WidgetPosition != nil && *req.WidgetPosition != "" {
+		updates["widget_position"] = *req.WidgetPosition
+	}
+	if req.RetrievalTopK != nil {
+		updates["retrieval_top_k"] = *req.RetrievalTopK
+	}
+	if req.RetrievalMinScore != nil {
+		updates["retrieval_min_score"] = *req.RetrievalMinScore
+	}
+	if req.NoAnswerMsg != nil {
+		updates["no_answer_msg"] = *req.NoAnswerMsg
+	}
+	if req.Model != nil {
+		if !validateAgentModel(c, *req.Model) {
+			return
+		}
+		updates["model"] = *req.Model
+	}
+	if req.SystemPrompt != nil {
+		updates["system_prompt"] = *req.SystemPrompt
+	}
+	if req.Persona != nil {
+		updates["persona"] = *req.Persona
+	}
+	if req.ClearTemperature {
+		if req.Temperature != nil {
+			utils.ValidationError(c, "temperature与clear_temperature不能同时设置")
+			return
+		}
+		updates["temperature"] = nil
+	} else if req.Temperature != nil {
+		updates["temperature"] = *req.Temperature
+	}
+	if req.MaxTokens != nil {
+		updates["max_tokens"] = *req.MaxTokens
+	}
 
-	if err := config.DB.Model(&agent).Updates(updates).Error; err != nil {
+	var allowedDomains []string
+	if req.AllowedDomains != nil {
+		var ok bool
+		if allowedDomains, ok = normalizeAllowedDomains(c, req.AllowedDomains); !ok {
+			return
+		}
+	}
+	var documentCategoryIDs, faqCategoryIDs []uint
+	if req.DocumentCategoryIDs != nil {
+		var ok bool
+		if documentCategoryIDs, ok = validateCategoryIDs(c, &models.DocumentCategory{}, agent.ID, req.DocumentCategoryIDs, "文档分类"); !ok {
+			return
+		}
+	}
+	if req.FAQCategoryIDs != nil {
+		var ok bool
+		if faqCategoryIDs, ok = validateCategoryIDs(c, &models.FAQCategory{}, agent.ID, req.FAQCategoryIDs, "问答分类"); !ok {
+			return
+		}
+	}
+
+	if err := config.DB.Model(agent).Updates(updates).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"code":    500,
 			"message": "更新智能体失败",
@@ -187,6 +355,27 @@ func UpdateAgent(c *gin.Context) {
 		return
 	}
 
+	// 允许接入的域名与知识范围为JSON字段，按结构体更新以使用序列化
+	var jsonFields []string
+	if req.AllowedDomains != nil {
+		agent.AllowedDomains = allowedDomains
+		jsonFields = append(jsonFields, "allowed_domains")
+	}
+	if req.DocumentCategoryIDs != nil {
+		agent.DocumentCategoryIDs = documentCategoryIDs
+		jsonFields = append(jsonFields, "document_category_ids")
+	}
+	if req.FAQCategoryIDs != nil {
+		agent.FAQCategoryIDs = faqCategoryIDs
+		jsonFields = append(jsonFields, "faq_category_ids")
+	}
+	if len(jsonFields) > 0 {
+		if err := config.DB.Model(agent).Select(jsonFields).Updates(agent).Error; err != nil {
+			utils.UpdateFailed(c, "智能体")
+			return
+		}
+	}
+
 	// 更新轮播图
 	if req.CarouselImages != nil {
 		// 删除旧的轮播图
@@ -212,24 +401,7 @@ func UpdateAgent(c *gin.Context) {
 
 // ToggleAgentStatus 切换智能体状态
 func ToggleAgentStatus(c *gin.Context) {
-	id := c.Param("id")
-	agentID, err := strconv.ParseUint(id, 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"code":    400,
-			"message": "无效的智能体ID",
-		})
-		return
-	}
-
-	var agent models.Agent
-	if err := config.DB.First(&agent, agentID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"code":    404,
-			"message": "智能体不存在",
-		})
-		return
-	}
+	agent := currentAgent(c)
 
 	// 切换状态
 	newStatus := "offline"
@@ -237,7 +409,13 @@ func ToggleAgentStatus(c *gin.Context) {
 		newStatus = "online"
 	}
 
-	if err := config.DB.Model(&agent).Update("status", newStatus).Error; err != nil {
+	// 上线后访客使用发布版本的配置，尚未发布时不能上线
+	if newStatus == "online" && agent.PublishedVersion == 0 {
+		utils.BadRequest(c, "请先发布智能体再上线")
+		return
+	}
+
+	if err := config.DB.Model(agent).Update("status", newStatus).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"code":    500,
 			"message": "更新状态失败",
@@ -257,30 +435,10 @@ func ToggleAgentStatus(c *gin.Context) {
 
 // DeleteAgent 删除智能体
 func DeleteAgent(c *gin.Context) {
-	id := c.Param("id")
-	agentID, err := strconv.ParseUint(id, 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"code":    400,
-			"message": "无效的智能体ID",
-		})
-		return
-	}
-
-	var agent models.Agent
-	if err := config.DB.First(&agent, agentID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"code":    404,
-			"message": "智能体不存在",
-		})
-		return
-	}
-
-	// 删除相关的轮播图
-	config.DB.Where("agent_id = ?", agent.ID).Delete(&models.AgentCarouselImage{})
+	agent := currentAgent(c)
 
-	// 删除智能体
-	if err := config.DB.Delete(&agent).Error; err != nil {
+	// 删除智能体及其知识库、发布版本与对话记录
+	if err := services.DeleteAgent(c.Request.Context(), agent); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"code":    500,
 			"message": "删除智能体失败",
@@ -293,3 +451,76 @@ func DeleteAgent(c *gin.Context) {
 		"message": "删除成功",
 	})
 }
+
+// CloneAgent 复制智能体及其知识库，app_id为空时自动生成，name为空时使用「原名称（副本）」
+func CloneAgent(c *gin.Context) {
+	user, err := utils.GetUserFromContext(c)
+	if err != nil {
+		utils.Unauthorized(c, "用户未登录")
+		return
+	}
+	source := currentAgent(c)
+
+	var req CloneAgentRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.ValidationError(c, err.Error())
+			return
+		}
+	}
+
+	appID := strings.TrimSpace(req.AppID)
+	if appID == "" {
+		suffix, err := utils.GenerateRandomString(6)
+		if err != nil {
+			utils.CreateFailed(c, "智能体")
+			return
+		}
+		appID = source.AppID + "-" + suffix
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		name = source.Name + "（副本）"
+	}
+
+	agent, err := services.CloneAgent(source, user.UserID, appID, name)
+	if errors.Is(err, services.ErrAppIDExists) {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+	if err != nil {
+		utils.InternalServerError(c, "复制智能体失败: "+err.Error())
+		return
+	}
+
+	utils.Success(c, agent, "复制成功")
+}
+
+// GetAgentModels 获取可供智能体选择的模型列表
+func GetAgentModels(c *gin.Context) {
+	utils.Success(c, utils.ListLLMModels(), "获取成功")
+}
+
+// PreviewAgentPrompt 预览智能体回答指定问题时组装的系统提示词与检索到的资料
+func PreviewAgentPrompt(c *gin.Context) {
+	agent := currentAgent(c)
+
+	var req PreviewAgentPromptRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(c, err.Error())
+		return
+	}
+
+	preview, err := services.PreviewPrompt(c.Request.Context(), agent, req.Question)
+	if err != nil {
+		utils.InternalServerError(c, "预览提示词失败: "+err.Error())
+		return
+	}
+	utils.Success(c, preview, "获取成功")
+}
+
+// currentAgent 获取权限中间件加载到上下文中的智能体
+func currentAgent(c *gin.Context) *models.Agent {
+	agent, _ := utils.GetAgentFromContext(c)
+	return agent
+}
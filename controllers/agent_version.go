@@ -0,0 +1,180 @@
+package controllers
+
+import (
+	"errors"
+	"strconv"
+
+	"ai-assistant-backend/models"
+	"ai-assistant-backend/services"
+	"ai-assistant-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PublishAgentRequest struct {
+	Note string `json:"note" binding:"max=255"`
+}
+
+// GetAgentVersions 获取智能体的发布记录与草稿状态
+func GetAgentVersions(c *gin.Context) {
+	agent := currentAgent(c)
+
+	versions, err := services.ListAgentVersions(agent.ID)
+	if err != nil {
+		utils.GetFailed(c, "版本列表")
+		return
+	}
+	hasDraftChanges, err := services.HasDraftChanges(agent)
+	if err != nil {
+		utils.GetFailed(c, "版本列表")
+		return
+	}
+
+	utils.Success(c, gin.H{
+		"published_version": agent.PublishedVersion,
+		"has_draft_changes": hasDraftChanges,
+		"versions":          versions,
+	}, "获取成功")
+}
+
+// GetAgentVersion 获取指定版本的配置快照
+func GetAgentVersion(c *gin.Context) {
+	agent := currentAgent(c)
+
+	number, err := strconv.Atoi(c.Param("version"))
+	if err != nil || number <= 0 {
+		utils.BadRequest(c, "无效的版本号")
+		return
+	}
+	version, err := services.GetAgentVersion(agent.ID, number)
+	if err != nil {
+		agentVersionError(c, err, "版本")
+		return
+	}
+	utils.Success(c, version, "获取成功")
+}
+
+// DiffAgentVersions 比较两个版本的配置，from与to可为版本号、draft（草稿）或published（当前发布版本），
+// 默认比较当前发布版本与草稿
+func DiffAgentVersions(c *gin.Context) {
+	agent := currentAgent(c)
+
+	from, ok := resolveAgentSnapshot(c, agent, c.DefaultQuery("from", "published"))
+	if !ok {
+		return
+	}
+	to, ok := resolveAgentSnapshot(c, agent, c.DefaultQuery("to", "draft"))
+	if !ok {
+		return
+	}
+
+	changes, err := services.DiffSnapshots(from, to)
+	if err != nil {
+		utils.InternalServerError(c, "比较版本失败")
+		return
+	}
+	utils.Success(c, gin.H{
+		"from":    c.DefaultQuery("from", "published"),
+		"to":      c.DefaultQuery("to", "draft"),
+		"changes": changes,
+	}, "获取成功")
+}
+
+// PublishAgent 将草稿发布为新版本
+func PublishAgent(c *gin.Context) {
+	agent := currentAgent(c)
+	user, err := utils.GetUserFromContext(c)
+	if err != nil {
+		utils.Unauthorized(c, "用户未登录")
+		return
+	}
+
+	var req PublishAgentRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.ValidationError(c, err.Error())
+			return
+		}
+	}
+
+	version, err := services.PublishAgent(agent, user.UserID, req.Note)
+	if err != nil {
+		agentVersionError(c, err, "发布")
+		return
+	}
+	utils.Success(c, version, "发布成功")
+}
+
+// RollbackAgentVersion 回滚到指定版本：该版本重新发布为新版本，草稿同时恢复为该版本的配置
+func RollbackAgentVersion(c *gin.Context) {
+	agent := currentAgent(c)
+	user, err := utils.GetUserFromContext(c)
+	if err != nil {
+		utils.Unauthorized(c, "用户未登录")
+		return
+	}
+
+	number, err := strconv.Atoi(c.Param("version"))
+	if err != nil || number <= 0 {
+		utils.BadRequest(c, "无效的版本号")
+		return
+	}
+
+	var req PublishAgentRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.ValidationError(c, err.Error())
+			return
+		}
+	}
+
+	version, err := services.RollbackAgent(agent, number, user.UserID, req.Note)
+	if err != nil {
+		agentVersionError(c, err, "回滚")
+		return
+	}
+	utils.Success(c, version, "回滚成功")
+}
+
+// resolveAgentSnapshot 按版本号、draft或published获取配置快照，失败时已写入响应
+func resolveAgentSnapshot(c *gin.Context, agent *models.Agent, ref string) (*models.AgentSnapshot, bool) {
+	switch ref {
+	case "draft":
+		snapshot, err := services.DraftSnapshot(agent)
+		if err != nil {
+			utils.GetFailed(c, "草稿")
+			return nil, false
+		}
+		return snapshot, true
+	case "published":
+		if agent.PublishedVersion == 0 {
+			utils.BadRequest(c, services.ErrAgentNotPublished.Error())
+			return nil, false
+		}
+		ref = strconv.Itoa(agent.PublishedVersion)
+	}
+
+	number, err := strconv.Atoi(ref)
+	if err != nil || number <= 0 {
+		utils.BadRequest(c, "版本需为版本号、draft或published")
+		return nil, false
+	}
+	version, err := services.GetAgentVersion(agent.ID, number)
+	if err != nil {
+		agentVersionError(c, err, "版本")
+		return nil, false
+	}
+	return &version.Snapshot, true
+}
+
+// agentVersionError 将版本相关的错误转换为响应
+func agentVersionError(c *gin.Context, err error, action string) {
+	switch {
+	case errors.Is(err, services.ErrAgentVersionNotFound):
+		utils.NotFound(c, err.Error())
+	case errors.Is(err, services.ErrNoDraftChanges), errors.Is(err, services.ErrAgentNotPublished):
+		utils.BadRequest(c, err.Error())
+	default:
+		utils.InternalServerError(c, action+"失败")
+	}
+}
@@ -0,0 +1,143 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"ai-assistant-backend/config"
+	"ai-assistant-backend/models"
+	"ai-assistant-backend/services"
+	"ai-assistant-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxAPIKeyExpireDays API密钥的最长有效期（天）
+const maxAPIKeyExpireDays = 365
+
+type CreateAPIKeyRequest struct {
+	Name   string   `json:"name" binding:"required,max=100"`
+	Scopes []string `json:"scopes" binding:"required,min=1"`
+	// ExpiresInDays 有效天数，为0或不传表示永不过期
+	ExpiresInDays int `json:"expires_in_days" binding:"min=0"`
+}
+
+// GetAPIKeys 获取当前工作空间的API密钥，有成员管理权限时返回全部成员的密钥，否则只返回自己的密钥
+func GetAPIKeys(c *gin.Context) {
+	claims, err := utils.GetUserFromContext(c)
+	if err != nil {
+		utils.Unauthorized(c, "用户未登录")
+		return
+	}
+	member, _ := utils.GetWorkspaceFromContext(c)
+
+	query := config.DB.Where("workspace_id = ?", member.WorkspaceID)
+	if !canManageAPIKeys(claims, member) {
+		query = query.Where("user_id = ?", claims.UserID)
+	}
+
+	var keys []models.APIKey
+	if err := query.Order("id DESC").Find(&keys).Error; err != nil {
+		utils.GetFailed(c, "API密钥列表")
+		return
+	}
+
+	utils.Success(c, gin.H{
+		"api_keys": keys,
+		"scopes":   utils.APIKeyScopes,
+	}, "获取成功")
+}
+
+// CreateAPIKey 创建API密钥，明文密钥只在本次响应中返回
+func CreateAPIKey(c *gin.Context) {
+	claims, err := utils.GetUserFromContext(c)
+	if err != nil {
+		utils.Unauthorized(c, "用户未登录")
+		return
+	}
+	member, _ := utils.GetWorkspaceFromContext(c)
+
+	var req CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(c, err.Error())
+		return
+	}
+	if req.ExpiresInDays > maxAPIKeyExpireDays {
+		utils.BadRequest(c, "有效期不能超过"+strconv.Itoa(maxAPIKeyExpireDays)+"天")
+		return
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, claims.UserID).Error; err != nil {
+		utils.UserNotFound(c)
+		return
+	}
+
+	var expiresAt *time.Time
+	if req.ExpiresInDays > 0 {
+		value := time.Now().AddDate(0, 0, req.ExpiresInDays)
+		expiresAt = &value
+	}
+
+	apiKey, key, err := services.CreateAPIKey(member, &user, req.Name, req.Scopes, expiresAt)
+	var scopeErr *services.APIKeyScopeError
+	if errors.As(err, &scopeErr) {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+	if err != nil {
+		log.Printf("创建API密钥失败: %v", err)
+		utils.CreateFailed(c, "API密钥")
+		return
+	}
+
+	utils.Success(c, gin.H{
+		"api_key": apiKey,
+		"key":     key,
+	}, "API密钥已创建，请立即保存，之后将无法再次查看")
+}
+
+// DeleteAPIKey 撤销API密钥，只能撤销自己的密钥，有成员管理权限时可撤销工作空间内的任意密钥
+func DeleteAPIKey(c *gin.Context) {
+	claims, err := utils.GetUserFromContext(c)
+	if err != nil {
+		utils.Unauthorized(c, "用户未登录")
+		return
+	}
+	member, _ := utils.GetWorkspaceFromContext(c)
+
+	keyID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.InvalidID(c, "API密钥")
+		return
+	}
+
+	var apiKey models.APIKey
+	if err := config.DB.Where("id = ? AND workspace_id = ?", keyID, member.WorkspaceID).First(&apiKey).Error; err != nil {
+		utils.NotFound(c, "API密钥不存在")
+		return
+	}
+	if apiKey.UserID != claims.UserID && !canManageAPIKeys(claims, member) {
+		utils.Forbidden(c, "只能撤销自己创建的API密钥")
+		return
+	}
+
+	if err := config.DB.Delete(&apiKey).Error; err != nil {
+		utils.DeleteFailed(c, "API密钥")
+		return
+	}
+
+	utils.SuccessWithMessage(c, "API密钥已撤销")
+}
+
+// canManageAPIKeys 超级管理员或拥有成员管理权限的成员可以管理工作空间内全部API密钥
+func canManageAPIKeys(claims *utils.Claims, member *models.WorkspaceMember) bool {
+	var roles []uint8
+	config.DB.Model(&models.User{}).Where("id = ?", claims.UserID).Limit(1).Pluck("role", &roles)
+	if len(roles) > 0 && roles[0] == models.RoleSuperAdmin {
+		return true
+	}
+	return utils.HasPermission(member.Role, utils.PermMembersManage)
+}
@@ -1,14 +1,16 @@
 package controllers
 
 import (
-	"context"
-	"fmt"
+	"errors"
+	"log"
 
 	"ai-assistant-backend/config"
 	"ai-assistant-backend/models"
+	"ai-assistant-backend/services"
 	"ai-assistant-backend/utils"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 type LoginRequest struct {
@@ -16,10 +18,35 @@ type LoginRequest struct {
 	Password string `json:"password" binding:"required"`
 }
 
+type LoginTwoFactorRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code" binding:"required"`
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
 type RegisterRequest struct {
 	Username string `json:"username" binding:"required"`
 	Password string `json:"password" binding:"required"`
 	Email    string `json:"email" binding:"required,email"`
+	// InviteToken 邀请令牌，注册后直接加入邀请的工作空间
+	InviteToken string `json:"invite_token"`
 }
 
 // Login 用户登录
@@ -30,50 +57,143 @@ func Login(c *gin.Context) {
 		return
 	}
 
-	// 查找用户
+	// 失败次数过多时暂时禁止登录
+	ctx := c.Request.Context()
+	if err := services.CheckLoginAllowed(ctx, req.Username, c.ClientIP()); err != nil {
+		loginBlocked(c, err)
+		return
+	}
+
+	// 查找用户并验证密码，用户不存在同样计入失败次数
 	var user models.User
-	if err := config.DB.Where("username = ?", req.Username).First(&user).Error; err != nil {
+	err := config.DB.Where("username = ?", req.Username).First(&user).Error
+	if err != nil || !utils.CheckPassword(req.Password, user.Password) {
+		if err := services.RecordLoginFailure(ctx, req.Username, c.ClientIP()); err != nil {
+			loginBlocked(c, err)
+			return
+		}
 		utils.LoginFailed(c)
 		return
 	}
 
-	// 验证密码
-	if !utils.CheckPassword(req.Password, user.Password) {
-		utils.LoginFailed(c)
+	// 已启用双因素认证时先返回验证令牌，验证码校验通过后才签发访问令牌
+	if user.TOTPEnabled {
+		twoFactorChallenge(c, &user)
 		return
 	}
 
-	// 生成JWT token并存储到Redis
-	token, err := utils.GenerateToken(user.ID, user.Username, user.Role)
+	services.ResetLoginFailures(ctx, req.Username)
+	issueLogin(c, &user)
+}
+
+// twoFactorChallenge 返回登录第二步使用的验证令牌
+func twoFactorChallenge(c *gin.Context, user *models.User) {
+	challengeToken, err := services.CreateLoginChallenge(c.Request.Context(), user)
 	if err != nil {
-		utils.InternalServerError(c, "生成令牌失败")
+		log.Printf("创建登录验证失败: %v", err)
+		utils.InternalServerError(c, "登录失败")
+		return
+	}
+	utils.Success(c, gin.H{
+		"two_factor_required": true,
+		"challenge_token":     challengeToken,
+	}, "请输入双因素认证验证码")
+}
+
+// LoginTwoFactor 登录第二步，使用验证器应用的验证码或恢复码完成登录
+func LoginTwoFactor(c *gin.Context) {
+	var req LoginTwoFactorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(c, err.Error())
+		return
+	}
+
+	// 校验前检查登录限制，防止借助第二步暴力猜测验证码
+	ctx := c.Request.Context()
+	user, err := services.LoginChallengeUser(ctx, req.ChallengeToken)
+	if err != nil {
+		utils.Unauthorized(c, services.ErrLoginChallengeInvalid.Error())
+		return
+	}
+	if err := services.CheckLoginAllowed(ctx, user.Username, c.ClientIP()); err != nil {
+		loginBlocked(c, err)
+		return
+	}
+
+	user, err = services.CompleteLoginChallenge(ctx, req.ChallengeToken, req.Code)
+	switch {
+	case errors.Is(err, services.ErrInvalidTwoFactorCode):
+		// 验证码错误同样计入登录失败次数
+		if err := services.RecordLoginFailure(ctx, user.Username, c.ClientIP()); err != nil {
+			loginBlocked(c, err)
+			return
+		}
+		utils.BadRequest(c, services.ErrInvalidTwoFactorCode.Error())
+		return
+	case errors.Is(err, services.ErrLoginChallengeInvalid):
+		utils.Unauthorized(c, err.Error())
+		return
+	case err != nil:
+		log.Printf("双因素认证登录失败: %v", err)
+		utils.InternalServerError(c, "登录失败")
+		return
+	}
+
+	services.ResetLoginFailures(ctx, user.Username)
+	issueLogin(c, user)
+}
+
+// issueLogin 创建登录会话并返回访问令牌与刷新令牌
+func issueLogin(c *gin.Context, user *models.User) {
+	// 默认进入最早加入的工作空间
+	workspaceID, _ := services.DefaultWorkspaceID(user.ID)
+
+	session, refreshToken, err := utils.CreateSession(user.ID, workspaceID, c.Request.UserAgent(), c.ClientIP())
+	if err != nil {
+		utils.InternalServerError(c, "保存会话失败")
 		return
 	}
-	//将token存到redis
-	err = config.RedisClient.Set(context.Background(), fmt.Sprintf("token:%s", token), user.ID, 0).Err()
+	token, err := utils.GenerateToken(user.ID, user.Username, user.Role, workspaceID, session.ID)
 	if err != nil {
-		utils.InternalServerError(c, "保存令牌失败")
+		utils.InternalServerError(c, "生成令牌失败")
 		return
 	}
 
 	utils.Success(c, gin.H{
-		"token": token,
+		"token":         token,
+		"refresh_token": refreshToken,
+		"expires_in":    int(utils.AccessTokenLifetime().Seconds()),
 		"user": gin.H{
-			"id":       user.ID,
-			"username": user.Username,
-			"email":    user.Email,
-			"avatar":   user.Avatar,
-			"role":     user.Role,
+			"id":           user.ID,
+			"username":     user.Username,
+			"email":        user.Email,
+			"avatar":       user.Avatar,
+			"role":         user.Role,
+			"totp_enabled": user.TOTPEnabled,
 		},
+		"workspace_id": workspaceID,
 	}, "登录成功")
 }
 
-// Logout 用户登出
+// loginBlocked 登录被限制时返回429
+func loginBlocked(c *gin.Context, err error) {
+	var blocked *services.LoginBlockedError
+	if errors.As(err, &blocked) {
+		utils.TooManyRequests(c, blocked.Error(), blocked.RetryAfter)
+		return
+	}
+	utils.LoginFailed(c)
+}
+
+// Logout 用户登出，注销当前会话
 func Logout(c *gin.Context) {
-	userID := c.GetUint("user_id")
+	user, err := utils.GetUserFromContext(c)
+	if err != nil {
+		utils.Unauthorized(c, "用户未登录")
+		return
+	}
 
-	// 使token失效
-	if err := utils.InvalidateToken(userID); err != nil {
+	if err := utils.RevokeSession(user.UserID, user.SessionID); err != nil {
 		utils.InternalServerError(c, "登出失败")
 		return
 	}
@@ -81,23 +201,120 @@ func Logout(c *gin.Context) {
 	utils.SuccessWithMessage(c, "登出成功")
 }
 
-// RefreshToken 刷新token
-func RefreshToken(c *gin.Context) {
+// LogoutAll 退出所有设备，注销当前用户的全部会话
+func LogoutAll(c *gin.Context) {
+	user, err := utils.GetUserFromContext(c)
+	if err != nil {
+		utils.Unauthorized(c, "用户未登录")
+		return
+	}
+
+	count, err := utils.RevokeUserSessions(user.UserID, "")
+	if err != nil {
+		utils.InternalServerError(c, "登出失败")
+		return
+	}
+
+	utils.Success(c, gin.H{"revoked": count}, "已退出所有设备")
+}
+
+// GetSessions 获取当前用户的登录会话
+func GetSessions(c *gin.Context) {
 	user, err := utils.GetUserFromContext(c)
+	if err != nil {
+		utils.Unauthorized(c, "用户未登录")
+		return
+	}
 
+	sessions, err := utils.ListSessions(user.UserID)
+	if err != nil {
+		utils.GetFailed(c, "会话列表")
+		return
+	}
+
+	result := []gin.H{}
+	for _, session := range sessions {
+		result = append(result, gin.H{
+			"id":           session.ID,
+			"device":       session.Device,
+			"user_agent":   session.UserAgent,
+			"ip":           session.IP,
+			"created_at":   session.CreatedAt,
+			"last_seen_at": session.LastSeenAt,
+			"expires_at":   session.ExpiresAt,
+			"current":      session.ID == user.SessionID,
+		})
+	}
+
+	utils.Success(c, result, "获取成功")
+}
+
+// RevokeSession 注销当前用户的指定会话
+func RevokeSession(c *gin.Context) {
+	user, err := utils.GetUserFromContext(c)
 	if err != nil {
 		utils.Unauthorized(c, "用户未登录")
 		return
 	}
-	// 生成新token
-	token, err := utils.RefreshToken(user.UserID, user.Username, user.Role)
+
+	session, err := utils.GetSession(c.Param("id"))
+	if err != nil || session.UserID != user.UserID {
+		utils.NotFound(c, "会话不存在")
+		return
+	}
+
+	if err := utils.RevokeSession(user.UserID, session.ID); err != nil {
+		utils.InternalServerError(c, "注销会话失败")
+		return
+	}
+
+	utils.SuccessWithMessage(c, "注销成功")
+}
+
+// RefreshToken 使用刷新令牌换取新的访问令牌，刷新令牌同时轮换，旧刷新令牌作废
+func RefreshToken(c *gin.Context) {
+	var req RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(c, err.Error())
+		return
+	}
+
+	session, refreshToken, err := utils.RotateRefreshToken(req.RefreshToken)
+	if errors.Is(err, utils.ErrRefreshTokenInvalid) || errors.Is(err, utils.ErrRefreshTokenReused) {
+		utils.Unauthorized(c, err.Error())
+		return
+	}
 	if err != nil {
 		utils.InternalServerError(c, "刷新令牌失败")
 		return
 	}
 
+	// 角色等信息以数据库为准，用户已删除时注销会话
+	var user models.User
+	if err := config.DB.First(&user, session.UserID).Error; err != nil {
+		utils.RevokeSession(session.UserID, session.ID)
+		utils.Unauthorized(c, "用户不存在")
+		return
+	}
+
+	// 已被移出原工作空间时回到默认工作空间
+	workspaceID := session.WorkspaceID
+	if _, err := services.GetWorkspaceMember(workspaceID, user.ID); err != nil {
+		workspaceID, _ = services.DefaultWorkspaceID(user.ID)
+		utils.SetSessionWorkspace(session.ID, workspaceID)
+	}
+
+	token, err := utils.GenerateToken(user.ID, user.Username, user.Role, workspaceID, session.ID)
+	if err != nil {
+		utils.InternalServerError(c, "生成令牌失败")
+		return
+	}
+
 	utils.Success(c, gin.H{
-		"token": token,
+		"token":         token,
+		"refresh_token": refreshToken,
+		"expires_in":    int(utils.AccessTokenLifetime().Seconds()),
+		"workspace_id":  workspaceID,
 	}, "刷新成功")
 }
 
@@ -122,6 +339,23 @@ func Register(c *gin.Context) {
 		return
 	}
 
+	if err := utils.ValidatePassword(req.Password, req.Username); err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	// 注册前校验邀请，避免创建账号后才发现邀请无效
+	if req.InviteToken != "" {
+		invitation, err := services.FindInvitation(config.DB, req.InviteToken)
+		if err == nil && services.NormalizeEmail(req.Email) != invitation.Email {
+			err = services.ErrInvitationEmail
+		}
+		if err != nil {
+			utils.BadRequest(c, err.Error())
+			return
+		}
+	}
+
 	// 加密密码
 	hashedPassword, err := utils.HashPassword(req.Password)
 	if err != nil {
@@ -136,33 +370,137 @@ func Register(c *gin.Context) {
 		Email:    req.Email,
 	}
 
-	if err := config.DB.Create(&user).Error; err != nil {
+	// 同时创建个人工作空间，携带邀请时加入邀请的工作空间
+	var invitation *models.Invitation
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+		if _, err := services.CreatePersonalWorkspace(tx, &user); err != nil {
+			return err
+		}
+		if req.InviteToken != "" {
+			invitation, err = services.AcceptInvitation(tx, req.InviteToken, &user)
+			return err
+		}
+		return nil
+	})
+	if isInvitationError(err) {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+	if err != nil {
 		utils.CreateFailed(c, "用户")
 		return
 	}
 
-	utils.Success(c, gin.H{
+	data := gin.H{
 		"id":       user.ID,
 		"username": user.Username,
 		"email":    user.Email,
-	}, "注册成功")
+	}
+	if invitation != nil {
+		data["workspace_id"] = invitation.WorkspaceID
+	}
+	utils.Success(c, data, "注册成功")
+}
+
+// ChangePassword 修改密码，成功后注销当前会话以外的全部会话
+func ChangePassword(c *gin.Context) {
+	claims, err := utils.GetUserFromContext(c)
+	if err != nil {
+		utils.Unauthorized(c, "用户未登录")
+		return
+	}
+
+	var req ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(c, err.Error())
+		return
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, claims.UserID).Error; err != nil {
+		utils.UserNotFound(c)
+		return
+	}
+
+	if err := services.ChangePassword(&user, req.CurrentPassword, req.NewPassword, claims.SessionID); err != nil {
+		passwordError(c, err, "修改密码失败")
+		return
+	}
+
+	utils.SuccessWithMessage(c, "密码修改成功，其他设备已退出登录")
+}
+
+// ForgotPassword 发送重置密码邮件，无论邮箱是否注册都返回成功
+func ForgotPassword(c *gin.Context) {
+	var req ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(c, err.Error())
+		return
+	}
+
+	if err := services.RequestPasswordReset(c.Request.Context(), req.Email); err != nil {
+		log.Printf("发送重置密码邮件失败: %v", err)
+		utils.InternalServerError(c, "发送重置邮件失败")
+		return
+	}
+
+	utils.SuccessWithMessage(c, "如果该邮箱已注册，重置密码邮件将很快送达")
+}
+
+// ResetPassword 使用邮件中的令牌重置密码，成功后所有设备需要重新登录
+func ResetPassword(c *gin.Context) {
+	var req ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(c, err.Error())
+		return
+	}
+
+	if err := services.ResetPassword(c.Request.Context(), req.Token, req.NewPassword); err != nil {
+		passwordError(c, err, "重置密码失败")
+		return
+	}
+
+	utils.SuccessWithMessage(c, "密码重置成功，请重新登录")
+}
+
+// passwordError 密码校验相关错误返回400，其他错误返回500
+func passwordError(c *gin.Context, err error, message string) {
+	var policyErr *utils.PasswordPolicyError
+	switch {
+	case errors.As(err, &policyErr),
+		errors.Is(err, services.ErrWrongPassword),
+		errors.Is(err, services.ErrSamePassword),
+		errors.Is(err, services.ErrResetTokenInvalid):
+		utils.BadRequest(c, err.Error())
+	default:
+		log.Printf("%s: %v", message, err)
+		utils.InternalServerError(c, message)
+	}
 }
 
 // GetProfile 获取用户信息
 func GetProfile(c *gin.Context) {
-	userID := c.GetUint("user_id")
+	claims, err := utils.GetUserFromContext(c)
+	if err != nil {
+		utils.Unauthorized(c, "用户未登录")
+		return
+	}
 
 	var user models.User
-	if err := config.DB.First(&user, userID).Error; err != nil {
+	if err := config.DB.First(&user, claims.UserID).Error; err != nil {
 		utils.UserNotFound(c)
 		return
 	}
 
 	utils.Success(c, gin.H{
-		"id":       user.ID,
-		"username": user.Username,
-		"email":    user.Email,
-		"avatar":   user.Avatar,
-		"role":     user.Role,
+		"id":           user.ID,
+		"username":     user.Username,
+		"email":        user.Email,
+		"avatar":       user.Avatar,
+		"role":         user.Role,
+		"totp_enabled": user.TOTPEnabled,
 	}, "获取成功")
 }
@@ -0,0 +1,240 @@
+package controllers
+
+import (
+	"errors"
+	"strconv"
+
+	"ai-assistant-backend/config"
+	"ai-assistant-backend/models"
+	"ai-assistant-backend/services"
+	"ai-assistant-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type StartConversationRequest struct {
+	VisitorID string `json:"visitor_id"`
+}
+
+type SendMessageRequest struct {
+	Content string `json:"content" binding:"required"`
+}
+
+type MessageFeedbackRequest struct {
+	Feedback string `json:"feedback" binding:"omitempty,oneof=like dislike"`
+	Comment  string `json:"comment"`
+}
+
+// loadChatAgent 根据路径中的AppID加载可对话的智能体，校验请求来源域名并应用发布版本的配置
+func loadChatAgent(c *gin.Context) (*models.Agent, bool) {
+	var agent models.Agent
+	if err := config.DB.Where("app_id = ?", c.Param("app_id")).First(&agent).Error; err != nil {
+		utils.AgentNotFound(c)
+		return nil, false
+	}
+
+	if agent.Status != "online" {
+		utils.Forbidden(c, "智能体已下线")
+		return nil, false
+	}
+	if !checkAgentOrigin(c, &agent) {
+		return nil, false
+	}
+
+	// 访客始终使用发布版本的配置
+	if err := services.ApplyPublishedVersion(&agent); err != nil {
+		utils.Forbidden(c, "智能体尚未发布")
+		return nil, false
+	}
+
+	return &agent, true
+}
+
+// loadChatConversation 加载智能体及其下的会话
+func loadChatConversation(c *gin.Context) (*models.Agent, *models.Conversation, bool) {
+	agent, ok := loadChatAgent(c)
+	if !ok {
+		return nil, nil, false
+	}
+
+	conversation, err := services.GetConversation(agent.ID, c.Param("conversation_id"))
+	if err != nil {
+		utils.NotFound(c, err.Error())
+		return nil, nil, false
+	}
+
+	return agent, conversation, true
+}
+
+// StartConversation 开启新会话
+func StartConversation(c *gin.Context) {
+	agent, ok := loadChatAgent(c)
+	if !ok {
+		return
+	}
+
+	var req StartConversationRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.ValidationError(c, err.Error())
+			return
+		}
+	}
+
+	conversation, messages, err := services.StartConversation(agent, req.VisitorID)
+	if err != nil {
+		utils.CreateFailed(c, "会话")
+		return
+	}
+
+	utils.Success(c, gin.H{
+		"conversation_id": conversation.SessionID,
+		"agent": gin.H{
+			"name": agent.Name,
+			"logo": agent.Logo,
+		},
+		"messages": messages,
+	}, "会话创建成功")
+}
+
+// GetConversationMessages 获取会话消息
+func GetConversationMessages(c *gin.Context) {
+	_, conversation, ok := loadChatConversation(c)
+	if !ok {
+		return
+	}
+
+	messages, err := services.GetMessages(conversation.ID)
+	if err != nil {
+		utils.GetFailed(c, "会话消息")
+		return
+	}
+
+	utils.Success(c, gin.H{
+		"conversation_id": conversation.SessionID,
+		"messages":        messages,
+	}, "获取成功")
+}
+
+// SendMessage 发送消息并获取智能体回复
+func SendMessage(c *gin.Context) {
+	agent, conversation, ok := loadChatConversation(c)
+	if !ok {
+		return
+	}
+
+	var req SendMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(c, err.Error())
+		return
+	}
+
+	if len([]rune(req.Content)) > 1000 {
+		utils.BadRequest(c, "消息长度不能超过1000个字符")
+		return
+	}
+
+	reply, err := services.SendMessage(c.Request.Context(), agent, conversation, req.Content)
+	if err != nil {
+		utils.InternalServerError(c, "生成回复失败")
+		return
+	}
+
+	utils.Success(c, gin.H{
+		"conversation_id": conversation.SessionID,
+		"message":         reply.Message,
+		"usage":           reply.Usage,
+		"documents":       reply.Documents,
+		"faqs":            reply.FAQs,
+	}, "发送成功")
+}
+
+// StreamMessage 发送消息并以SSE流式返回智能体回复
+//
+// 事件类型：delta（增量内容）、done（消息ID、用量与引用）、error（生成失败）
+func StreamMessage(c *gin.Context) {
+	agent, conversation, ok := loadChatConversation(c)
+	if !ok {
+		return
+	}
+
+	var req SendMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(c, err.Error())
+		return
+	}
+
+	if len([]rune(req.Content)) > 1000 {
+		utils.BadRequest(c, "消息长度不能超过1000个字符")
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	ctx := c.Request.Context()
+	reply, err := services.StreamMessage(ctx, agent, conversation, req.Content, func(delta string) {
+		c.SSEvent("delta", gin.H{"content": delta})
+		c.Writer.Flush()
+	})
+	if err != nil {
+		// 访客已断开连接，无需再写入
+		if ctx.Err() != nil {
+			return
+		}
+		c.SSEvent("error", gin.H{"message": "生成回复失败"})
+		c.Writer.Flush()
+		return
+	}
+
+	c.SSEvent("done", gin.H{
+		"conversation_id": conversation.SessionID,
+		"message_id":      reply.Message.ID,
+		"usage":           reply.Usage,
+		"documents":       reply.Documents,
+		"faqs":            reply.FAQs,
+	})
+	c.Writer.Flush()
+}
+
+// SetMessageFeedback 访客评价智能体回复
+func SetMessageFeedback(c *gin.Context) {
+	_, conversation, ok := loadChatConversation(c)
+	if !ok {
+		return
+	}
+
+	messageID, err := strconv.ParseUint(c.Param("message_id"), 10, 32)
+	if err != nil {
+		utils.InvalidID(c, "消息")
+		return
+	}
+
+	var req MessageFeedbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(c, err.Error())
+		return
+	}
+
+	if len([]rune(req.Comment)) > 500 {
+		utils.BadRequest(c, "评价内容不能超过500个字符")
+		return
+	}
+
+	message, err := services.SetMessageFeedback(conversation, uint(messageID), req.Feedback, req.Comment)
+	if err != nil {
+		if errors.Is(err, services.ErrMessageNotFound) {
+			utils.NotFound(c, err.Error())
+			return
+		}
+		utils.UpdateFailed(c, "评价")
+		return
+	}
+
+	utils.Success(c, gin.H{
+		"message_id": message.ID,
+		"feedback":   req.Feedback,
+	}, "评价成功")
+}
@@ -0,0 +1,97 @@
+package controllers
+
+import (
+	"strconv"
+	"time"
+
+	"ai-assistant-backend/config"
+	"ai-assistant-backend/models"
+	"ai-assistant-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetAgentConversations 获取智能体的会话列表
+func GetAgentConversations(c *gin.Context) {
+	agent := currentAgent(c)
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 10
+	}
+
+	query := config.DB.Model(&models.Conversation{}).Where("agent_id = ?", agent.ID)
+
+	// 按日期筛选，结束日期包含当天
+	if startDate := c.Query("start_date"); startDate != "" {
+		start, err := time.ParseInLocation("2006-01-02", startDate, time.Local)
+		if err != nil {
+			utils.BadRequest(c, "开始日期格式错误，应为YYYY-MM-DD")
+			return
+		}
+		query = query.Where("created_at >= ?", start)
+	}
+	if endDate := c.Query("end_date"); endDate != "" {
+		end, err := time.ParseInLocation("2006-01-02", endDate, time.Local)
+		if err != nil {
+			utils.BadRequest(c, "结束日期格式错误，应为YYYY-MM-DD")
+			return
+		}
+		query = query.Where("created_at < ?", end.AddDate(0, 0, 1))
+	}
+	if visitorID := c.Query("visitor_id"); visitorID != "" {
+		query = query.Where("visitor_id = ?", visitorID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		utils.GetFailed(c, "会话列表")
+		return
+	}
+
+	var conversations []models.Conversation
+	offset := (page - 1) * pageSize
+	if err := query.Offset(offset).Limit(pageSize).Order("last_message_at desc").Find(&conversations).Error; err != nil {
+		utils.GetFailed(c, "会话列表")
+		return
+	}
+
+	utils.Success(c, gin.H{
+		"conversations": conversations,
+		"total":         total,
+		"page":          page,
+		"page_size":     pageSize,
+	}, "获取成功")
+}
+
+// GetAgentConversation 获取会话详情及全部消息
+func GetAgentConversation(c *gin.Context) {
+	agent := currentAgent(c)
+
+	conversationID, err := strconv.ParseUint(c.Param("conversation_id"), 10, 32)
+	if err != nil {
+		utils.InvalidID(c, "会话")
+		return
+	}
+
+	var conversation models.Conversation
+	if err := config.DB.Where("id = ? AND agent_id = ?", conversationID, agent.ID).First(&conversation).Error; err != nil {
+		utils.NotFound(c, "会话不存在")
+		return
+	}
+
+	var messages []models.Message
+	if err := config.DB.Where("conversation_id = ?", conversation.ID).Order("id").Find(&messages).Error; err != nil {
+		utils.GetFailed(c, "会话消息")
+		return
+	}
+
+	utils.Success(c, gin.H{
+		"conversation": conversation,
+		"messages":     messages,
+	}, "获取成功")
+}
@@ -7,6 +7,8 @@ import (
 
 	"ai-assistant-backend/config"
 	"ai-assistant-backend/models"
+	"ai-assistant-backend/services"
+	"ai-assistant-backend/utils"
 
 	"github.com/gin-gonic/gin"
 )
@@ -27,28 +29,19 @@ type AddTagRequest struct {
 	TagName string `json:"tag_name" binding:"required"`
 }
 
+// categoryBelongsToAgent 校验分类是否属于指定智能体，model为分类模型
+func categoryBelongsToAgent(model interface{}, categoryID, agentID uint) bool {
+	var count int64
+	config.DB.Model(model).Where("id = ? AND agent_id = ?", categoryID, agentID).Count(&count)
+	return count > 0
+}
+
 // GetDocumentCategories 获取文档分类列表
 func GetDocumentCategories(c *gin.Context) {
-	agentID := c.Query("agent_id")
-	if agentID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"code":    400,
-			"message": "缺少智能体ID参数",
-		})
-		return
-	}
-
-	agentIDUint, err := strconv.ParseUint(agentID, 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"code":    400,
-			"message": "无效的智能体ID",
-		})
-		return
-	}
+	agent := currentAgent(c)
 
 	var categories []models.DocumentCategory
-	if err := config.DB.Where("agent_id = ?", agentIDUint).Order("sort").Find(&categories).Error; err != nil {
+	if err := config.DB.Where("agent_id = ?", agent.ID).Order("sort").Find(&categories).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"code":    500,
 			"message": "获取文档分类失败",
@@ -75,27 +68,11 @@ func CreateDocumentCategory(c *gin.Context) {
 		return
 	}
 
-	agentID := c.Query("agent_id")
-	if agentID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"code":    400,
-			"message": "缺少智能体ID参数",
-		})
-		return
-	}
-
-	agentIDUint, err := strconv.ParseUint(agentID, 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"code":    400,
-			"message": "无效的智能体ID",
-		})
-		return
-	}
+	agent := currentAgent(c)
 
 	category := models.DocumentCategory{
 		Name:    req.Name,
-		AgentID: uint(agentIDUint),
+		AgentID: agent.ID,
 		Sort:    0,
 	}
 
@@ -116,42 +93,29 @@ func CreateDocumentCategory(c *gin.Context) {
 
 // GetDocuments 获取文档列表
 func GetDocuments(c *gin.Context) {
-	agentID := c.Query("agent_id")
+	agent := currentAgent(c)
 	categoryID := c.Query("category_id")
+	status := c.Query("status")
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
 
-	if agentID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"code":    400,
-			"message": "缺少智能体ID参数",
-		})
-		return
-	}
-
-	agentIDUint, err := strconv.ParseUint(agentID, 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"code":    400,
-			"message": "无效的智能体ID",
-		})
-		return
-	}
-
-	query := config.DB.Where("agent_id = ?", agentIDUint)
+	query := config.DB.Where("agent_id = ?", agent.ID)
 	if categoryID != "" {
 		categoryIDUint, err := strconv.ParseUint(categoryID, 10, 32)
 		if err == nil {
 			query = query.Where("category_id = ?", categoryIDUint)
 		}
 	}
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
 
 	var documents []models.Document
 	var total int64
 
 	query.Model(&models.Document{}).Count(&total)
 	offset := (page - 1) * pageSize
-	if err := query.Offset(offset).Limit(pageSize).Order("created_at desc").Find(&documents).Error; err != nil {
+	if err := query.Omit("content").Offset(offset).Limit(pageSize).Order("created_at desc").Find(&documents).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"code":    500,
 			"message": "获取文档列表失败",
@@ -195,31 +159,25 @@ func CreateDocument(c *gin.Context) {
 		return
 	}
 
-	agentID := c.Query("agent_id")
-	if agentID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"code":    400,
-			"message": "缺少智能体ID参数",
-		})
+	agent := currentAgent(c)
+	if req.CategoryID != 0 && !categoryBelongsToAgent(&models.DocumentCategory{}, req.CategoryID, agent.ID) {
+		utils.BadRequest(c, "文档分类不存在")
 		return
 	}
-
-	agentIDUint, err := strconv.ParseUint(agentID, 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"code":    400,
-			"message": "无效的智能体ID",
-		})
+	// 文档文件需位于智能体所属工作空间内，避免读取其他工作空间的文件
+	if !utils.ObjectInWorkspace(utils.ObjectNameFromPath(req.Path), agent.WorkspaceID) {
+		utils.Forbidden(c, "无权访问该文件")
 		return
 	}
 
 	document := models.Document{
-		AgentID:    uint(agentIDUint),
+		AgentID:    agent.ID,
 		CategoryID: req.CategoryID,
 		Name:       req.Name,
 		Path:       req.Path,
 		Format:     req.Format,
 		Size:       req.Size,
+		Status:     models.DocumentStatusPending,
 		UploadTime: time.Now(),
 	}
 
@@ -231,6 +189,9 @@ func CreateDocument(c *gin.Context) {
 		return
 	}
 
+	// 异步解析文档内容
+	services.EnqueueIngestion(document.ID)
+
 	c.JSON(http.StatusOK, gin.H{
 		"code":    200,
 		"message": "创建成功",
@@ -259,8 +220,9 @@ func DeleteDocument(c *gin.Context) {
 		return
 	}
 
-	// 删除相关的标签
+	// 删除相关的标签与切片
 	config.DB.Where("document_id = ?", document.ID).Delete(&models.DocumentTag{})
+	services.GetVectorStore().DeleteDocumentChunks(c.Request.Context(), document.ID)
 
 	// 删除文档
 	if err := config.DB.Delete(&document).Error; err != nil {
@@ -279,26 +241,10 @@ func DeleteDocument(c *gin.Context) {
 
 // GetTags 获取标签列表
 func GetTags(c *gin.Context) {
-	agentID := c.Query("agent_id")
-	if agentID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"code":    400,
-			"message": "缺少智能体ID参数",
-		})
-		return
-	}
-
-	agentIDUint, err := strconv.ParseUint(agentID, 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"code":    400,
-			"message": "无效的智能体ID",
-		})
-		return
-	}
+	agent := currentAgent(c)
 
 	var tags []models.Tag
-	if err := config.DB.Where("agent_id = ?", agentIDUint).Find(&tags).Error; err != nil {
+	if err := config.DB.Where("agent_id = ?", agent.ID).Find(&tags).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"code":    500,
 			"message": "获取标签列表失败",
@@ -325,27 +271,11 @@ func CreateTag(c *gin.Context) {
 		return
 	}
 
-	agentID := c.Query("agent_id")
-	if agentID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"code":    400,
-			"message": "缺少智能体ID参数",
-		})
-		return
-	}
-
-	agentIDUint, err := strconv.ParseUint(agentID, 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"code":    400,
-			"message": "无效的智能体ID",
-		})
-		return
-	}
+	agent := currentAgent(c)
 
 	tag := models.Tag{
 		Name:    req.TagName,
-		AgentID: uint(agentIDUint),
+		AgentID: agent.ID,
 	}
 
 	if err := config.DB.Create(&tag).Error; err != nil {
@@ -362,3 +292,37 @@ func CreateTag(c *gin.Context) {
 		"data":    tag,
 	})
 }
+
+// IngestDocument 重新解析文档
+func IngestDocument(c *gin.Context) {
+	documentID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.InvalidID(c, "文档")
+		return
+	}
+
+	var document models.Document
+	if err := config.DB.Omit("content").First(&document, documentID).Error; err != nil {
+		utils.DocumentNotFound(c)
+		return
+	}
+
+	if document.Status == models.DocumentStatusPending || document.Status == models.DocumentStatusProcessing {
+		utils.BadRequest(c, "文档正在解析中")
+		return
+	}
+
+	if err := config.DB.Model(&document).Updates(map[string]interface{}{
+		"status":       models.DocumentStatusPending,
+		"status_error": "",
+	}).Error; err != nil {
+		utils.UpdateFailed(c, "文档")
+		return
+	}
+	services.EnqueueIngestion(document.ID)
+
+	utils.Success(c, gin.H{
+		"id":     document.ID,
+		"status": models.DocumentStatusPending,
+	}, "已加入解析队列")
+}
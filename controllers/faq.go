@@ -6,6 +6,7 @@ import (
 
 	"ai-assistant-backend/config"
 	"ai-assistant-backend/models"
+	"ai-assistant-backend/utils"
 
 	"github.com/gin-gonic/gin"
 )
@@ -28,26 +29,10 @@ type UpdateFAQRequest struct {
 
 // GetFAQCategories 获取问答分类列表
 func GetFAQCategories(c *gin.Context) {
-	agentID := c.Query("agent_id")
-	if agentID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"code":    400,
-			"message": "缺少智能体ID参数",
-		})
-		return
-	}
-
-	agentIDUint, err := strconv.ParseUint(agentID, 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"code":    400,
-			"message": "无效的智能体ID",
-		})
-		return
-	}
+	agent := currentAgent(c)
 
 	var categories []models.FAQCategory
-	if err := config.DB.Where("agent_id = ?", agentIDUint).Order("sort").Find(&categories).Error; err != nil {
+	if err := config.DB.Where("agent_id = ?", agent.ID).Order("sort").Find(&categories).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"code":    500,
 			"message": "获取问答分类失败",
@@ -74,27 +59,11 @@ func CreateFAQCategory(c *gin.Context) {
 		return
 	}
 
-	agentID := c.Query("agent_id")
-	if agentID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"code":    400,
-			"message": "缺少智能体ID参数",
-		})
-		return
-	}
-
-	agentIDUint, err := strconv.ParseUint(agentID, 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"code":    400,
-			"message": "无效的智能体ID",
-		})
-		return
-	}
+	agent := currentAgent(c)
 
 	category := models.FAQCategory{
 		Name:    req.Name,
-		AgentID: uint(agentIDUint),
+		AgentID: agent.ID,
 		Sort:    0,
 	}
 
@@ -115,27 +84,10 @@ func CreateFAQCategory(c *gin.Context) {
 
 // GetFAQs 获取常见问答列表
 func GetFAQs(c *gin.Context) {
-	agentID := c.Query("agent_id")
+	agent := currentAgent(c)
 	categoryID := c.Query("category_id")
 
-	if agentID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"code":    400,
-			"message": "缺少智能体ID参数",
-		})
-		return
-	}
-
-	agentIDUint, err := strconv.ParseUint(agentID, 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"code":    400,
-			"message": "无效的智能体ID",
-		})
-		return
-	}
-
-	query := config.DB.Where("agent_id = ?", agentIDUint)
+	query := config.DB.Where("agent_id = ?", agent.ID)
 	if categoryID != "" {
 		categoryIDUint, err := strconv.ParseUint(categoryID, 10, 32)
 		if err == nil {
@@ -144,7 +96,7 @@ func GetFAQs(c *gin.Context) {
 	}
 
 	var faqs []models.FAQ
-	if err := query.Order("created_at desc").Find(&faqs).Error; err != nil {
+	if err := query.Omit("embedding").Order("created_at desc").Find(&faqs).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"code":    500,
 			"message": "获取常见问答失败",
@@ -189,26 +141,14 @@ func CreateFAQ(c *gin.Context) {
 		return
 	}
 
-	agentID := c.Query("agent_id")
-	if agentID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"code":    400,
-			"message": "缺少智能体ID参数",
-		})
-		return
-	}
-
-	agentIDUint, err := strconv.ParseUint(agentID, 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"code":    400,
-			"message": "无效的智能体ID",
-		})
+	agent := currentAgent(c)
+	if req.CategoryID != 0 && !categoryBelongsToAgent(&models.FAQCategory{}, req.CategoryID, agent.ID) {
+		utils.BadRequest(c, "问答分类不存在")
 		return
 	}
 
 	faq := models.FAQ{
-		AgentID:    uint(agentIDUint),
+		AgentID:    agent.ID,
 		CategoryID: req.CategoryID,
 		Question:   req.Question,
 		Answer:     req.Answer,
@@ -282,11 +222,18 @@ func UpdateFAQ(c *gin.Context) {
 	updates := make(map[string]interface{})
 	if req.Question != "" {
 		updates["question"] = req.Question
+		// 问题变更后需重新生成向量
+		updates["embedding"] = nil
+		updates["embedding_model"] = ""
 	}
 	if req.Answer != "" {
 		updates["answer"] = req.Answer
 	}
 	if req.CategoryID != 0 {
+		if !categoryBelongsToAgent(&models.FAQCategory{}, req.CategoryID, faq.AgentID) {
+			utils.BadRequest(c, "问答分类不存在")
+			return
+		}
 		updates["category_id"] = req.CategoryID
 	}
 
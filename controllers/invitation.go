@@ -0,0 +1,203 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"ai-assistant-backend/config"
+	"ai-assistant-backend/models"
+	"ai-assistant-backend/services"
+	"ai-assistant-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type CreateInvitationRequest struct {
+	Email string `json:"email" binding:"required,email"`
+	Role  *uint8 `json:"role" binding:"required"`
+}
+
+type InvitationTokenRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// CreateInvitation 邀请成员加入工作空间，邀请链接通过邮件发送
+func CreateInvitation(c *gin.Context) {
+	operator, err := utils.GetUserFromContext(c)
+	if err != nil {
+		utils.Unauthorized(c, "用户未登录")
+		return
+	}
+
+	var req CreateInvitationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(c, err.Error())
+		return
+	}
+	if !models.ValidMemberRole(*req.Role) {
+		utils.BadRequest(c, "无效的角色")
+		return
+	}
+
+	member, _ := utils.GetWorkspaceFromContext(c)
+	var workspace models.Workspace
+	if err := config.DB.First(&workspace, member.WorkspaceID).Error; err != nil {
+		utils.NotFound(c, "工作空间不存在")
+		return
+	}
+	var inviter models.User
+	if err := config.DB.First(&inviter, operator.UserID).Error; err != nil {
+		utils.UserNotFound(c)
+		return
+	}
+
+	invitation, err := services.InviteMember(c.Request.Context(), &workspace, &inviter, req.Email, *req.Role)
+	if errors.Is(err, services.ErrAlreadyMember) {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+	if err != nil {
+		log.Printf("邀请成员失败: %v", err)
+		utils.InternalServerError(c, "发送邀请失败")
+		return
+	}
+
+	utils.Success(c, invitation, "邀请已发送")
+}
+
+// GetInvitations 获取工作空间的邀请列表，status可选 pending、accepted、revoked、expired
+func GetInvitations(c *gin.Context) {
+	member, _ := utils.GetWorkspaceFromContext(c)
+
+	query := config.DB.Where("workspace_id = ?", member.WorkspaceID)
+	switch status := c.Query("status"); status {
+	case "":
+	case models.InvitationPending:
+		query = query.Where("status = ? AND expires_at > ?", status, time.Now())
+	case models.InvitationExpired:
+		query = query.Where("status = ? AND expires_at <= ?", models.InvitationPending, time.Now())
+	case models.InvitationAccepted, models.InvitationRevoked:
+		query = query.Where("status = ?", status)
+	default:
+		utils.BadRequest(c, "无效的邀请状态")
+		return
+	}
+
+	var invitations []models.Invitation
+	if err := query.Order("created_at desc").Find(&invitations).Error; err != nil {
+		utils.GetFailed(c, "邀请列表")
+		return
+	}
+	for i := range invitations {
+		invitations[i].Status = invitations[i].DisplayStatus()
+	}
+
+	utils.Success(c, invitations, "获取成功")
+}
+
+// RevokeInvitation 撤销待接受的邀请
+func RevokeInvitation(c *gin.Context) {
+	invitationID, err := strconv.ParseUint(c.Param("invitation_id"), 10, 32)
+	if err != nil {
+		utils.InvalidID(c, "邀请")
+		return
+	}
+
+	member, _ := utils.GetWorkspaceFromContext(c)
+	var invitation models.Invitation
+	if err := config.DB.Where("workspace_id = ?", member.WorkspaceID).First(&invitation, invitationID).Error; err != nil {
+		utils.NotFound(c, "邀请不存在")
+		return
+	}
+	if invitation.Status != models.InvitationPending {
+		utils.BadRequest(c, "只能撤销待接受的邀请")
+		return
+	}
+
+	if err := config.DB.Model(&invitation).Update("status", models.InvitationRevoked).Error; err != nil {
+		utils.UpdateFailed(c, "邀请")
+		return
+	}
+
+	utils.SuccessWithMessage(c, "撤销成功")
+}
+
+// PreviewInvitation 按令牌查看邀请信息，用于接受邀请页面展示，无需登录
+func PreviewInvitation(c *gin.Context) {
+	var req InvitationTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(c, err.Error())
+		return
+	}
+
+	invitation, err := services.FindInvitation(config.DB, req.Token)
+	if err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	var workspace models.Workspace
+	config.DB.First(&workspace, invitation.WorkspaceID)
+	var inviter models.User
+	config.DB.First(&inviter, invitation.InvitedBy)
+
+	utils.Success(c, gin.H{
+		"workspace_name": workspace.Name,
+		"inviter":        inviter.Username,
+		"email":          invitation.Email,
+		"role":           invitation.Role,
+		"role_name":      utils.RoleNames[invitation.Role],
+		"expires_at":     invitation.ExpiresAt,
+	}, "获取成功")
+}
+
+// AcceptInvitation 已登录用户接受邀请，加入对应的工作空间
+func AcceptInvitation(c *gin.Context) {
+	claims, err := utils.GetUserFromContext(c)
+	if err != nil {
+		utils.Unauthorized(c, "用户未登录")
+		return
+	}
+
+	var req InvitationTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(c, err.Error())
+		return
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, claims.UserID).Error; err != nil {
+		utils.UserNotFound(c)
+		return
+	}
+
+	var invitation *models.Invitation
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		invitation, err = services.AcceptInvitation(tx, req.Token, &user)
+		return err
+	})
+	if isInvitationError(err) {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+	if err != nil {
+		log.Printf("接受邀请失败: %v", err)
+		utils.InternalServerError(c, "接受邀请失败")
+		return
+	}
+
+	utils.Success(c, gin.H{
+		"workspace_id": invitation.WorkspaceID,
+		"role":         invitation.Role,
+	}, "已加入工作空间")
+}
+
+// isInvitationError 是否为邀请令牌无效、过期或邮箱不一致等可提示给用户的错误
+func isInvitationError(err error) bool {
+	return errors.Is(err, services.ErrInvitationInvalid) ||
+		errors.Is(err, services.ErrInvitationExpired) ||
+		errors.Is(err, services.ErrInvitationEmail)
+}
@@ -0,0 +1,76 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+
+	"ai-assistant-backend/config"
+	"ai-assistant-backend/services"
+	"ai-assistant-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type OIDCCallbackRequest struct {
+	Code  string `json:"code" binding:"required"`
+	State string `json:"state" binding:"required"`
+}
+
+// OIDCLogin 发起单点登录，返回身份提供方的授权地址，前端保存state后跳转
+func OIDCLogin(c *gin.Context) {
+	if !config.GlobalConfig.OIDC.Enabled {
+		utils.NotFound(c, "未启用单点登录")
+		return
+	}
+
+	authURL, state, err := services.BeginOIDCLogin(c.Request.Context())
+	if err != nil {
+		log.Printf("发起单点登录失败: %v", err)
+		utils.InternalServerError(c, "发起单点登录失败")
+		return
+	}
+
+	utils.Success(c, gin.H{
+		"authorization_url": authURL,
+		"state":             state,
+	}, "获取成功")
+}
+
+// OIDCCallback 前端回调页面收到授权码后调用，校验通过后签发与密码登录相同的令牌
+func OIDCCallback(c *gin.Context) {
+	if !config.GlobalConfig.OIDC.Enabled {
+		utils.NotFound(c, "未启用单点登录")
+		return
+	}
+
+	var req OIDCCallbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(c, err.Error())
+		return
+	}
+
+	user, err := services.CompleteOIDCLogin(c.Request.Context(), req.Code, req.State)
+	switch {
+	case errors.Is(err, services.ErrOIDCStateInvalid),
+		errors.Is(err, services.ErrOIDCEmailMissing),
+		errors.Is(err, services.ErrOIDCEmailUnverified):
+		utils.BadRequest(c, err.Error())
+		return
+	case errors.Is(err, services.ErrOIDCAccountNotFound),
+		errors.Is(err, services.ErrOIDCIdentityConflict):
+		utils.Forbidden(c, err.Error())
+		return
+	case err != nil:
+		log.Printf("单点登录失败: %v", err)
+		utils.Unauthorized(c, "单点登录失败")
+		return
+	}
+
+	// 已启用双因素认证的用户同样需要完成第二步验证
+	if user.TOTPEnabled {
+		twoFactorChallenge(c, user)
+		return
+	}
+	services.ResetLoginFailures(c.Request.Context(), user.Username)
+	issueLogin(c, user)
+}
@@ -0,0 +1,105 @@
+package controllers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"ai-assistant-backend/models"
+	"ai-assistant-backend/services"
+	"ai-assistant-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// publicConfigCacheControl 对话窗口配置的缓存策略，修改智能体后最迟一分钟生效
+const publicConfigCacheControl = "public, max-age=60"
+
+// PublicSelfService 对外公开的自助服务字段
+type PublicSelfService struct {
+	Name string `json:"name"`
+	Link string `json:"link"`
+	Icon string `json:"icon"`
+}
+
+// PublicAgentConfig 对话窗口使用的智能体公开配置
+type PublicAgentConfig struct {
+	AppID          string              `json:"app_id"`
+	Name           string              `json:"name"`
+	Logo           string              `json:"logo"`
+	WelcomeMsg     string              `json:"welcome_msg"`
+	ThemeColor     string              `json:"theme_color"`
+	ThemeTextColor string              `json:"theme_text_color"`
+	WidgetPosition string              `json:"widget_position"`
+	CarouselImages []string            `json:"carousel_images"`
+	SelfServices   []PublicSelfService `json:"self_services"`
+}
+
+// checkAgentOrigin 校验请求来源是否在智能体允许的域名中，未携带Origin的请求（非浏览器跨域请求）
+// 与托管对话页发起的请求不受限制
+func checkAgentOrigin(c *gin.Context, agent *models.Agent) bool {
+	origin := c.GetHeader("Origin")
+	if origin == "" || origin == services.PublicOrigin() || utils.OriginAllowed(origin, agent.AllowedDomains) {
+		return true
+	}
+	utils.Forbidden(c, "当前网站未被允许接入该智能体")
+	return false
+}
+
+// GetPublicAgentConfig 获取对话窗口配置，无需登录，欢迎语、轮播图与自助服务取自发布版本。
+// 响应带ETag，If-None-Match一致时返回304
+func GetPublicAgentConfig(c *gin.Context) {
+	agent, ok := loadChatAgent(c)
+	if !ok {
+		return
+	}
+
+	data := PublicAgentConfig{
+		AppID:          agent.AppID,
+		Name:           agent.Name,
+		Logo:           agent.Logo,
+		WelcomeMsg:     agent.WelcomeMsg,
+		ThemeColor:     agent.ThemeColor,
+		ThemeTextColor: agent.ThemeTextColor,
+		WidgetPosition: agent.WidgetPosition,
+		CarouselImages: agent.CarouselImages,
+		SelfServices:   []PublicSelfService{},
+	}
+	for _, service := range agent.SelfServices {
+		data.SelfServices = append(data.SelfServices, PublicSelfService{
+			Name: service.Name,
+			Link: service.Link,
+			Icon: service.Icon,
+		})
+	}
+
+	payload, err := json.Marshal(data)
+	if err != nil {
+		utils.GetFailed(c, "智能体配置")
+		return
+	}
+	sum := sha256.Sum256(payload)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", publicConfigCacheControl)
+	if etagMatches(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	utils.Success(c, data, "获取成功")
+}
+
+// etagMatches If-None-Match是否包含当前ETag，支持多个值与弱校验前缀
+func etagMatches(header, etag string) bool {
+	for _, value := range strings.Split(header, ",") {
+		value = strings.TrimPrefix(strings.TrimSpace(value), "W/")
+		if value == etag || value == "*" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,226 @@
+package controllers
+
+import (
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	"ai-assistant-backend/config"
+	"ai-assistant-backend/models"
+	"ai-assistant-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// maxSelfServices 每个智能体最多的自助服务数量
+const maxSelfServices = 10
+
+// selfServiceLinkSchemes 自助服务链接允许的协议
+var selfServiceLinkSchemes = map[string]bool{"http": true, "https": true, "mailto": true, "tel": true}
+
+// selfServiceIconTypes 自助服务图标允许的图片格式
+var selfServiceIconTypes = map[string]bool{".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".svg": true, ".webp": true, ".ico": true}
+
+type SelfServiceRequest struct {
+	Name string `json:"name" binding:"required,max=50"`
+	Link string `json:"link" binding:"required,max=500"`
+	Icon string `json:"icon" binding:"required,max=500"`
+}
+
+type ReorderSelfServicesRequest struct {
+	IDs []uint `json:"ids" binding:"required,min=1"`
+}
+
+// loadSelfServices 按排序加载智能体的自助服务
+func loadSelfServices(agentID uint) []models.SelfService {
+	items := []models.SelfService{}
+	config.DB.Where("agent_id = ?", agentID).Order("sort, id").Find(&items)
+	return items
+}
+
+// GetSelfServices 获取智能体的自助服务列表
+func GetSelfServices(c *gin.Context) {
+	agent := currentAgent(c)
+	utils.Success(c, loadSelfServices(agent.ID), "获取成功")
+}
+
+// CreateSelfService 创建自助服务，排在最后
+func CreateSelfService(c *gin.Context) {
+	agent := currentAgent(c)
+
+	var req SelfServiceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(c, err.Error())
+		return
+	}
+	if !validateSelfService(c, &req) {
+		return
+	}
+
+	var count int64
+	config.DB.Model(&models.SelfService{}).Where("agent_id = ?", agent.ID).Count(&count)
+	if count >= maxSelfServices {
+		utils.BadRequest(c, "自助服务最多"+strconv.Itoa(maxSelfServices)+"个")
+		return
+	}
+
+	var maxSort int
+	config.DB.Model(&models.SelfService{}).Where("agent_id = ?", agent.ID).Select("COALESCE(MAX(sort), 0)").Scan(&maxSort)
+
+	service := models.SelfService{
+		AgentID: agent.ID,
+		Name:    req.Name,
+		Link:    req.Link,
+		Icon:    req.Icon,
+		Sort:    maxSort + 1,
+	}
+	if err := config.DB.Create(&service).Error; err != nil {
+		utils.CreateFailed(c, "自助服务")
+		return
+	}
+
+	utils.Success(c, service, "创建成功")
+}
+
+// UpdateSelfService 更新自助服务
+func UpdateSelfService(c *gin.Context) {
+	agent := currentAgent(c)
+	service, ok := loadSelfService(c, agent.ID)
+	if !ok {
+		return
+	}
+
+	var req SelfServiceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(c, err.Error())
+		return
+	}
+	if !validateSelfService(c, &req) {
+		return
+	}
+
+	service.Name = req.Name
+	service.Link = req.Link
+	service.Icon = req.Icon
+	if err := config.DB.Save(service).Error; err != nil {
+		utils.UpdateFailed(c, "自助服务")
+		return
+	}
+
+	utils.Success(c, service, "更新成功")
+}
+
+// DeleteSelfService 删除自助服务
+func DeleteSelfService(c *gin.Context) {
+	agent := currentAgent(c)
+	service, ok := loadSelfService(c, agent.ID)
+	if !ok {
+		return
+	}
+
+	if err := config.DB.Delete(service).Error; err != nil {
+		utils.DeleteFailed(c, "自助服务")
+		return
+	}
+
+	utils.SuccessWithMessage(c, "删除成功")
+}
+
+// ReorderSelfServices 按ids的顺序重新排序，ids需包含智能体的全部自助服务
+func ReorderSelfServices(c *gin.Context) {
+	agent := currentAgent(c)
+
+	var req ReorderSelfServicesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(c, err.Error())
+		return
+	}
+
+	current := loadSelfServices(agent.ID)
+	existing := map[uint]bool{}
+	for _, service := range current {
+		existing[service.ID] = true
+	}
+	seen := map[uint]bool{}
+	for _, id := range req.IDs {
+		if !existing[id] || seen[id] {
+			utils.BadRequest(c, "自助服务ID无效或重复")
+			return
+		}
+		seen[id] = true
+	}
+	if len(req.IDs) != len(current) {
+		utils.BadRequest(c, "需要提供全部自助服务的ID")
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		for i, id := range req.IDs {
+			if err := tx.Model(&models.SelfService{}).Where("id = ? AND agent_id = ?", id, agent.ID).Update("sort", i+1).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		utils.UpdateFailed(c, "自助服务排序")
+		return
+	}
+
+	utils.Success(c, loadSelfServices(agent.ID), "排序成功")
+}
+
+// loadSelfService 按路径参数service_id加载智能体的自助服务，失败时已写入响应
+func loadSelfService(c *gin.Context, agentID uint) (*models.SelfService, bool) {
+	serviceID, err := strconv.ParseUint(c.Param("service_id"), 10, 32)
+	if err != nil {
+		utils.InvalidID(c, "自助服务")
+		return nil, false
+	}
+
+	var service models.SelfService
+	if err := config.DB.Where("id = ? AND agent_id = ?", serviceID, agentID).First(&service).Error; err != nil {
+		utils.NotFound(c, "自助服务不存在")
+		return nil, false
+	}
+	return &service, true
+}
+
+// validateSelfService 校验名称、链接与图标，失败时已写入响应
+func validateSelfService(c *gin.Context, req *SelfServiceRequest) bool {
+	req.Name = strings.TrimSpace(req.Name)
+	req.Link = strings.TrimSpace(req.Link)
+	req.Icon = strings.TrimSpace(req.Icon)
+
+	if req.Name == "" {
+		utils.BadRequest(c, "名称不能为空")
+		return false
+	}
+
+	link, err := url.Parse(req.Link)
+	if err != nil || !selfServiceLinkSchemes[strings.ToLower(link.Scheme)] {
+		utils.BadRequest(c, "链接需以 http://、https://、mailto: 或 tel: 开头")
+		return false
+	}
+	if (link.Scheme == "http" || link.Scheme == "https") && link.Host == "" {
+		utils.BadRequest(c, "无效的链接")
+		return false
+	}
+	if (link.Scheme == "mailto" || link.Scheme == "tel") && link.Opaque == "" {
+		utils.BadRequest(c, "无效的链接")
+		return false
+	}
+
+	icon, err := url.Parse(req.Icon)
+	if err != nil || (icon.Scheme != "http" && icon.Scheme != "https") || icon.Host == "" {
+		utils.BadRequest(c, "图标需为 http:// 或 https:// 开头的图片地址")
+		return false
+	}
+	if !selfServiceIconTypes[strings.ToLower(path.Ext(icon.Path))] {
+		utils.BadRequest(c, "图标格式仅支持 png、jpg、jpeg、gif、svg、webp、ico")
+		return false
+	}
+	return true
+}
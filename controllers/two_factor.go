@@ -0,0 +1,251 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"strconv"
+
+	"ai-assistant-backend/config"
+	"ai-assistant-backend/models"
+	"ai-assistant-backend/services"
+	"ai-assistant-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type DisableTwoFactorRequest struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+type TwoFactorPolicyRequest struct {
+	Roles []uint8 `json:"roles" binding:"required"`
+}
+
+// currentUser 查询当前登录用户，失败时已写入响应
+func currentUser(c *gin.Context) (*models.User, bool) {
+	claims, err := utils.GetUserFromContext(c)
+	if err != nil {
+		utils.Unauthorized(c, "用户未登录")
+		return nil, false
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, claims.UserID).Error; err != nil {
+		utils.UserNotFound(c)
+		return nil, false
+	}
+	return &user, true
+}
+
+// GetTwoFactorStatus 获取当前用户的双因素认证状态
+func GetTwoFactorStatus(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	utils.Success(c, gin.H{
+		"enabled":                  user.TOTPEnabled,
+		"enabled_at":               user.TOTPEnabledAt,
+		"required":                 services.TwoFactorRequired(user.Role),
+		"recovery_codes_remaining": services.RecoveryCodesRemaining(user),
+	}, "获取成功")
+}
+
+// SetupTwoFactor 生成TOTP密钥与otpauth链接，需调用确认接口后才会启用
+func SetupTwoFactor(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	secret, uri, err := services.BeginTOTPSetup(c.Request.Context(), user)
+	if err != nil {
+		twoFactorError(c, err, "生成密钥失败")
+		return
+	}
+
+	utils.Success(c, gin.H{
+		"secret":      secret,
+		"otpauth_uri": uri,
+	}, "请使用验证器应用扫描二维码后输入验证码完成设置")
+}
+
+// ConfirmTwoFactor 校验验证码并启用双因素认证，返回的恢复码只展示这一次
+func ConfirmTwoFactor(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(c, err.Error())
+		return
+	}
+
+	codes, err := services.ConfirmTOTPSetup(c.Request.Context(), user, req.Code)
+	if err != nil {
+		twoFactorError(c, err, "启用双因素认证失败")
+		return
+	}
+
+	utils.Success(c, gin.H{"recovery_codes": codes}, "双因素认证已启用，请妥善保存恢复码")
+}
+
+// DisableTwoFactor 校验密码与验证码后关闭双因素认证，角色要求启用时不允许关闭
+func DisableTwoFactor(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	var req DisableTwoFactorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(c, err.Error())
+		return
+	}
+
+	if !user.TOTPEnabled {
+		utils.BadRequest(c, services.ErrTwoFactorNotEnabled.Error())
+		return
+	}
+	if services.TwoFactorRequired(user.Role) {
+		utils.Forbidden(c, services.ErrTwoFactorRequired.Error()+"，不能关闭")
+		return
+	}
+	if !utils.CheckPassword(req.Password, user.Password) {
+		utils.BadRequest(c, services.ErrWrongPassword.Error())
+		return
+	}
+	if err := services.VerifySecondFactor(user, req.Code); err != nil {
+		twoFactorError(c, err, "关闭双因素认证失败")
+		return
+	}
+
+	if err := services.DisableTwoFactor(user); err != nil {
+		log.Printf("关闭双因素认证失败: %v", err)
+		utils.InternalServerError(c, "关闭双因素认证失败")
+		return
+	}
+
+	utils.SuccessWithMessage(c, "双因素认证已关闭")
+}
+
+// RegenerateRecoveryCodes 校验验证码后重新生成恢复码，旧恢复码全部作废
+func RegenerateRecoveryCodes(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(c, err.Error())
+		return
+	}
+
+	if err := services.VerifySecondFactor(user, req.Code); err != nil {
+		twoFactorError(c, err, "生成恢复码失败")
+		return
+	}
+	codes, err := services.RegenerateRecoveryCodes(user)
+	if err != nil {
+		twoFactorError(c, err, "生成恢复码失败")
+		return
+	}
+
+	utils.Success(c, gin.H{"recovery_codes": codes}, "恢复码已重新生成，请妥善保存")
+}
+
+// GetTwoFactorPolicy 获取要求启用双因素认证的角色
+func GetTwoFactorPolicy(c *gin.Context) {
+	utils.Success(c, gin.H{"roles": twoFactorPolicyRoles(services.TwoFactorRequiredRoles())}, "获取成功")
+}
+
+// UpdateTwoFactorPolicy 设置要求启用双因素认证的角色，对应角色的用户启用前无法访问需要权限的接口
+func UpdateTwoFactorPolicy(c *gin.Context) {
+	var req TwoFactorPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(c, err.Error())
+		return
+	}
+
+	roles := make([]uint8, 0, len(req.Roles))
+	seen := map[uint8]bool{}
+	for _, role := range req.Roles {
+		if !models.ValidRole(role) {
+			utils.BadRequest(c, "无效的角色")
+			return
+		}
+		if !seen[role] {
+			seen[role] = true
+			roles = append(roles, role)
+		}
+	}
+
+	if err := services.SetTwoFactorRequiredRoles(roles); err != nil {
+		log.Printf("保存双因素认证策略失败: %v", err)
+		utils.UpdateFailed(c, "双因素认证策略")
+		return
+	}
+
+	utils.Success(c, gin.H{"roles": twoFactorPolicyRoles(roles)}, "设置成功")
+}
+
+// twoFactorPolicyRoles 角色列表附带角色名称
+func twoFactorPolicyRoles(roles []uint8) []gin.H {
+	result := []gin.H{}
+	for _, role := range roles {
+		result = append(result, gin.H{
+			"role": role,
+			"name": utils.RoleNames[role],
+		})
+	}
+	return result
+}
+
+// ResetUserTwoFactor 管理员重置用户的双因素认证（如用户丢失设备且恢复码用尽），并注销其全部会话
+func ResetUserTwoFactor(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.InvalidID(c, "用户")
+		return
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, userID).Error; err != nil {
+		utils.UserNotFound(c)
+		return
+	}
+
+	if err := services.DisableTwoFactor(&user); err != nil {
+		log.Printf("重置双因素认证失败: %v", err)
+		utils.InternalServerError(c, "重置双因素认证失败")
+		return
+	}
+	if _, err := utils.RevokeUserSessions(user.ID, ""); err != nil {
+		log.Printf("重置双因素认证后注销用户 %d 的会话失败: %v", user.ID, err)
+	}
+
+	utils.SuccessWithMessage(c, "已重置该用户的双因素认证")
+}
+
+// twoFactorError 双因素认证相关错误返回400，其他错误返回500
+func twoFactorError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrTwoFactorEnabled),
+		errors.Is(err, services.ErrTwoFactorNotEnabled),
+		errors.Is(err, services.ErrTwoFactorSetupExpired),
+		errors.Is(err, services.ErrInvalidTwoFactorCode):
+		utils.BadRequest(c, err.Error())
+	default:
+		log.Printf("%s: %v", message, err)
+		utils.InternalServerError(c, message)
+	}
+}
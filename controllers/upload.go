@@ -2,13 +2,43 @@ package controllers
 
 import (
 	"ai-assistant-backend/config"
+	"ai-assistant-backend/models"
 	"ai-assistant-backend/utils"
 
 	"fmt"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
+// workspacePrefix 当前工作空间的对象名称前缀
+func workspacePrefix(c *gin.Context) (string, bool) {
+	workspace, err := utils.GetWorkspaceFromContext(c)
+	if err != nil {
+		utils.Forbidden(c, "未选择工作空间")
+		return "", false
+	}
+	return utils.WorkspaceObjectPrefix(workspace.WorkspaceID), true
+}
+
+// authorizeObject 校验对象是否属于当前工作空间，超级管理员可以访问全部对象
+func authorizeObject(c *gin.Context, objectName string) bool {
+	if user, err := utils.GetUserFromContext(c); err == nil && user.Role == models.RoleSuperAdmin {
+		return true
+	}
+
+	workspace, err := utils.GetWorkspaceFromContext(c)
+	if err != nil {
+		utils.Forbidden(c, "未选择工作空间")
+		return false
+	}
+	if !utils.ObjectInWorkspace(objectName, workspace.WorkspaceID) {
+		utils.Forbidden(c, "无权访问该文件")
+		return false
+	}
+	return true
+}
+
 // UploadFile 文件上传
 func UploadFile(c *gin.Context) {
 	if config.GlobalConfig == nil {
@@ -23,12 +53,17 @@ func UploadFile(c *gin.Context) {
 		return
 	}
 
+	prefix, ok := workspacePrefix(c)
+	if !ok {
+		return
+	}
+
 	// 使用MinIO上传文件
 	objectName, fileURL, err := utils.UploadFileWithValidation(
 		file,
 		config.GlobalConfig.Upload.AllowedTypes,
 		config.GlobalConfig.Upload.MaxFileSize,
-		"uploads", // 文件前缀
+		prefix+"uploads", // 文件前缀
 	)
 	if err != nil {
 		utils.BadRequestWithDetail(c, "文件上传失败", err.Error())
@@ -51,8 +86,13 @@ func GetPresignedUploadURL(c *gin.Context) {
 		return
 	}
 
+	prefix, ok := workspacePrefix(c)
+	if !ok {
+		return
+	}
+
 	// 生成对象名称
-	objectName := utils.GenerateObjectName(filename, "uploads")
+	objectName := utils.GenerateObjectName(filename, prefix+"uploads")
 
 	// 获取预签名URL
 	uploader := utils.NewMinIOUploader()
@@ -76,6 +116,9 @@ func GetPresignedDownloadURL(c *gin.Context) {
 		utils.BadRequest(c, "对象名称不能为空")
 		return
 	}
+	if !authorizeObject(c, objectName) {
+		return
+	}
 
 	// 获取预签名下载URL
 	uploader := utils.NewMinIOUploader()
@@ -98,6 +141,9 @@ func DeleteFile(c *gin.Context) {
 		utils.BadRequest(c, "对象名称不能为空")
 		return
 	}
+	if !authorizeObject(c, objectName) {
+		return
+	}
 
 	// 删除文件
 	uploader := utils.NewMinIOUploader()
@@ -110,9 +156,18 @@ func DeleteFile(c *gin.Context) {
 	utils.SuccessWithMessage(c, "文件删除成功")
 }
 
-// ListFiles 列出文件
+// ListFiles 列出当前工作空间的文件，prefix为工作空间内的相对前缀
 func ListFiles(c *gin.Context) {
+	workspace, ok := workspacePrefix(c)
+	if !ok {
+		return
+	}
 	prefix := c.Query("prefix")
+	if strings.Contains(prefix, "..") {
+		utils.BadRequest(c, "无效的文件前缀")
+		return
+	}
+	prefix = workspace + strings.TrimPrefix(prefix, "/")
 	recursive := c.Query("recursive") == "true"
 
 	// 列出文件
@@ -147,6 +202,9 @@ func GetFileInfo(c *gin.Context) {
 		utils.BadRequest(c, "对象名称不能为空")
 		return
 	}
+	if !authorizeObject(c, objectName) {
+		return
+	}
 
 	// 获取文件信息
 	uploader := utils.NewMinIOUploader()
@@ -172,6 +230,9 @@ func GetFileAccessURL(c *gin.Context) {
 		utils.BadRequest(c, "对象名称不能为空")
 		return
 	}
+	if !authorizeObject(c, objectName) {
+		return
+	}
 
 	// 获取有效时间参数
 	expireHoursStr := c.Query("expire_hours")
@@ -0,0 +1,159 @@
+package controllers
+
+import (
+	"strconv"
+
+	"ai-assistant-backend/config"
+	"ai-assistant-backend/models"
+	"ai-assistant-backend/services"
+	"ai-assistant-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type UpdateUserRoleRequest struct {
+	Role *uint8 `json:"role" binding:"required"`
+}
+
+type UnlockUserRequest struct {
+	IP string `json:"ip"` // 可选，同时解除该IP的登录限制
+}
+
+// GetUsers 获取用户列表
+func GetUsers(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 10
+	}
+
+	query := config.DB.Model(&models.User{})
+	if keyword := c.Query("keyword"); keyword != "" {
+		query = query.Where("username LIKE ? OR email LIKE ?", "%"+keyword+"%", "%"+keyword+"%")
+	}
+	if role := c.Query("role"); role != "" {
+		roleValue, err := strconv.ParseUint(role, 10, 8)
+		if err != nil {
+			utils.BadRequest(c, "无效的角色")
+			return
+		}
+		query = query.Where("role = ?", roleValue)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		utils.GetFailed(c, "用户列表")
+		return
+	}
+
+	var users []models.User
+	offset := (page - 1) * pageSize
+	if err := query.Offset(offset).Limit(pageSize).Order("id").Find(&users).Error; err != nil {
+		utils.GetFailed(c, "用户列表")
+		return
+	}
+
+	utils.Success(c, gin.H{
+		"users":     users,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	}, "获取成功")
+}
+
+// GetRoles 获取角色及其权限
+func GetRoles(c *gin.Context) {
+	roles := []gin.H{}
+	for _, role := range []uint8{models.RoleSuperAdmin, models.RoleOwner, models.RoleEditor, models.RoleViewer} {
+		roles = append(roles, gin.H{
+			"role":        role,
+			"name":        utils.RoleNames[role],
+			"permissions": utils.RolePermissions(role),
+		})
+	}
+	utils.Success(c, roles, "获取成功")
+}
+
+// UpdateUserRole 修改用户角色
+func UpdateUserRole(c *gin.Context) {
+	operator, err := utils.GetUserFromContext(c)
+	if err != nil {
+		utils.Unauthorized(c, "用户未登录")
+		return
+	}
+
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.InvalidID(c, "用户")
+		return
+	}
+
+	var req UpdateUserRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(c, err.Error())
+		return
+	}
+	if !models.ValidRole(*req.Role) {
+		utils.BadRequest(c, "无效的角色")
+		return
+	}
+
+	// 避免管理员误操作导致没有可用的管理员账号
+	if uint(userID) == operator.UserID {
+		utils.BadRequest(c, "不能修改自己的角色")
+		return
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, userID).Error; err != nil {
+		utils.UserNotFound(c)
+		return
+	}
+
+	if err := config.DB.Model(&user).Update("role", *req.Role).Error; err != nil {
+		utils.UpdateFailed(c, "用户角色")
+		return
+	}
+
+	utils.Success(c, user, "角色修改成功")
+}
+
+// UnlockUser 解除用户因登录失败次数过多导致的锁定
+func UnlockUser(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.InvalidID(c, "用户")
+		return
+	}
+
+	var req UnlockUserRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.ValidationError(c, err.Error())
+			return
+		}
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, userID).Error; err != nil {
+		utils.UserNotFound(c)
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := services.ResetLoginFailures(ctx, user.Username); err != nil {
+		utils.InternalServerError(c, "解除锁定失败")
+		return
+	}
+	if req.IP != "" {
+		if err := services.UnlockLoginIP(ctx, req.IP); err != nil {
+			utils.InternalServerError(c, "解除锁定失败")
+			return
+		}
+	}
+
+	utils.SuccessWithMessage(c, "已解除锁定")
+}
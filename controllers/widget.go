@@ -0,0 +1,80 @@
+package controllers
+
+import (
+	"html/template"
+	"net/http"
+	"strings"
+
+	"ai-assistant-backend/config"
+	"ai-assistant-backend/models"
+	"ai-assistant-backend/services"
+	"ai-assistant-backend/utils"
+	"ai-assistant-backend/web"
+
+	"github.com/gin-gonic/gin"
+)
+
+// widgetScriptCacheControl 对话窗口脚本的缓存策略
+const widgetScriptCacheControl = "public, max-age=3600"
+
+var chatPageTemplate = template.Must(template.ParseFS(web.Assets, "chat.html"))
+
+// chatPageData 托管对话页的模板数据，其余配置由页面通过公开接口获取
+type chatPageData struct {
+	AppID          string
+	Name           string
+	APIBase        string
+	ThemeColor     template.CSS
+	ThemeTextColor template.CSS
+}
+
+// GetWidgetScript 返回客户网站引入的对话窗口脚本
+func GetWidgetScript(c *gin.Context) {
+	script, err := web.Assets.ReadFile("widget.js")
+	if err != nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	c.Header("Cache-Control", widgetScriptCacheControl)
+	c.Data(http.StatusOK, "application/javascript; charset=utf-8", script)
+}
+
+// GetChatPage 返回智能体的托管对话页，可直接访问或由对话窗口脚本以iframe嵌入。
+// 智能体设置了允许的域名时，只允许这些网站以iframe嵌入
+func GetChatPage(c *gin.Context) {
+	var agent models.Agent
+	if err := config.DB.Where("app_id = ?", c.Param("app_id")).First(&agent).Error; err != nil {
+		c.String(http.StatusNotFound, "智能体不存在")
+		return
+	}
+
+	c.Header("Content-Security-Policy", "frame-ancestors "+frameAncestors(agent.AllowedDomains))
+	c.Status(http.StatusOK)
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	chatPageTemplate.Execute(c.Writer, chatPageData{
+		AppID:   agent.AppID,
+		Name:    agent.Name,
+		APIBase: services.PublicBaseURL(),
+		// 颜色保存时已校验为十六进制格式
+		ThemeColor:     template.CSS(agent.ThemeColor),
+		ThemeTextColor: template.CSS(agent.ThemeTextColor),
+	})
+}
+
+// GetAgentEmbed 获取智能体的对话页地址与嵌入代码
+func GetAgentEmbed(c *gin.Context) {
+	agent := currentAgent(c)
+	utils.Success(c, services.AgentWidgetEmbed(agent.AppID), "获取成功")
+}
+
+// frameAncestors 按允许的域名生成CSP frame-ancestors，为空时允许任意网站嵌入
+func frameAncestors(domains []string) string {
+	if len(domains) == 0 {
+		return "*"
+	}
+	sources := []string{"'self'"}
+	for _, domain := range domains {
+		sources = append(sources, "http://"+domain, "https://"+domain)
+	}
+	return strings.Join(sources, " ")
+}
@@ -0,0 +1,296 @@
+package controllers
+
+import (
+	"strconv"
+	"time"
+
+	"ai-assistant-backend/config"
+	"ai-assistant-backend/models"
+	"ai-assistant-backend/services"
+	"ai-assistant-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type WorkspaceRequest struct {
+	Name string `json:"name" binding:"required,max=100"`
+}
+
+type AddWorkspaceMemberRequest struct {
+	Account string `json:"account" binding:"required"` // 用户名或邮箱
+	Role    *uint8 `json:"role" binding:"required"`
+}
+
+type UpdateWorkspaceMemberRequest struct {
+	Role *uint8 `json:"role" binding:"required"`
+}
+
+// WorkspaceMemberInfo 工作空间成员及其用户信息
+type WorkspaceMemberInfo struct {
+	UserID    uint      `json:"user_id"`
+	Username  string    `json:"username"`
+	Email     string    `json:"email"`
+	Role      uint8     `json:"role"`
+	RoleName  string    `json:"role_name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// GetWorkspaces 获取当前用户加入的工作空间
+func GetWorkspaces(c *gin.Context) {
+	user, err := utils.GetUserFromContext(c)
+	if err != nil {
+		utils.Unauthorized(c, "用户未登录")
+		return
+	}
+
+	var members []models.WorkspaceMember
+	if err := config.DB.Where("user_id = ?", user.UserID).Order("id").Find(&members).Error; err != nil {
+		utils.GetFailed(c, "工作空间列表")
+		return
+	}
+
+	workspaceIDs := make([]uint, 0, len(members))
+	for _, member := range members {
+		workspaceIDs = append(workspaceIDs, member.WorkspaceID)
+	}
+	var workspaces []models.Workspace
+	if err := config.DB.Where("id IN ?", workspaceIDs).Find(&workspaces).Error; err != nil {
+		utils.GetFailed(c, "工作空间列表")
+		return
+	}
+	byID := make(map[uint]models.Workspace, len(workspaces))
+	for _, workspace := range workspaces {
+		byID[workspace.ID] = workspace
+	}
+
+	result := []gin.H{}
+	for _, member := range members {
+		workspace, ok := byID[member.WorkspaceID]
+		if !ok {
+			continue
+		}
+		result = append(result, gin.H{
+			"workspace": workspace,
+			"role":      member.Role,
+			"role_name": utils.RoleNames[member.Role],
+			"current":   workspace.ID == user.WorkspaceID,
+		})
+	}
+
+	utils.Success(c, result, "获取成功")
+}
+
+// CreateWorkspace 创建工作空间，创建者成为所有者
+func CreateWorkspace(c *gin.Context) {
+	user, err := utils.GetUserFromContext(c)
+	if err != nil {
+		utils.Unauthorized(c, "用户未登录")
+		return
+	}
+
+	var req WorkspaceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(c, err.Error())
+		return
+	}
+
+	var workspace *models.Workspace
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		workspace, err = services.CreateWorkspace(tx, req.Name, user.UserID)
+		return err
+	})
+	if err != nil {
+		utils.CreateFailed(c, "工作空间")
+		return
+	}
+
+	utils.Success(c, workspace, "创建成功")
+}
+
+// UpdateWorkspace 修改工作空间名称
+func UpdateWorkspace(c *gin.Context) {
+	var req WorkspaceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(c, err.Error())
+		return
+	}
+
+	member, _ := utils.GetWorkspaceFromContext(c)
+	var workspace models.Workspace
+	if err := config.DB.First(&workspace, member.WorkspaceID).Error; err != nil {
+		utils.NotFound(c, "工作空间不存在")
+		return
+	}
+
+	if err := config.DB.Model(&workspace).Update("name", req.Name).Error; err != nil {
+		utils.UpdateFailed(c, "工作空间")
+		return
+	}
+
+	utils.Success(c, workspace, "更新成功")
+}
+
+// SwitchWorkspace 切换当前工作空间，返回携带新工作空间的令牌，之后刷新的令牌沿用该工作空间
+func SwitchWorkspace(c *gin.Context) {
+	user, err := utils.GetUserFromContext(c)
+	if err != nil {
+		utils.Unauthorized(c, "用户未登录")
+		return
+	}
+
+	member, _ := utils.GetWorkspaceFromContext(c)
+	if err := utils.SetSessionWorkspace(user.SessionID, member.WorkspaceID); err != nil {
+		utils.InternalServerError(c, "切换工作空间失败")
+		return
+	}
+	token, err := utils.GenerateToken(user.UserID, user.Username, user.Role, member.WorkspaceID, user.SessionID)
+	if err != nil {
+		utils.InternalServerError(c, "生成令牌失败")
+		return
+	}
+
+	utils.Success(c, gin.H{
+		"token":        token,
+		"workspace_id": member.WorkspaceID,
+		"role":         member.Role,
+	}, "切换成功")
+}
+
+// GetWorkspaceMembers 获取工作空间成员列表
+func GetWorkspaceMembers(c *gin.Context) {
+	member, _ := utils.GetWorkspaceFromContext(c)
+
+	var members []models.WorkspaceMember
+	if err := config.DB.Where("workspace_id = ?", member.WorkspaceID).Order("id").Find(&members).Error; err != nil {
+		utils.GetFailed(c, "成员列表")
+		return
+	}
+
+	userIDs := make([]uint, 0, len(members))
+	for _, item := range members {
+		userIDs = append(userIDs, item.UserID)
+	}
+	var users []models.User
+	if err := config.DB.Where("id IN ?", userIDs).Find(&users).Error; err != nil {
+		utils.GetFailed(c, "成员列表")
+		return
+	}
+	byID := make(map[uint]models.User, len(users))
+	for _, user := range users {
+		byID[user.ID] = user
+	}
+
+	result := []WorkspaceMemberInfo{}
+	for _, item := range members {
+		user := byID[item.UserID]
+		result = append(result, WorkspaceMemberInfo{
+			UserID:    item.UserID,
+			Username:  user.Username,
+			Email:     user.Email,
+			Role:      item.Role,
+			RoleName:  utils.RoleNames[item.Role],
+			CreatedAt: item.CreatedAt,
+		})
+	}
+
+	utils.Success(c, result, "获取成功")
+}
+
+// AddWorkspaceMember 按用户名或邮箱添加已注册用户为工作空间成员
+func AddWorkspaceMember(c *gin.Context) {
+	var req AddWorkspaceMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(c, err.Error())
+		return
+	}
+	if !models.ValidMemberRole(*req.Role) {
+		utils.BadRequest(c, "无效的角色")
+		return
+	}
+
+	var user models.User
+	if err := config.DB.Where("username = ? OR email = ?", req.Account, req.Account).First(&user).Error; err != nil {
+		utils.UserNotFound(c)
+		return
+	}
+
+	current, _ := utils.GetWorkspaceFromContext(c)
+	if _, err := services.GetWorkspaceMember(current.WorkspaceID, user.ID); err == nil {
+		utils.BadRequest(c, "该用户已是工作空间成员")
+		return
+	}
+
+	member := models.WorkspaceMember{WorkspaceID: current.WorkspaceID, UserID: user.ID, Role: *req.Role}
+	if err := config.DB.Create(&member).Error; err != nil {
+		utils.CreateFailed(c, "工作空间成员")
+		return
+	}
+
+	utils.Success(c, member, "添加成功")
+}
+
+// UpdateWorkspaceMember 修改成员角色
+func UpdateWorkspaceMember(c *gin.Context) {
+	var req UpdateWorkspaceMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(c, err.Error())
+		return
+	}
+	if !models.ValidMemberRole(*req.Role) {
+		utils.BadRequest(c, "无效的角色")
+		return
+	}
+
+	member, ok := loadWorkspaceMember(c)
+	if !ok {
+		return
+	}
+	if member.Role == models.RoleOwner && *req.Role != models.RoleOwner && services.CountWorkspaceOwners(member.WorkspaceID) <= 1 {
+		utils.BadRequest(c, services.ErrLastWorkspaceOwner.Error())
+		return
+	}
+
+	if err := config.DB.Model(member).Update("role", *req.Role).Error; err != nil {
+		utils.UpdateFailed(c, "成员角色")
+		return
+	}
+
+	utils.Success(c, member, "角色修改成功")
+}
+
+// RemoveWorkspaceMember 移除工作空间成员
+func RemoveWorkspaceMember(c *gin.Context) {
+	member, ok := loadWorkspaceMember(c)
+	if !ok {
+		return
+	}
+	if member.Role == models.RoleOwner && services.CountWorkspaceOwners(member.WorkspaceID) <= 1 {
+		utils.BadRequest(c, services.ErrLastWorkspaceOwner.Error())
+		return
+	}
+
+	if err := config.DB.Delete(member).Error; err != nil {
+		utils.DeleteFailed(c, "工作空间成员")
+		return
+	}
+
+	utils.SuccessWithMessage(c, "移除成功")
+}
+
+// loadWorkspaceMember 按路径参数user_id获取当前工作空间的成员
+func loadWorkspaceMember(c *gin.Context) (*models.WorkspaceMember, bool) {
+	userID, err := strconv.ParseUint(c.Param("user_id"), 10, 32)
+	if err != nil {
+		utils.InvalidID(c, "用户")
+		return nil, false
+	}
+
+	current, _ := utils.GetWorkspaceFromContext(c)
+	member, err := services.GetWorkspaceMember(current.WorkspaceID, uint(userID))
+	if err != nil {
+		utils.NotFound(c, "成员不存在")
+		return nil, false
+	}
+	return member, true
+}
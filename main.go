@@ -9,12 +9,14 @@ import (
 	"syscall"
 
 	"ai-assistant-backend/config"
+	"ai-assistant-backend/middleware"
 	"ai-assistant-backend/models"
 	"ai-assistant-backend/routes"
+	"ai-assistant-backend/services"
+	"ai-assistant-backend/utils"
 
 	_ "net/http/pprof"
 
-	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 )
 
@@ -42,31 +44,77 @@ func main() {
 		log.Fatal("初始化MinIO失败:", err)
 	}
 
+	// 初始化大模型服务
+	if err := utils.InitLLM(); err != nil {
+		log.Fatal("初始化大模型服务失败:", err)
+	}
+
+	// 初始化邮件服务
+	if err := utils.InitMailer(); err != nil {
+		log.Fatal("初始化邮件服务失败:", err)
+	}
+
+	// AppID添加唯一索引前处理重复数据
+	if err := services.MigrateAgentAppIDs(); err != nil {
+		log.Printf("处理重复的智能体AppID失败: %v", err)
+	}
+
 	// 自动迁移数据库表
 	config.DB.AutoMigrate(
 		&models.User{},
+		&models.Workspace{},
+		&models.WorkspaceMember{},
+		&models.Invitation{},
+		&models.APIKey{},
 		&models.Agent{},
+		&models.AgentVersion{},
 		&models.AgentCarouselImage{},
 		&models.SelfService{},
 		&models.DocumentCategory{},
 		&models.Document{},
+		&models.DocumentChunk{},
 		&models.DocumentTag{},
 		&models.Tag{},
 		&models.FAQCategory{},
 		&models.FAQ{},
+		&models.Conversation{},
+		&models.Message{},
+		&models.Setting{},
 	)
 
+	// 为升级前的用户和智能体补充工作空间
+	if err := services.MigrateWorkspaces(); err != nil {
+		log.Printf("迁移工作空间数据失败: %v", err)
+	}
+
+	// 升级前上传的文件迁移到所属工作空间
+	if err := services.MigrateLegacyObjects(); err != nil {
+		log.Printf("迁移文件到工作空间失败: %v", err)
+	}
+
+	// 标签名称改为同一智能体内唯一
+	if err := services.MigrateTagUniqueIndex(); err != nil {
+		log.Printf("迁移标签唯一索引失败: %v", err)
+	}
+
+	// 为升级前已上线的智能体发布初始版本
+	if err := services.MigrateAgentVersions(); err != nil {
+		log.Printf("发布智能体初始版本失败: %v", err)
+	}
+
+	// 启动文档解析任务
+	services.StartIngestionWorkers()
+
 	// 创建Gin实例
 	router := gin.Default()
 
-	// 配置CORS
-	corsConfig := cors.Config{
-		AllowOrigins:     config.GlobalConfig.Server.CORS.AllowedOrigins,
-		AllowHeaders:     config.GlobalConfig.Server.CORS.AllowedHeaders,
-		AllowMethods:     config.GlobalConfig.Server.CORS.AllowedMethods,
-		AllowCredentials: true,
+	// 只信任配置的反向代理转发的客户端IP，未配置时忽略X-Forwarded-For等请求头
+	if err := router.SetTrustedProxies(config.GlobalConfig.Server.TrustedProxies); err != nil {
+		log.Fatal("配置可信代理失败:", err)
 	}
-	router.Use(cors.New(corsConfig))
+
+	// 配置CORS，对话等公开接口由智能体的允许域名控制
+	router.Use(middleware.CORS())
 
 	// 设置路由
 	routes.SetupAuthRoutes(router)
@@ -74,6 +122,12 @@ func main() {
 	routes.SetupDocumentRoutes(router)
 	routes.SetupFAQRoutes(router)
 	routes.SetupUploadRoutes(router)
+	routes.SetupChatRoutes(router)
+	routes.SetupPublicRoutes(router)
+	routes.SetupWidgetRoutes(router)
+	routes.SetupWorkspaceRoutes(router)
+	routes.SetupAPIKeyRoutes(router)
+	routes.SetupAdminRoutes(router)
 
 	// 健康检查接口
 	router.GET("/health", func(c *gin.Context) {
@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"strconv"
+
+	"ai-assistant-backend/config"
+	"ai-assistant-backend/models"
+	"ai-assistant-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LoadAgent 按路径参数id加载智能体，校验当前用户的访问权限后存入上下文
+func LoadAgent() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		agentID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			utils.InvalidID(c, "智能体")
+			c.Abort()
+			return
+		}
+		authorizeAgent(c, uint(agentID))
+	}
+}
+
+// LoadAgentFromQuery 按查询参数agent_id加载智能体，校验当前用户的访问权限后存入上下文
+func LoadAgentFromQuery() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		agentID := c.Query("agent_id")
+		if agentID == "" {
+			utils.BadRequest(c, "缺少智能体ID参数")
+			c.Abort()
+			return
+		}
+		agentIDUint, err := strconv.ParseUint(agentID, 10, 32)
+		if err != nil {
+			utils.InvalidID(c, "智能体")
+			c.Abort()
+			return
+		}
+		authorizeAgent(c, uint(agentIDUint))
+	}
+}
+
+// LoadAgentOf 按路径参数id找到资源（如文档、常见问答）所属的智能体，校验当前用户的访问权限后存入上下文。
+// model为资源模型，需包含agent_id字段；resource为资源名称，用于错误提示
+func LoadAgentOf(model interface{}, resource string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		resourceID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			utils.InvalidID(c, resource)
+			c.Abort()
+			return
+		}
+
+		var agentIDs []uint
+		if err := config.DB.Model(model).Where("id = ?", resourceID).Limit(1).Pluck("agent_id", &agentIDs).Error; err != nil || len(agentIDs) == 0 {
+			utils.NotFound(c, resource+"不存在")
+			c.Abort()
+			return
+		}
+		authorizeAgent(c, agentIDs[0])
+	}
+}
+
+// authorizeAgent 智能体不存在返回404，不属于当前工作空间且当前用户不是超级管理员时返回403。
+// 需在WorkspaceMiddleware与RequirePermission之后使用
+func authorizeAgent(c *gin.Context, agentID uint) {
+	user, err := utils.GetUserFromContext(c)
+	if err != nil {
+		utils.Unauthorized(c, "用户未登录")
+		c.Abort()
+		return
+	}
+
+	var agent models.Agent
+	if err := config.DB.First(&agent, agentID).Error; err != nil {
+		utils.AgentNotFound(c)
+		c.Abort()
+		return
+	}
+	member, err := utils.GetWorkspaceFromContext(c)
+	if err != nil {
+		utils.Forbidden(c, "未选择工作空间")
+		c.Abort()
+		return
+	}
+	if agent.WorkspaceID != member.WorkspaceID && user.Role != models.RoleSuperAdmin {
+		utils.Forbidden(c, "无权访问该智能体")
+		c.Abort()
+		return
+	}
+
+	utils.SetAgentToContext(c, &agent)
+	c.Next()
+}
@@ -0,0 +1,127 @@
+package middleware_test
+
+import (
+	"net/http"
+	"testing"
+
+	"ai-assistant-backend/config"
+	"ai-assistant-backend/models"
+)
+
+// agentFixture 两个工作空间各有一个智能体、文档与常见问答
+type agentFixture struct {
+	editor     *models.User
+	outsider   *models.User
+	superAdmin *models.User
+	workspace  *models.Workspace
+	agent      *models.Agent
+	document   *models.Document
+	faq        *models.FAQ
+	other      *models.Workspace
+	otherAgent *models.Agent
+	otherDoc   *models.Document
+	otherFAQ   *models.FAQ
+}
+
+func newAgentFixture(t *testing.T, name string) *agentFixture {
+	t.Helper()
+	f := &agentFixture{
+		editor:     createUser(t, name+"-editor", models.RoleEditor, false),
+		outsider:   createUser(t, name+"-outsider", models.RoleEditor, false),
+		superAdmin: createUser(t, name+"-admin", models.RoleSuperAdmin, true),
+	}
+	f.workspace = createWorkspace(t, name, map[*models.User]uint8{f.editor: models.RoleEditor})
+	f.other = createWorkspace(t, name+"-other", map[*models.User]uint8{f.outsider: models.RoleEditor})
+	f.agent, f.document, f.faq = createAgentData(t, name, f.workspace.ID)
+	f.otherAgent, f.otherDoc, f.otherFAQ = createAgentData(t, name+"-other", f.other.ID)
+	return f
+}
+
+// createAgentData 在工作空间中创建智能体、待解析的文档与常见问答
+func createAgentData(t *testing.T, appID string, workspaceID uint) (*models.Agent, *models.Document, *models.FAQ) {
+	t.Helper()
+	agent := &models.Agent{AppID: appID, Name: appID, WorkspaceID: workspaceID}
+	if err := config.DB.Create(agent).Error; err != nil {
+		t.Fatal(err)
+	}
+	document := &models.Document{AgentID: agent.ID, Name: "手册.pdf", Status: models.DocumentStatusPending}
+	if err := config.DB.Create(document).Error; err != nil {
+		t.Fatal(err)
+	}
+	faq := &models.FAQ{AgentID: agent.ID, Question: "问题", Answer: "答案"}
+	if err := config.DB.Create(faq).Error; err != nil {
+		t.Fatal(err)
+	}
+	return agent, document, faq
+}
+
+func TestLoadAgent(t *testing.T) {
+	f := newAgentFixture(t, "load-agent")
+	editor := loginToken(t, f.editor, f.workspace.ID)
+
+	assertStatus(t, request(http.MethodGet, "/api/agents/"+id(f.agent.ID), editor, "", nil), http.StatusOK)
+	// 其他工作空间的智能体
+	assertStatus(t, request(http.MethodGet, "/api/agents/"+id(f.otherAgent.ID), editor, "", nil), http.StatusForbidden)
+	assertStatus(t, request(http.MethodGet, "/api/agents/999999", editor, "", nil), http.StatusNotFound)
+	assertStatus(t, request(http.MethodGet, "/api/agents/abc", editor, "", nil), http.StatusBadRequest)
+
+	// 切换到未加入的工作空间
+	switched := request(http.MethodGet, "/api/agents/"+id(f.otherAgent.ID), editor, "", map[string]string{"X-Workspace-ID": id(f.other.ID)})
+	assertStatus(t, switched, http.StatusForbidden)
+
+	// 非成员
+	outsider := loginToken(t, f.outsider, f.other.ID)
+	assertStatus(t, request(http.MethodGet, "/api/agents/"+id(f.agent.ID), outsider, "", nil), http.StatusForbidden)
+	assertStatus(t, request(http.MethodGet, "/api/agents/"+id(f.agent.ID), outsider, "", map[string]string{"X-Workspace-ID": id(f.workspace.ID)}), http.StatusForbidden)
+
+	// 超级管理员可访问任意工作空间的智能体
+	admin := loginToken(t, f.superAdmin, f.other.ID)
+	assertStatus(t, request(http.MethodGet, "/api/agents/"+id(f.agent.ID), admin, "", nil), http.StatusOK)
+	assertStatus(t, request(http.MethodGet, "/api/agents/999999", admin, "", nil), http.StatusNotFound)
+}
+
+func TestLoadAgentFromQuery(t *testing.T) {
+	f := newAgentFixture(t, "load-query")
+	editor := loginToken(t, f.editor, f.workspace.ID)
+
+	for _, path := range []string{"/api/documents", "/api/faqs"} {
+		assertStatus(t, request(http.MethodGet, path+"?agent_id="+id(f.agent.ID), editor, "", nil), http.StatusOK)
+		assertStatus(t, request(http.MethodGet, path+"?agent_id="+id(f.otherAgent.ID), editor, "", nil), http.StatusForbidden)
+		assertStatus(t, request(http.MethodGet, path+"?agent_id=999999", editor, "", nil), http.StatusNotFound)
+		assertStatus(t, request(http.MethodGet, path, editor, "", nil), http.StatusBadRequest)
+
+		admin := loginToken(t, f.superAdmin, f.other.ID)
+		assertStatus(t, request(http.MethodGet, path+"?agent_id="+id(f.agent.ID), admin, "", nil), http.StatusOK)
+	}
+}
+
+func TestLoadAgentOf(t *testing.T) {
+	f := newAgentFixture(t, "load-of")
+	editor := loginToken(t, f.editor, f.workspace.ID)
+
+	// 通过校验后由接口处理：文档正在解析中、请求体为空均返回400
+	assertStatus(t, request(http.MethodPost, "/api/documents/"+id(f.document.ID)+"/ingest", editor, "", nil), http.StatusBadRequest)
+	assertStatus(t, request(http.MethodPut, "/api/faqs/"+id(f.faq.ID), editor, "", nil), http.StatusBadRequest)
+
+	// 其他工作空间的文档与常见问答
+	assertStatus(t, request(http.MethodPost, "/api/documents/"+id(f.otherDoc.ID)+"/ingest", editor, "", nil), http.StatusForbidden)
+	assertStatus(t, request(http.MethodDelete, "/api/documents/"+id(f.otherDoc.ID), editor, "", nil), http.StatusForbidden)
+	assertStatus(t, request(http.MethodPut, "/api/faqs/"+id(f.otherFAQ.ID), editor, `{"question":"改","answer":"改"}`, nil), http.StatusForbidden)
+	assertStatus(t, request(http.MethodDelete, "/api/faqs/"+id(f.otherFAQ.ID), editor, "", nil), http.StatusForbidden)
+
+	// 其他工作空间的数据未被修改
+	var document models.Document
+	config.DB.First(&document, f.otherDoc.ID)
+	var faq models.FAQ
+	config.DB.First(&faq, f.otherFAQ.ID)
+	if document.ID == 0 || faq.Question != "问题" {
+		t.Errorf("其他工作空间的数据被修改: %+v %+v", document, faq)
+	}
+
+	assertStatus(t, request(http.MethodDelete, "/api/documents/999999", editor, "", nil), http.StatusNotFound)
+	assertStatus(t, request(http.MethodDelete, "/api/faqs/999999", editor, "", nil), http.StatusNotFound)
+
+	admin := loginToken(t, f.superAdmin, f.other.ID)
+	assertStatus(t, request(http.MethodPost, "/api/documents/"+id(f.document.ID)+"/ingest", admin, "", nil), http.StatusBadRequest)
+	assertStatus(t, request(http.MethodPut, "/api/faqs/"+id(f.faq.ID), admin, "", nil), http.StatusBadRequest)
+}
@@ -1,16 +1,41 @@
 package middleware
 
 import (
+	"errors"
 	"net/http"
 	"strings"
 
+	"ai-assistant-backend/services"
 	"ai-assistant-backend/utils"
 
 	"github.com/gin-gonic/gin"
 )
 
+// APIKeyHeader 使用API密钥认证的请求头
+const APIKeyHeader = "X-API-Key"
+
+// AuthMiddleware 校验Bearer访问令牌，同时接受请求头X-API-Key中的API密钥
 func AuthMiddleware() gin.HandlerFunc {
+	return authenticate(true)
+}
+
+// SessionAuthMiddleware 只接受登录会话的访问令牌，用于账号、成员、密钥管理等不允许API密钥调用的接口
+func SessionAuthMiddleware() gin.HandlerFunc {
+	return authenticate(false)
+}
+
+func authenticate(allowAPIKey bool) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if key := c.GetHeader(APIKeyHeader); key != "" {
+			if !allowAPIKey {
+				utils.Forbidden(c, "该接口不支持API密钥访问")
+				c.Abort()
+				return
+			}
+			authenticateAPIKey(c, key)
+			return
+		}
+
 		// 获取Authorization header
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -37,6 +62,14 @@ func AuthMiddleware() gin.HandlerFunc {
 
 		// 解析token
 		claims, err := utils.ParseToken(tokenString)
+		if utils.IsTokenExpired(err) {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"code":    401,
+				"message": "认证令牌已过期",
+			})
+			c.Abort()
+			return
+		}
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"code":    401,
@@ -46,9 +79,9 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		// 验证token是否在Redis中存在
-		valid, err := utils.ValidateToken(claims.UserID, tokenString)
-		if err != nil || !valid {
+		// 验证令牌所属的会话未被注销
+		session, err := utils.ValidateSession(claims)
+		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"code":    401,
 				"message": "认证令牌已失效，请重新登录",
@@ -57,8 +90,40 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		utils.TouchSession(session, c.ClientIP())
+
 		// 使用公共方法将完整的用户对象存储到上下文中
 		utils.SetUserToContext(c, claims)
 		c.Next()
 	}
 }
+
+// authenticateAPIKey 以API密钥创建者的身份访问密钥所属的工作空间
+func authenticateAPIKey(c *gin.Context, key string) {
+	apiKey, user, err := services.AuthenticateAPIKey(key)
+	if errors.Is(err, services.ErrAPIKeyExpired) || errors.Is(err, services.ErrAPIKeyInvalid) {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"code":    401,
+			"message": err.Error(),
+		})
+		c.Abort()
+		return
+	}
+	if err != nil {
+		utils.InternalServerError(c, "验证API密钥失败")
+		c.Abort()
+		return
+	}
+
+	services.TouchAPIKey(apiKey, c.ClientIP())
+
+	utils.SetUserToContext(c, &utils.Claims{
+		UserID:      user.ID,
+		Username:    user.Username,
+		Role:        user.Role,
+		WorkspaceID: apiKey.WorkspaceID,
+		APIKeyID:    apiKey.ID,
+		Scopes:      apiKey.Scopes,
+	})
+	c.Next()
+}
@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"ai-assistant-backend/config"
+
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+)
+
+// publicPathPrefixes 嵌入在客户网站中调用的公开接口与对话窗口资源，不使用后台的跨域白名单，
+// 由接口按智能体的允许域名校验请求来源
+var publicPathPrefixes = []string{"/api/public/", "/api/chat/", "/widget.js", "/chat/"}
+
+// CORS 后台接口按配置的白名单处理跨域，公开接口允许任意来源发起跨域请求（不携带凭证）
+func CORS() gin.HandlerFunc {
+	cfg := config.GlobalConfig.Server.CORS
+	admin := cors.New(cors.Config{
+		AllowOrigins:     cfg.AllowedOrigins,
+		AllowHeaders:     cfg.AllowedHeaders,
+		AllowMethods:     cfg.AllowedMethods,
+		AllowCredentials: true,
+	})
+
+	return func(c *gin.Context) {
+		if isPublicPath(c.Request.URL.Path) {
+			publicCORS(c)
+			return
+		}
+		admin(c)
+	}
+}
+
+func isPublicPath(path string) bool {
+	for _, prefix := range publicPathPrefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// publicCORS 公开接口的响应（包括是否允许接入）取决于请求来源，无论是否携带Origin都需设置Vary，
+// 避免共享缓存将不带Origin的响应返回给跨域请求
+func publicCORS(c *gin.Context) {
+	header := c.Writer.Header()
+	header.Add("Vary", "Origin")
+
+	origin := c.GetHeader("Origin")
+	if origin == "" {
+		c.Next()
+		return
+	}
+
+	header.Set("Access-Control-Allow-Origin", origin)
+	header.Set("Access-Control-Expose-Headers", "ETag")
+
+	if c.Request.Method == http.MethodOptions {
+		header.Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		header.Set("Access-Control-Allow-Headers", "Content-Type, If-None-Match")
+		header.Set("Access-Control-Max-Age", "600")
+		c.AbortWithStatus(http.StatusNoContent)
+		return
+	}
+	c.Next()
+}
@@ -0,0 +1,72 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"ai-assistant-backend/config"
+	"ai-assistant-backend/middleware"
+	"ai-assistant-backend/models"
+	"ai-assistant-backend/routes"
+	"ai-assistant-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestPublicCORSVaryOrigin(t *testing.T) {
+	config.GlobalConfig.Server.CORS = config.CORSConfig{
+		AllowedOrigins: []string{"https://admin.example.com"},
+		AllowedHeaders: []string{"Authorization", "Content-Type"},
+		AllowedMethods: []string{"GET", "POST"},
+	}
+	t.Cleanup(func() { config.GlobalConfig.Server.CORS = config.CORSConfig{} })
+	router := gin.New()
+	router.Use(middleware.CORS())
+	routes.SetupPublicRoutes(router)
+
+	owner := createUser(t, "cors-owner", models.RoleViewer, false)
+	agent := &models.Agent{AppID: "cors-agent", Name: "客服", UserID: owner.ID, Status: "online", AllowedDomains: []string{"shop.example.com"}}
+	if err := config.DB.Create(agent).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := services.PublishAgent(agent, owner.ID, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	path := "/api/public/agents/cors-agent/config"
+	tests := []struct {
+		name       string
+		method     string
+		origin     string
+		wantStatus int
+		wantAllow  string
+	}{
+		// 不带Origin的响应同样可被共享缓存，必须声明随Origin变化
+		{"无Origin", http.MethodGet, "", http.StatusOK, ""},
+		{"允许的来源", http.MethodGet, "https://shop.example.com", http.StatusOK, "https://shop.example.com"},
+		{"不允许的来源", http.MethodGet, "https://evil.com", http.StatusForbidden, "https://evil.com"},
+		{"预检请求", http.MethodOptions, "https://shop.example.com", http.StatusNoContent, "https://shop.example.com"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, path, nil)
+		if tt.origin != "" {
+			req.Header.Set("Origin", tt.origin)
+		}
+		if tt.method == http.MethodOptions {
+			req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+		}
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+
+		if recorder.Code != tt.wantStatus {
+			t.Errorf("%s: 状态码 = %d，期望 %d", tt.name, recorder.Code, tt.wantStatus)
+		}
+		if vary := recorder.Header().Values("Vary"); len(vary) != 1 || vary[0] != "Origin" {
+			t.Errorf("%s: Vary = %v，期望 [Origin]", tt.name, vary)
+		}
+		if got := recorder.Header().Get("Access-Control-Allow-Origin"); got != tt.wantAllow {
+			t.Errorf("%s: Access-Control-Allow-Origin = %q，期望 %q", tt.name, got, tt.wantAllow)
+		}
+	}
+}
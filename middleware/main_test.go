@@ -0,0 +1,146 @@
+package middleware_test
+
+import (
+	"log"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"ai-assistant-backend/config"
+	"ai-assistant-backend/models"
+	"ai-assistant-backend/routes"
+	"ai-assistant-backend/utils"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"github.com/go-redis/redis/v8"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testRouter 注册了真实路由的测试服务
+var testRouter *gin.Engine
+
+// TestMain 使用内存SQLite与miniredis代替MySQL和Redis，并注册与线上相同的路由
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	config.GlobalConfig = &config.Config{}
+	config.GlobalConfig.JWT.Secret = "test-secret"
+
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		log.Fatal("打开测试数据库失败:", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		log.Fatal("打开测试数据库失败:", err)
+	}
+	// 内存数据库只在同一连接内可见
+	sqlDB.SetMaxOpenConns(1)
+	err = db.AutoMigrate(
+		&models.User{},
+		&models.Workspace{},
+		&models.WorkspaceMember{},
+		&models.APIKey{},
+		&models.Setting{},
+		&models.Agent{},
+		&models.AgentCarouselImage{},
+		&models.SelfService{},
+		&models.DocumentCategory{},
+		&models.Document{},
+		&models.DocumentTag{},
+		&models.Tag{},
+		&models.FAQCategory{},
+		&models.FAQ{},
+		&models.AgentVersion{},
+	)
+	if err != nil {
+		log.Fatal("迁移测试数据库失败:", err)
+	}
+	config.DB = db
+
+	redisServer, err := miniredis.Run()
+	if err != nil {
+		log.Fatal("启动测试Redis失败:", err)
+	}
+	config.RedisClient = redis.NewClient(&redis.Options{Addr: redisServer.Addr()})
+
+	testRouter = gin.New()
+	routes.SetupAgentRoutes(testRouter)
+	routes.SetupDocumentRoutes(testRouter)
+	routes.SetupFAQRoutes(testRouter)
+
+	code := m.Run()
+	redisServer.Close()
+	os.Exit(code)
+}
+
+// createUser 创建用户，totp为true时视为已启用双因素认证
+func createUser(t *testing.T, username string, role uint8, totp bool) *models.User {
+	t.Helper()
+	user := &models.User{Username: username, Password: "x", Email: username + "@example.com", Role: role, TOTPEnabled: totp}
+	if err := config.DB.Create(user).Error; err != nil {
+		t.Fatalf("创建用户失败: %v", err)
+	}
+	return user
+}
+
+// createWorkspace 创建工作空间并按成员角色加入成员
+func createWorkspace(t *testing.T, name string, members map[*models.User]uint8) *models.Workspace {
+	t.Helper()
+	workspace := &models.Workspace{Name: name}
+	if err := config.DB.Create(workspace).Error; err != nil {
+		t.Fatal(err)
+	}
+	for user, role := range members {
+		member := &models.WorkspaceMember{WorkspaceID: workspace.ID, UserID: user.ID, Role: role}
+		if err := config.DB.Create(member).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	return workspace
+}
+
+// loginToken 为用户创建登录会话并返回访问令牌
+func loginToken(t *testing.T, user *models.User, workspaceID uint) string {
+	t.Helper()
+	session, _, err := utils.CreateSession(user.ID, workspaceID, "test", "127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := utils.GenerateToken(user.ID, user.Username, user.Role, workspaceID, session.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+// request 发送请求，headers为额外的请求头
+func request(method, path, token, body string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	recorder := httptest.NewRecorder()
+	testRouter.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func id(value uint) string {
+	return strconv.FormatUint(uint64(value), 10)
+}
+
+// assertStatus 校验响应状态码
+func assertStatus(t *testing.T, recorder *httptest.ResponseRecorder, want int) {
+	t.Helper()
+	if recorder.Code != want {
+		t.Errorf("状态码 = %d，期望 %d，响应: %s", recorder.Code, want, recorder.Body.String())
+	}
+}
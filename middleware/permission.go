@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"ai-assistant-backend/config"
+	"ai-assistant-backend/models"
+	"ai-assistant-backend/services"
+	"ai-assistant-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequirePermission 校验当前用户是否拥有指定权限，需在AuthMiddleware之后使用。
+// 超级管理员拥有全部权限；其他用户需同时满足全局角色与当前工作空间成员角色的权限。
+// 角色以数据库中的最新值为准，修改角色后无需重新登录即可生效。
+// 角色被要求启用双因素认证而用户尚未启用时拒绝访问；使用API密钥时还需密钥包含该权限范围
+func RequirePermission(permission utils.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, err := utils.GetUserFromContext(c)
+		if err != nil {
+			utils.Unauthorized(c, "用户未登录")
+			c.Abort()
+			return
+		}
+
+		var current models.User
+		if err := config.DB.Select("id", "role", "totp_enabled").First(&current, user.UserID).Error; err != nil {
+			utils.Unauthorized(c, "用户不存在")
+			c.Abort()
+			return
+		}
+		user.Role = current.Role
+
+		// 角色要求双因素认证但尚未启用时，只能访问双因素认证设置相关接口
+		if !current.TOTPEnabled && services.TwoFactorRequired(user.Role) {
+			utils.Forbidden(c, "当前角色要求启用双因素认证，请先完成设置")
+			c.Abort()
+			return
+		}
+
+		// API密钥还需包含对应的权限范围
+		if user.APIKeyID != 0 && !user.HasScope(permission) {
+			utils.Forbidden(c, "API密钥没有该权限: "+string(permission))
+			c.Abort()
+			return
+		}
+
+		if user.Role == models.RoleSuperAdmin {
+			c.Next()
+			return
+		}
+
+		allowed := utils.HasPermission(user.Role, permission)
+		if member, err := utils.GetWorkspaceFromContext(c); err == nil {
+			allowed = allowed && utils.HasPermission(member.Role, permission)
+		}
+		if !allowed {
+			utils.Forbidden(c, "没有操作权限")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
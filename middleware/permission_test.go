@@ -0,0 +1,169 @@
+package middleware_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"ai-assistant-backend/config"
+	"ai-assistant-backend/middleware"
+	"ai-assistant-backend/models"
+	"ai-assistant-backend/services"
+)
+
+// permissionProbe 需要指定权限的接口，通过权限校验后因请求不完整返回probeStatus
+type permissionProbe struct {
+	permission  string
+	method      string
+	path        func(f *agentFixture) string
+	probeStatus int
+}
+
+var permissionProbes = []permissionProbe{
+	{"agents:read", http.MethodGet, func(f *agentFixture) string { return "/api/agents/" + id(f.agent.ID) }, http.StatusOK},
+	{"agents:write", http.MethodPut, func(f *agentFixture) string { return "/api/agents/" + id(f.agent.ID) }, http.StatusBadRequest},
+	{"documents:read", http.MethodGet, func(f *agentFixture) string { return "/api/documents?agent_id=" + id(f.agent.ID) }, http.StatusOK},
+	{"documents:write", http.MethodPost, func(f *agentFixture) string { return "/api/documents/" + id(f.document.ID) + "/ingest" }, http.StatusBadRequest},
+	{"faq:read", http.MethodGet, func(f *agentFixture) string { return "/api/faqs?agent_id=" + id(f.agent.ID) }, http.StatusOK},
+	{"faq:write", http.MethodPut, func(f *agentFixture) string { return "/api/faqs/" + id(f.faq.ID) }, http.StatusBadRequest},
+}
+
+func TestRequirePermissionRoles(t *testing.T) {
+	roles := map[uint8]string{models.RoleOwner: "owner", models.RoleEditor: "editor", models.RoleViewer: "viewer"}
+	// 各角色拥有的写权限，读权限所有角色都有
+	writable := map[uint8]map[string]bool{
+		models.RoleOwner:  {"agents:write": true, "documents:write": true, "faq:write": true},
+		models.RoleEditor: {"documents:write": true, "faq:write": true},
+		models.RoleViewer: {},
+	}
+	allowed := func(role uint8, permission string) bool {
+		return permission == "agents:read" || permission == "documents:read" || permission == "faq:read" || writable[role][permission]
+	}
+
+	for userRole, userRoleName := range roles {
+		for memberRole, memberRoleName := range roles {
+			name := fmt.Sprintf("perm-%s-%s", userRoleName, memberRoleName)
+			f := newAgentFixture(t, name)
+			user := createUser(t, name+"-user", userRole, false)
+			config.DB.Create(&models.WorkspaceMember{WorkspaceID: f.workspace.ID, UserID: user.ID, Role: memberRole})
+			token := loginToken(t, user, f.workspace.ID)
+
+			for _, probe := range permissionProbes {
+				// 需同时满足全局角色与成员角色的权限
+				want := http.StatusForbidden
+				if allowed(userRole, probe.permission) && allowed(memberRole, probe.permission) {
+					want = probe.probeStatus
+				}
+				recorder := request(probe.method, probe.path(f), token, "", nil)
+				if recorder.Code != want {
+					t.Errorf("用户角色%s 成员角色%s %s: 状态码 = %d，期望 %d", userRoleName, memberRoleName, probe.permission, recorder.Code, want)
+				}
+			}
+		}
+	}
+}
+
+func TestRequirePermissionSuperAdmin(t *testing.T) {
+	f := newAgentFixture(t, "perm-super")
+	// 以只读成员身份加入的超级管理员仍拥有全部权限
+	config.DB.Create(&models.WorkspaceMember{WorkspaceID: f.workspace.ID, UserID: f.superAdmin.ID, Role: models.RoleViewer})
+	for _, workspaceID := range []uint{f.workspace.ID, f.other.ID} {
+		token := loginToken(t, f.superAdmin, workspaceID)
+		for _, probe := range permissionProbes {
+			recorder := request(probe.method, probe.path(f), token, "", nil)
+			if recorder.Code != probe.probeStatus {
+				t.Errorf("工作空间%d %s: 状态码 = %d，期望 %d", workspaceID, probe.permission, recorder.Code, probe.probeStatus)
+			}
+		}
+	}
+}
+
+func TestRequirePermissionUsesCurrentRole(t *testing.T) {
+	f := newAgentFixture(t, "perm-demoted")
+	user := createUser(t, "perm-demoted-user", models.RoleOwner, false)
+	config.DB.Create(&models.WorkspaceMember{WorkspaceID: f.workspace.ID, UserID: user.ID, Role: models.RoleOwner})
+	token := loginToken(t, user, f.workspace.ID)
+	path := "/api/agents/" + id(f.agent.ID)
+
+	assertStatus(t, request(http.MethodPut, path, token, "", nil), http.StatusBadRequest)
+	// 降级后令牌中的角色不再生效
+	config.DB.Model(user).Update("role", models.RoleViewer)
+	assertStatus(t, request(http.MethodPut, path, token, "", nil), http.StatusForbidden)
+	assertStatus(t, request(http.MethodGet, path, token, "", nil), http.StatusOK)
+}
+
+func TestRequirePermissionAPIKeyScopes(t *testing.T) {
+	f := newAgentFixture(t, "perm-api-key")
+	owner := createUser(t, "perm-api-key-owner", models.RoleOwner, false)
+	member := &models.WorkspaceMember{WorkspaceID: f.workspace.ID, UserID: owner.ID, Role: models.RoleOwner}
+	config.DB.Create(member)
+
+	_, key, err := services.CreateAPIKey(member, owner, "ci", []string{"agents:read", "documents:write"}, nil)
+	if err != nil {
+		t.Fatalf("创建API密钥失败: %v", err)
+	}
+	headers := map[string]string{middleware.APIKeyHeader: key}
+
+	for _, probe := range permissionProbes {
+		want := http.StatusForbidden
+		if probe.permission == "agents:read" || probe.permission == "documents:write" {
+			want = probe.probeStatus
+		}
+		recorder := request(probe.method, probe.path(f), "", "", headers)
+		if recorder.Code != want {
+			t.Errorf("%s: 状态码 = %d，期望 %d，响应: %s", probe.permission, recorder.Code, want, recorder.Body.String())
+		}
+	}
+
+	// 权限范围不超过创建者当前的角色
+	config.DB.Model(member).Update("role", models.RoleViewer)
+	assertStatus(t, request(http.MethodPost, "/api/documents/"+id(f.document.ID)+"/ingest", "", "", headers), http.StatusForbidden)
+
+	// 只能访问所属工作空间
+	assertStatus(t, request(http.MethodGet, "/api/agents/"+id(f.otherAgent.ID), "", "", headers), http.StatusForbidden)
+	otherWorkspace := map[string]string{middleware.APIKeyHeader: key, middleware.WorkspaceHeader: id(f.other.ID)}
+	assertStatus(t, request(http.MethodGet, "/api/agents/"+id(f.otherAgent.ID), "", "", otherWorkspace), http.StatusForbidden)
+
+	// 过期与无效的密钥
+	expired := time.Now().Add(-time.Minute)
+	_, expiredKey, err := services.CreateAPIKey(member, owner, "expired", []string{"agents:read"}, &expired)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertStatus(t, request(http.MethodGet, "/api/agents/"+id(f.agent.ID), "", "", map[string]string{middleware.APIKeyHeader: expiredKey}), http.StatusUnauthorized)
+	assertStatus(t, request(http.MethodGet, "/api/agents/"+id(f.agent.ID), "", "", map[string]string{middleware.APIKeyHeader: "ak_invalid"}), http.StatusUnauthorized)
+}
+
+func TestRequirePermissionTwoFactorGate(t *testing.T) {
+	previous := config.GlobalConfig.Security.TwoFactorRoles
+	config.GlobalConfig.Security.TwoFactorRoles = []uint8{models.RoleSuperAdmin, models.RoleEditor}
+	t.Cleanup(func() { config.GlobalConfig.Security.TwoFactorRoles = previous })
+
+	f := newAgentFixture(t, "perm-2fa")
+	path := "/api/agents/" + id(f.agent.ID)
+
+	// 要求双因素认证的角色未启用时拒绝访问
+	assertStatus(t, request(http.MethodGet, path, loginToken(t, f.editor, f.workspace.ID), "", nil), http.StatusForbidden)
+	admin := createUser(t, "perm-2fa-admin-no-totp", models.RoleSuperAdmin, false)
+	assertStatus(t, request(http.MethodGet, path, loginToken(t, admin, f.workspace.ID), "", nil), http.StatusForbidden)
+
+	// 启用后可以访问
+	config.DB.Model(f.editor).Update("totp_enabled", true)
+	assertStatus(t, request(http.MethodGet, path, loginToken(t, f.editor, f.workspace.ID), "", nil), http.StatusOK)
+	assertStatus(t, request(http.MethodGet, path, loginToken(t, f.superAdmin, f.workspace.ID), "", nil), http.StatusOK)
+
+	// 不要求双因素认证的角色不受影响
+	viewer := createUser(t, "perm-2fa-viewer", models.RoleViewer, false)
+	config.DB.Create(&models.WorkspaceMember{WorkspaceID: f.workspace.ID, UserID: viewer.ID, Role: models.RoleViewer})
+	assertStatus(t, request(http.MethodGet, path, loginToken(t, viewer, f.workspace.ID), "", nil), http.StatusOK)
+
+	// API密钥同样受限于创建者的双因素认证状态
+	member, _ := services.GetWorkspaceMember(f.workspace.ID, f.editor.ID)
+	_, key, err := services.CreateAPIKey(member, f.editor, "ci", []string{"agents:read"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.DB.Model(f.editor).Update("totp_enabled", false)
+	assertStatus(t, request(http.MethodGet, path, "", "", map[string]string{middleware.APIKeyHeader: key}), http.StatusForbidden)
+}
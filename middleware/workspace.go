@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"strconv"
+
+	"ai-assistant-backend/config"
+	"ai-assistant-backend/models"
+	"ai-assistant-backend/services"
+	"ai-assistant-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WorkspaceHeader 切换当前工作空间的请求头
+const WorkspaceHeader = "X-Workspace-ID"
+
+// WorkspaceMiddleware 确定当前工作空间：优先使用请求头X-Workspace-ID，其次为令牌中的工作空间，
+// 都未指定时使用用户最早加入的工作空间。校验成员身份后将成员信息存入上下文，需在AuthMiddleware之后使用
+func WorkspaceMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, err := utils.GetUserFromContext(c)
+		if err != nil {
+			utils.Unauthorized(c, "用户未登录")
+			c.Abort()
+			return
+		}
+
+		workspaceID := user.WorkspaceID
+		if header := c.GetHeader(WorkspaceHeader); header != "" {
+			id, err := strconv.ParseUint(header, 10, 32)
+			if err != nil {
+				utils.InvalidID(c, "工作空间")
+				c.Abort()
+				return
+			}
+			// API密钥只能访问所属的工作空间
+			if user.APIKeyID != 0 && uint(id) != user.WorkspaceID {
+				utils.Forbidden(c, "API密钥只能访问所属的工作空间")
+				c.Abort()
+				return
+			}
+			workspaceID = uint(id)
+		}
+		if workspaceID == 0 {
+			if workspaceID, err = services.DefaultWorkspaceID(user.UserID); err != nil {
+				utils.Forbidden(c, err.Error())
+				c.Abort()
+				return
+			}
+		}
+
+		activateWorkspace(c, user, workspaceID)
+	}
+}
+
+// LoadWorkspace 按路径参数id加载工作空间，校验成员身份后将成员信息存入上下文
+func LoadWorkspace() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, err := utils.GetUserFromContext(c)
+		if err != nil {
+			utils.Unauthorized(c, "用户未登录")
+			c.Abort()
+			return
+		}
+
+		workspaceID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			utils.InvalidID(c, "工作空间")
+			c.Abort()
+			return
+		}
+		activateWorkspace(c, user, uint(workspaceID))
+	}
+}
+
+// activateWorkspace 工作空间不存在返回404，非成员且非超级管理员返回403。
+// 超级管理员访问未加入的工作空间时视为所有者
+func activateWorkspace(c *gin.Context, user *utils.Claims, workspaceID uint) {
+	var workspace models.Workspace
+	if err := config.DB.First(&workspace, workspaceID).Error; err != nil {
+		utils.NotFound(c, "工作空间不存在")
+		c.Abort()
+		return
+	}
+
+	member, err := services.GetWorkspaceMember(workspace.ID, user.UserID)
+	if err != nil {
+		var roles []uint8
+		config.DB.Model(&models.User{}).Where("id = ?", user.UserID).Limit(1).Pluck("role", &roles)
+		if len(roles) == 0 || roles[0] != models.RoleSuperAdmin {
+			utils.Forbidden(c, err.Error())
+			c.Abort()
+			return
+		}
+		member = &models.WorkspaceMember{WorkspaceID: workspace.ID, UserID: user.UserID, Role: models.RoleOwner}
+	}
+
+	utils.SetWorkspaceToContext(c, member)
+	c.Next()
+}
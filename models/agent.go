@@ -5,17 +5,37 @@ import (
 )
 
 type Agent struct {
-	ID             uint      `json:"id" gorm:"primary_key"`
-	AppID          string    `json:"app_id"`
-	UserID         uint      `json:"user_id"`
-	Name           string    `json:"name" gorm:"not null"`
-	Logo           string    `json:"logo"`
-	Status         string    `json:"status" gorm:"default:'offline'"` // online, offline
-	Link           string    `json:"link"`
-	WelcomeMsg     string    `json:"welcome_msg"`
-	CarouselImages []string  `json:"carousel_images" gorm:"-"`
-	CreatedAt      time.Time `json:"created_at"`
-	UpdatedAt      time.Time `json:"updated_at"`
+	ID                uint     `json:"id" gorm:"primary_key"`
+	AppID             string   `json:"app_id" gorm:"size:191;uniqueIndex"`
+	UserID            uint     `json:"user_id"`                   // 创建者
+	WorkspaceID       uint     `json:"workspace_id" gorm:"index"` // 所属工作空间
+	Name              string   `json:"name" gorm:"not null"`
+	Logo              string   `json:"logo"`
+	Status            string   `json:"status" gorm:"default:'offline'"` // online, offline
+	Link              string   `json:"link"`
+	WelcomeMsg        string   `json:"welcome_msg"`
+	RetrievalTopK     int      `json:"retrieval_top_k" gorm:"default:5"`       // 文档切片与常见问答各自的最大检索条数
+	RetrievalMinScore float64  `json:"retrieval_min_score" gorm:"default:0.3"` // 相似度阈值，低于该值的结果不作为参考资料
+	NoAnswerMsg       string   `json:"no_answer_msg"`                          // 未检索到资料时的回复
+	Model             string   `json:"model"`                                  // 对话模型，为空时使用默认模型
+	SystemPrompt      string   `json:"system_prompt" gorm:"type:text"`         // 回答要求，为空时使用默认提示词
+	Persona           string   `json:"persona" gorm:"type:text"`               // 人设，如身份、语气
+	Temperature       *float64 `json:"temperature"`                            // 为空时使用服务商默认值
+	MaxTokens         int      `json:"max_tokens"`                             // 为0时使用服务商默认值
+	// DocumentCategoryIDs 知识范围：参与检索的文档分类，为空时使用全部文档
+	DocumentCategoryIDs []uint `json:"document_category_ids" gorm:"serializer:json;type:text"`
+	// FAQCategoryIDs 知识范围：参与匹配与检索的问答分类，为空时使用全部常见问答
+	FAQCategoryIDs   []uint `json:"faq_category_ids" gorm:"serializer:json;type:text"`
+	PublishedVersion int    `json:"published_version"` // 对访客生效的版本号，0表示尚未发布
+	// AllowedDomains 允许嵌入对话窗口的网站域名，支持 *.example.com，为空时不限制
+	AllowedDomains []string      `json:"allowed_domains" gorm:"serializer:json;type:text"`
+	ThemeColor     string        `json:"theme_color" gorm:"size:16;default:'#1677ff'"`          // 对话窗口主题色
+	ThemeTextColor string        `json:"theme_text_color" gorm:"size:16;default:'#ffffff'"`     // 主题色上的文字颜色
+	WidgetPosition string        `json:"widget_position" gorm:"size:16;default:'bottom-right'"` // 悬浮按钮位置：bottom-right, bottom-left
+	CarouselImages []string      `json:"carousel_images" gorm:"-"`
+	SelfServices   []SelfService `json:"self_services" gorm:"-"`
+	CreatedAt      time.Time     `json:"created_at"`
+	UpdatedAt      time.Time     `json:"updated_at"`
 }
 
 type AgentCarouselImage struct {
@@ -25,9 +45,10 @@ type AgentCarouselImage struct {
 	Sort     int    `json:"sort"`
 }
 
+// SelfService 智能体的自助服务入口，在对话窗口中显示为快捷按钮
 type SelfService struct {
 	ID      uint   `json:"id" gorm:"primary_key"`
-	AgentID uint   `json:"agent_id"`
+	AgentID uint   `json:"agent_id" gorm:"index"`
 	Name    string `json:"name" gorm:"not null"`
 	Link    string `json:"link" gorm:"not null"`
 	Icon    string `json:"icon" gorm:"not null"`
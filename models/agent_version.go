@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+)
+
+// AgentVersion 智能体的发布版本，保存发布时对访客生效的配置快照
+type AgentVersion struct {
+	ID           uint          `json:"id" gorm:"primary_key"`
+	AgentID      uint          `json:"agent_id" gorm:"uniqueIndex:idx_agent_version"`
+	Version      int           `json:"version" gorm:"uniqueIndex:idx_agent_version"` // 从1开始递增
+	Snapshot     AgentSnapshot `json:"snapshot" gorm:"serializer:json;type:mediumtext"`
+	Note         string        `json:"note" gorm:"size:255"`
+	PublishedBy  uint          `json:"published_by"`
+	RollbackFrom int           `json:"rollback_from"` // 回滚时为来源版本号，正常发布为0
+	CreatedAt    time.Time     `json:"created_at"`
+}
+
+// AgentSnapshot 需要发布后才对访客生效的配置：提示词、欢迎语、轮播图、自助服务与知识范围。
+// 名称、头像、允许的域名与对话窗口样式修改后立即生效，不在快照中
+type AgentSnapshot struct {
+	WelcomeMsg          string                `json:"welcome_msg"`
+	NoAnswerMsg         string                `json:"no_answer_msg"`
+	Model               string                `json:"model"`
+	SystemPrompt        string                `json:"system_prompt"`
+	Persona             string                `json:"persona"`
+	Temperature         *float64              `json:"temperature"`
+	MaxTokens           int                   `json:"max_tokens"`
+	RetrievalTopK       int                   `json:"retrieval_top_k"`
+	RetrievalMinScore   float64               `json:"retrieval_min_score"`
+	DocumentCategoryIDs []uint                `json:"document_category_ids"`
+	FAQCategoryIDs      []uint                `json:"faq_category_ids"`
+	CarouselImages      []string              `json:"carousel_images"`
+	SelfServices        []SnapshotSelfService `json:"self_services"`
+}
+
+// SnapshotSelfService 快照中的自助服务，按显示顺序排列
+type SnapshotSelfService struct {
+	Name string `json:"name"`
+	Link string `json:"link"`
+	Icon string `json:"icon"`
+}
+
+func (AgentVersion) TableName() string {
+	return "agent_versions"
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+)
+
+// APIKey 供CI、内部工具等服务端调用的API密钥，以创建者的身份访问所属工作空间，
+// 权限为Scopes与创建者当前角色权限的交集。密钥只保存摘要，Prefix用于识别
+type APIKey struct {
+	ID          uint       `json:"id" gorm:"primary_key"`
+	WorkspaceID uint       `json:"workspace_id" gorm:"index"`
+	UserID      uint       `json:"user_id" gorm:"index"`
+	Name        string     `json:"name" gorm:"not null;size:100"`
+	Prefix      string     `json:"prefix" gorm:"size:16;index"`
+	KeyHash     string     `json:"-" gorm:"size:64;uniqueIndex"`
+	Scopes      []string   `json:"scopes" gorm:"serializer:json;type:text"`
+	ExpiresAt   *time.Time `json:"expires_at"` // 为空表示永不过期
+	LastUsedAt  *time.Time `json:"last_used_at"`
+	LastUsedIP  string     `json:"last_used_ip" gorm:"size:64"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// Expired 密钥是否已过期
+func (k *APIKey) Expired() bool {
+	return k.ExpiresAt != nil && time.Now().After(*k.ExpiresAt)
+}
+
+func (APIKey) TableName() string {
+	return "api_keys"
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+)
+
+type Conversation struct {
+	ID            uint      `json:"id" gorm:"primary_key"`
+	AgentID       uint      `json:"agent_id" gorm:"index"`
+	SessionID     string    `json:"session_id" gorm:"size:64;uniqueIndex;not null"`
+	VisitorID     string    `json:"visitor_id" gorm:"size:64;index"`
+	MessageCount  int       `json:"message_count"`
+	LastMessageAt time.Time `json:"last_message_at"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+type Message struct {
+	ID               uint      `json:"id" gorm:"primary_key"`
+	ConversationID   uint      `json:"conversation_id" gorm:"index"`
+	AgentID          uint      `json:"agent_id" gorm:"index"`
+	Role             string    `json:"role" gorm:"size:16;not null"` // system, user, assistant
+	Content          string    `json:"content" gorm:"type:text"`
+	PromptTokens     int       `json:"prompt_tokens"`
+	CompletionTokens int       `json:"completion_tokens"`
+	LatencyMs        int64     `json:"latency_ms"`
+	FAQID            uint      `json:"faq_id" gorm:"index"`     // 直接命中的常见问答
+	Feedback         string    `json:"feedback" gorm:"size:16"` // like, dislike
+	FeedbackComment  string    `json:"feedback_comment"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+func (Conversation) TableName() string {
+	return "conversations"
+}
+
+func (Message) TableName() string {
+	return "messages"
+}
@@ -1,6 +1,10 @@
 package models
 
 import (
+	"database/sql/driver"
+	"encoding/binary"
+	"errors"
+	"math"
 	"time"
 )
 
@@ -11,17 +15,85 @@ type DocumentCategory struct {
 	Sort    int    `json:"sort"`
 }
 
+// 文档解析状态
+const (
+	DocumentStatusPending    = "pending"
+	DocumentStatusProcessing = "processing"
+	DocumentStatusReady      = "ready"
+	DocumentStatusFailed     = "failed"
+)
+
 type Document struct {
-	ID         uint      `json:"id" gorm:"primary_key"`
-	AgentID    uint      `json:"agent_id"`
-	CategoryID uint      `json:"category_id"`
-	Name       string    `json:"name" gorm:"not null"`
-	Format     string    `json:"format"`
-	Size       int64     `json:"size"`
-	Path       string    `json:"path" gorm:"not null"`
-	UploadTime time.Time `json:"upload_time"`
-	CreatedAt  time.Time `json:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at"`
+	ID          uint       `json:"id" gorm:"primary_key"`
+	AgentID     uint       `json:"agent_id"`
+	CategoryID  uint       `json:"category_id"`
+	Name        string     `json:"name" gorm:"not null"`
+	Format      string     `json:"format"`
+	Size        int64      `json:"size"`
+	Path        string     `json:"path" gorm:"not null"`
+	Status      string     `json:"status" gorm:"size:16;default:'pending';index"` // pending, processing, ready, failed
+	StatusError string     `json:"status_error"`
+	Content     string     `json:"-" gorm:"type:longtext"` // 解析出的纯文本
+	CharCount   int        `json:"char_count"`
+	IngestedAt  *time.Time `json:"ingested_at"`
+	UploadTime  time.Time  `json:"upload_time"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// DocumentChunk 文档切片及其向量，StartOffset/EndOffset为切片在文档文本中的字符位置
+type DocumentChunk struct {
+	ID             uint      `json:"id" gorm:"primary_key"`
+	AgentID        uint      `json:"agent_id" gorm:"index"`
+	DocumentID     uint      `json:"document_id" gorm:"index"`
+	ChunkIndex     int       `json:"chunk_index"`
+	Content        string    `json:"content" gorm:"type:text"`
+	StartOffset    int       `json:"start_offset"`
+	EndOffset      int       `json:"end_offset"`
+	Embedding      Vector    `json:"-" gorm:"type:mediumblob"`
+	EmbeddingModel string    `json:"embedding_model" gorm:"size:128"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// Vector 向量，以小端序float32二进制形式存储
+type Vector []float32
+
+// Value 实现 driver.Valuer
+func (v Vector) Value() (driver.Value, error) {
+	if v == nil {
+		return nil, nil
+	}
+	data := make([]byte, len(v)*4)
+	for i, value := range v {
+		binary.LittleEndian.PutUint32(data[i*4:], math.Float32bits(value))
+	}
+	return data, nil
+}
+
+// Scan 实现 sql.Scanner
+func (v *Vector) Scan(src interface{}) error {
+	var data []byte
+	switch value := src.(type) {
+	case nil:
+		*v = nil
+		return nil
+	case []byte:
+		data = value
+	case string:
+		data = []byte(value)
+	default:
+		return errors.New("无效的向量数据")
+	}
+	if len(data)%4 != 0 {
+		return errors.New("无效的向量数据")
+	}
+
+	vector := make(Vector, len(data)/4)
+	for i := range vector {
+		vector[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[i*4:]))
+	}
+	*v = vector
+	return nil
 }
 
 type DocumentTag struct {
@@ -30,10 +102,11 @@ type DocumentTag struct {
 	TagName    string `json:"tag_name" gorm:"not null"`
 }
 
+// Tag 标签，名称在同一智能体内唯一
 type Tag struct {
 	ID      uint   `json:"id" gorm:"primary_key"`
-	Name    string `json:"name" gorm:"unique;not null"`
-	AgentID uint   `json:"agent_id"`
+	Name    string `json:"name" gorm:"size:191;not null;uniqueIndex:idx_tags_agent_name,priority:2"`
+	AgentID uint   `json:"agent_id" gorm:"uniqueIndex:idx_tags_agent_name,priority:1"`
 }
 
 func (DocumentCategory) TableName() string {
@@ -44,6 +117,10 @@ func (Document) TableName() string {
 	return "documents"
 }
 
+func (DocumentChunk) TableName() string {
+	return "document_chunks"
+}
+
 func (DocumentTag) TableName() string {
 	return "document_tags"
 }
@@ -12,13 +12,17 @@ type FAQCategory struct {
 }
 
 type FAQ struct {
-	ID         uint      `json:"id" gorm:"primary_key"`
-	AgentID    uint      `json:"agent_id"`
-	CategoryID uint      `json:"category_id"`
-	Question   string    `json:"question" gorm:"not null"`
-	Answer     string    `json:"answer" gorm:"not null"`
-	CreatedAt  time.Time `json:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at"`
+	ID             uint       `json:"id" gorm:"primary_key"`
+	AgentID        uint       `json:"agent_id"`
+	CategoryID     uint       `json:"category_id"`
+	Question       string     `json:"question" gorm:"not null"`
+	Answer         string     `json:"answer" gorm:"not null"`
+	HitCount       int        `json:"hit_count"` // 被直接命中作为回复的次数
+	LastHitAt      *time.Time `json:"last_hit_at"`
+	Embedding      Vector     `json:"-" gorm:"type:mediumblob"` // 问题向量，问题修改后清空并在匹配时重新生成
+	EmbeddingModel string     `json:"-" gorm:"size:128"`        // 生成问题向量的模型，与当前向量模型不一致时重新生成
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
 }
 
 func (FAQCategory) TableName() string {
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+)
+
+// 邀请状态
+const (
+	InvitationPending  = "pending"
+	InvitationAccepted = "accepted"
+	InvitationRevoked  = "revoked"
+	InvitationExpired  = "expired" // 仅用于展示，数据库中过期的邀请仍为pending
+)
+
+// Invitation 工作空间成员邀请，令牌只保存摘要且只能使用一次
+type Invitation struct {
+	ID          uint       `json:"id" gorm:"primary_key"`
+	WorkspaceID uint       `json:"workspace_id" gorm:"index"`
+	Email       string     `json:"email" gorm:"not null;size:255"`
+	Role        uint8      `json:"role"`
+	TokenHash   string     `json:"-" gorm:"size:64;uniqueIndex"`
+	Status      string     `json:"status" gorm:"size:16;default:'pending'"`
+	InvitedBy   uint       `json:"invited_by"`
+	AcceptedBy  uint       `json:"accepted_by"`
+	AcceptedAt  *time.Time `json:"accepted_at"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// DisplayStatus 展示用状态，已过期的待接受邀请显示为expired
+func (i *Invitation) DisplayStatus() string {
+	if i.Status == InvitationPending && time.Now().After(i.ExpiresAt) {
+		return InvitationExpired
+	}
+	return i.Status
+}
+
+func (Invitation) TableName() string {
+	return "invitations"
+}
@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+)
+
+// Setting 系统设置，由管理员在运行时修改，Value为JSON
+type Setting struct {
+	Key       string    `json:"key" gorm:"primary_key;size:64"`
+	Value     string    `json:"value" gorm:"type:text"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (Setting) TableName() string {
+	return "settings"
+}
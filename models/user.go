@@ -4,6 +4,19 @@ import (
 	"time"
 )
 
+// 用户角色
+const (
+	RoleOwner      uint8 = 0 // 所有者，管理自己的智能体及其知识库（注册用户默认角色）
+	RoleSuperAdmin uint8 = 1 // 超级管理员，可以管理所有智能体与用户
+	RoleEditor     uint8 = 2 // 编辑，可以维护文档、问答等内容，不能创建或修改智能体
+	RoleViewer     uint8 = 3 // 只读成员
+)
+
+// ValidRole 是否为已定义的角色
+func ValidRole(role uint8) bool {
+	return role <= RoleViewer
+}
+
 type User struct {
 	ID        uint      `json:"id" gorm:"primary_key"`
 	Username  string    `json:"username" gorm:"unique;not null"`
@@ -12,7 +25,17 @@ type User struct {
 	Avatar    string    `json:"avatar"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
-	Role      uint8     `json:"role"`
+	Role      uint8     `json:"role"` // 0: 所有者, 1: 超级管理员, 2: 编辑, 3: 只读
+
+	// 双因素认证（TOTP）
+	TOTPEnabled   bool       `json:"totp_enabled"`
+	TOTPEnabledAt *time.Time `json:"totp_enabled_at"`
+	TOTPSecret    string     `json:"-" gorm:"size:64"`
+	TOTPLastStep  int64      `json:"-"`                  // 最近一次使用的验证码时间步，防止验证码重放
+	RecoveryCodes string     `json:"-" gorm:"type:text"` // 恢复码的SHA-256摘要，JSON数组
+
+	// OIDCSubject 单点登录身份提供方中的用户标识（sub），首次单点登录时按邮箱关联
+	OIDCSubject *string `json:"-" gorm:"column:oidc_subject;size:255;uniqueIndex"`
 }
 
 func (User) TableName() string {
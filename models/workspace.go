@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+)
+
+// Workspace 工作空间，智能体及其知识库归属于工作空间，由成员共同管理
+type Workspace struct {
+	ID        uint      `json:"id" gorm:"primary_key"`
+	Name      string    `json:"name" gorm:"not null"`
+	OwnerID   uint      `json:"owner_id" gorm:"index"` // 创建者
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// WorkspaceMember 工作空间成员，Role取值为 RoleOwner、RoleEditor、RoleViewer
+type WorkspaceMember struct {
+	ID          uint      `json:"id" gorm:"primary_key"`
+	WorkspaceID uint      `json:"workspace_id" gorm:"uniqueIndex:idx_workspace_member"`
+	UserID      uint      `json:"user_id" gorm:"uniqueIndex:idx_workspace_member;index"`
+	Role        uint8     `json:"role"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// ValidMemberRole 是否为可分配给工作空间成员的角色
+func ValidMemberRole(role uint8) bool {
+	return role == RoleOwner || role == RoleEditor || role == RoleViewer
+}
+
+func (Workspace) TableName() string {
+	return "workspaces"
+}
+
+func (WorkspaceMember) TableName() string {
+	return "workspace_members"
+}
@@ -0,0 +1,26 @@
+package routes
+
+import (
+	"ai-assistant-backend/controllers"
+	"ai-assistant-backend/middleware"
+	"ai-assistant-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetupAdminRoutes(router *gin.Engine) {
+	admin := router.Group("/api/admin")
+	admin.Use(middleware.SessionAuthMiddleware())
+	{
+		// 用户与角色管理
+		admin.GET("/roles", middleware.RequirePermission(utils.PermUsersRead), controllers.GetRoles)
+		admin.GET("/users", middleware.RequirePermission(utils.PermUsersRead), controllers.GetUsers)
+		admin.PUT("/users/:id/role", middleware.RequirePermission(utils.PermUsersManage), controllers.UpdateUserRole)
+		admin.POST("/users/:id/unlock", middleware.RequirePermission(utils.PermUsersManage), controllers.UnlockUser)
+		admin.POST("/users/:id/2fa/reset", middleware.RequirePermission(utils.PermUsersManage), controllers.ResetUserTwoFactor)
+
+		// 安全策略
+		admin.GET("/security/2fa-policy", middleware.RequirePermission(utils.PermUsersRead), controllers.GetTwoFactorPolicy)
+		admin.PUT("/security/2fa-policy", middleware.RequirePermission(utils.PermUsersManage), controllers.UpdateTwoFactorPolicy)
+	}
+}
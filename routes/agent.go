@@ -3,19 +3,48 @@ package routes
 import (
 	"ai-assistant-backend/controllers"
 	"ai-assistant-backend/middleware"
+	"ai-assistant-backend/utils"
 
 	"github.com/gin-gonic/gin"
 )
 
 func SetupAgentRoutes(router *gin.Engine) {
 	agent := router.Group("/api/agents")
-	agent.Use(middleware.AuthMiddleware())
+	agent.Use(middleware.AuthMiddleware(), middleware.WorkspaceMiddleware())
 	{
-		agent.GET("", controllers.GetAgents)
-		agent.GET("/:id", controllers.GetAgent)
-		agent.POST("", controllers.CreateAgent)
-		agent.PUT("/:id", controllers.UpdateAgent)
-		agent.PATCH("/:id/status", controllers.ToggleAgentStatus)
-		agent.DELETE("/:id", controllers.DeleteAgent)
+		read := middleware.RequirePermission(utils.PermAgentsRead)
+		write := middleware.RequirePermission(utils.PermAgentsWrite)
+		// 校验当前用户对路径中智能体的访问权限
+		load := middleware.LoadAgent()
+
+		agent.GET("", read, controllers.GetAgents)
+		agent.GET("/models", read, controllers.GetAgentModels)
+		agent.POST("", write, controllers.CreateAgent)
+		agent.GET("/:id", read, load, controllers.GetAgent)
+		agent.PUT("/:id", write, load, controllers.UpdateAgent)
+		agent.PATCH("/:id/status", write, load, controllers.ToggleAgentStatus)
+		agent.DELETE("/:id", write, load, controllers.DeleteAgent)
+		// 复制智能体会同时创建文档与常见问答
+		agent.POST("/:id/clone", write, middleware.RequirePermission(utils.PermDocumentsWrite), middleware.RequirePermission(utils.PermFAQWrite), load, controllers.CloneAgent)
+		agent.POST("/:id/prompt/preview", read, load, controllers.PreviewAgentPrompt)
+		agent.GET("/:id/embed", read, load, controllers.GetAgentEmbed)
+
+		// 版本：修改保存为草稿，发布后对访客生效
+		agent.POST("/:id/publish", write, load, controllers.PublishAgent)
+		agent.GET("/:id/versions", read, load, controllers.GetAgentVersions)
+		agent.GET("/:id/versions/diff", read, load, controllers.DiffAgentVersions)
+		agent.GET("/:id/versions/:version", read, load, controllers.GetAgentVersion)
+		agent.POST("/:id/versions/:version/rollback", write, load, controllers.RollbackAgentVersion)
+
+		// 自助服务
+		agent.GET("/:id/self-services", read, load, controllers.GetSelfServices)
+		agent.POST("/:id/self-services", write, load, controllers.CreateSelfService)
+		agent.PUT("/:id/self-services/reorder", write, load, controllers.ReorderSelfServices)
+		agent.PUT("/:id/self-services/:service_id", write, load, controllers.UpdateSelfService)
+		agent.DELETE("/:id/self-services/:service_id", write, load, controllers.DeleteSelfService)
+
+		// 会话记录
+		agent.GET("/:id/conversations", read, load, controllers.GetAgentConversations)
+		agent.GET("/:id/conversations/:conversation_id", read, load, controllers.GetAgentConversation)
 	}
 }
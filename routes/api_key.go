@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"ai-assistant-backend/controllers"
+	"ai-assistant-backend/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetupAPIKeyRoutes 当前工作空间的API密钥管理，只允许登录用户操作
+func SetupAPIKeyRoutes(router *gin.Engine) {
+	apiKey := router.Group("/api/api-keys")
+	apiKey.Use(middleware.SessionAuthMiddleware(), middleware.WorkspaceMiddleware())
+	{
+		apiKey.GET("", controllers.GetAPIKeys)
+		apiKey.POST("", controllers.CreateAPIKey)
+		apiKey.DELETE("/:id", controllers.DeleteAPIKey)
+	}
+}
@@ -11,9 +11,25 @@ func SetupAuthRoutes(router *gin.Engine) {
 	auth := router.Group("/api/auth")
 	{
 		auth.POST("/login", controllers.Login)
+		auth.POST("/login/2fa", controllers.LoginTwoFactor)
+		auth.GET("/oidc/login", controllers.OIDCLogin)
+		auth.POST("/oidc/callback", controllers.OIDCCallback)
 		auth.POST("/register", controllers.Register)
-		auth.GET("/profile", middleware.AuthMiddleware(), controllers.GetProfile)
-		auth.POST("/logout", middleware.AuthMiddleware(), controllers.Logout)
-		auth.POST("/refresh", middleware.AuthMiddleware(), controllers.RefreshToken)
+		auth.POST("/password/forgot", controllers.ForgotPassword)
+		auth.POST("/password/reset", controllers.ResetPassword)
+		auth.POST("/password/change", middleware.SessionAuthMiddleware(), controllers.ChangePassword)
+		auth.GET("/profile", middleware.SessionAuthMiddleware(), controllers.GetProfile)
+		auth.POST("/logout", middleware.SessionAuthMiddleware(), controllers.Logout)
+		auth.POST("/logout-all", middleware.SessionAuthMiddleware(), controllers.LogoutAll)
+		auth.POST("/refresh", controllers.RefreshToken)
+		auth.GET("/sessions", middleware.SessionAuthMiddleware(), controllers.GetSessions)
+		auth.DELETE("/sessions/:id", middleware.SessionAuthMiddleware(), controllers.RevokeSession)
+
+		// 双因素认证
+		auth.GET("/2fa", middleware.SessionAuthMiddleware(), controllers.GetTwoFactorStatus)
+		auth.POST("/2fa/setup", middleware.SessionAuthMiddleware(), controllers.SetupTwoFactor)
+		auth.POST("/2fa/confirm", middleware.SessionAuthMiddleware(), controllers.ConfirmTwoFactor)
+		auth.POST("/2fa/disable", middleware.SessionAuthMiddleware(), controllers.DisableTwoFactor)
+		auth.POST("/2fa/recovery-codes", middleware.SessionAuthMiddleware(), controllers.RegenerateRecoveryCodes)
 	}
 }
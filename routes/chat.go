@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"ai-assistant-backend/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetupChatRoutes 面向访客的对话接口，无需登录
+func SetupChatRoutes(router *gin.Engine) {
+	chat := router.Group("/api/chat/:app_id")
+	{
+		chat.POST("/conversations", controllers.StartConversation)
+		chat.GET("/conversations/:conversation_id/messages", controllers.GetConversationMessages)
+		chat.POST("/conversations/:conversation_id/messages", controllers.SendMessage)
+		chat.POST("/conversations/:conversation_id/messages/stream", controllers.StreamMessage)
+		chat.POST("/conversations/:conversation_id/messages/:message_id/feedback", controllers.SetMessageFeedback)
+	}
+}
@@ -3,25 +3,36 @@ package routes
 import (
 	"ai-assistant-backend/controllers"
 	"ai-assistant-backend/middleware"
+	"ai-assistant-backend/models"
+	"ai-assistant-backend/utils"
 
 	"github.com/gin-gonic/gin"
 )
 
 func SetupDocumentRoutes(router *gin.Engine) {
 	document := router.Group("/api/documents")
-	document.Use(middleware.AuthMiddleware())
+	document.Use(middleware.AuthMiddleware(), middleware.WorkspaceMiddleware())
 	{
+		read := middleware.RequirePermission(utils.PermDocumentsRead)
+		write := middleware.RequirePermission(utils.PermDocumentsWrite)
+
+		// 按查询参数agent_id校验智能体权限
+		byAgent := middleware.LoadAgentFromQuery()
+		// 按文档所属的智能体校验权限
+		byDocument := middleware.LoadAgentOf(&models.Document{}, "文档")
+
 		// 文档分类
-		document.GET("/categories", controllers.GetDocumentCategories)
-		document.POST("/categories", controllers.CreateDocumentCategory)
+		document.GET("/categories", read, byAgent, controllers.GetDocumentCategories)
+		document.POST("/categories", write, byAgent, controllers.CreateDocumentCategory)
 
 		// 文档管理
-		document.GET("", controllers.GetDocuments)
-		document.POST("", controllers.CreateDocument)
-		document.DELETE("/:id", controllers.DeleteDocument)
+		document.GET("", read, byAgent, controllers.GetDocuments)
+		document.POST("", write, byAgent, controllers.CreateDocument)
+		document.DELETE("/:id", write, byDocument, controllers.DeleteDocument)
+		document.POST("/:id/ingest", write, byDocument, controllers.IngestDocument)
 
 		// 标签管理
-		document.GET("/tags", controllers.GetTags)
-		document.POST("/tags", controllers.CreateTag)
+		document.GET("/tags", read, byAgent, controllers.GetTags)
+		document.POST("/tags", write, byAgent, controllers.CreateTag)
 	}
 }
@@ -3,22 +3,32 @@ package routes
 import (
 	"ai-assistant-backend/controllers"
 	"ai-assistant-backend/middleware"
+	"ai-assistant-backend/models"
+	"ai-assistant-backend/utils"
 
 	"github.com/gin-gonic/gin"
 )
 
 func SetupFAQRoutes(router *gin.Engine) {
 	faq := router.Group("/api/faqs")
-	faq.Use(middleware.AuthMiddleware())
+	faq.Use(middleware.AuthMiddleware(), middleware.WorkspaceMiddleware())
 	{
+		read := middleware.RequirePermission(utils.PermFAQRead)
+		write := middleware.RequirePermission(utils.PermFAQWrite)
+
+		// 按查询参数agent_id校验智能体权限
+		byAgent := middleware.LoadAgentFromQuery()
+		// 按常见问答所属的智能体校验权限
+		byFAQ := middleware.LoadAgentOf(&models.FAQ{}, "常见问答")
+
 		// 问答分类
-		faq.GET("/categories", controllers.GetFAQCategories)
-		faq.POST("/categories", controllers.CreateFAQCategory)
+		faq.GET("/categories", read, byAgent, controllers.GetFAQCategories)
+		faq.POST("/categories", write, byAgent, controllers.CreateFAQCategory)
 
 		// 常见问答
-		faq.GET("", controllers.GetFAQs)
-		faq.POST("", controllers.CreateFAQ)
-		faq.PUT("/:id", controllers.UpdateFAQ)
-		faq.DELETE("/:id", controllers.DeleteFAQ)
+		faq.GET("", read, byAgent, controllers.GetFAQs)
+		faq.POST("", write, byAgent, controllers.CreateFAQ)
+		faq.PUT("/:id", write, byFAQ, controllers.UpdateFAQ)
+		faq.DELETE("/:id", write, byFAQ, controllers.DeleteFAQ)
 	}
 }
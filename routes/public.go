@@ -0,0 +1,15 @@
+package routes
+
+import (
+	"ai-assistant-backend/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetupPublicRoutes 嵌入客户网站的对话窗口使用的公开接口，无需登录
+func SetupPublicRoutes(router *gin.Engine) {
+	public := router.Group("/api/public")
+	{
+		public.GET("/agents/:app_id/config", controllers.GetPublicAgentConfig)
+	}
+}
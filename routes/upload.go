@@ -3,20 +3,24 @@ package routes
 import (
 	"ai-assistant-backend/controllers"
 	"ai-assistant-backend/middleware"
+	"ai-assistant-backend/utils"
 
 	"github.com/gin-gonic/gin"
 )
 
 func SetupUploadRoutes(router *gin.Engine) {
 	upload := router.Group("/api/upload")
-	upload.Use(middleware.AuthMiddleware())
+	upload.Use(middleware.AuthMiddleware(), middleware.WorkspaceMiddleware())
 	{
-		upload.POST("/file", controllers.UploadFile)                           // 直接上传文件
-		upload.GET("/presigned-upload", controllers.GetPresignedUploadURL)     // 获取预签名上传URL
-		upload.GET("/presigned-download", controllers.GetPresignedDownloadURL) // 获取预签名下载URL
-		upload.GET("/file-access-url", controllers.GetFileAccessURL)           // 获取文件访问URL
-		upload.DELETE("/file", controllers.DeleteFile)                         // 删除文件
-		upload.GET("/files", controllers.ListFiles)                            // 列出文件
-		upload.GET("/file-info", controllers.GetFileInfo)                      // 获取文件信息
+		read := middleware.RequirePermission(utils.PermUploadsRead)
+		write := middleware.RequirePermission(utils.PermUploadsWrite)
+
+		upload.POST("/file", write, controllers.UploadFile)                          // 直接上传文件
+		upload.GET("/presigned-upload", write, controllers.GetPresignedUploadURL)    // 获取预签名上传URL
+		upload.GET("/presigned-download", read, controllers.GetPresignedDownloadURL) // 获取预签名下载URL
+		upload.GET("/file-access-url", read, controllers.GetFileAccessURL)           // 获取文件访问URL
+		upload.DELETE("/file", write, controllers.DeleteFile)                        // 删除文件
+		upload.GET("/files", read, controllers.ListFiles)                            // 列出文件
+		upload.GET("/file-info", read, controllers.GetFileInfo)                      // 获取文件信息
 	}
 }
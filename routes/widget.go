@@ -0,0 +1,13 @@
+package routes
+
+import (
+	"ai-assistant-backend/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetupWidgetRoutes 对话窗口脚本与托管对话页，无需登录
+func SetupWidgetRoutes(router *gin.Engine) {
+	router.GET("/widget.js", controllers.GetWidgetScript)
+	router.GET("/chat/:app_id", controllers.GetChatPage)
+}
@@ -0,0 +1,42 @@
+package routes
+
+import (
+	"ai-assistant-backend/controllers"
+	"ai-assistant-backend/middleware"
+	"ai-assistant-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetupWorkspaceRoutes(router *gin.Engine) {
+	workspace := router.Group("/api/workspaces")
+	workspace.Use(middleware.SessionAuthMiddleware())
+	{
+		// 校验当前用户是路径中工作空间的成员
+		load := middleware.LoadWorkspace()
+		manage := middleware.RequirePermission(utils.PermMembersManage)
+
+		workspace.GET("", controllers.GetWorkspaces)
+		workspace.POST("", controllers.CreateWorkspace)
+		workspace.PUT("/:id", load, manage, controllers.UpdateWorkspace)
+		workspace.POST("/:id/switch", load, controllers.SwitchWorkspace)
+
+		// 成员管理
+		workspace.GET("/:id/members", load, controllers.GetWorkspaceMembers)
+		workspace.POST("/:id/members", load, manage, controllers.AddWorkspaceMember)
+		workspace.PUT("/:id/members/:user_id", load, manage, controllers.UpdateWorkspaceMember)
+		workspace.DELETE("/:id/members/:user_id", load, manage, controllers.RemoveWorkspaceMember)
+
+		// 成员邀请
+		workspace.GET("/:id/invitations", load, manage, controllers.GetInvitations)
+		workspace.POST("/:id/invitations", load, manage, controllers.CreateInvitation)
+		workspace.DELETE("/:id/invitations/:invitation_id", load, manage, controllers.RevokeInvitation)
+	}
+
+	// 接受邀请，令牌放在请求体中避免出现在访问日志里
+	invitation := router.Group("/api/invitations")
+	{
+		invitation.POST("/preview", controllers.PreviewInvitation)
+		invitation.POST("/accept", middleware.SessionAuthMiddleware(), controllers.AcceptInvitation)
+	}
+}
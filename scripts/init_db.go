@@ -5,7 +5,10 @@ import (
 
 	"ai-assistant-backend/config"
 	"ai-assistant-backend/models"
+	"ai-assistant-backend/services"
 	"ai-assistant-backend/utils"
+
+	"gorm.io/gorm"
 )
 
 func main() {
@@ -22,15 +25,24 @@ func main() {
 	// 自动迁移数据库表
 	config.DB.AutoMigrate(
 		&models.User{},
+		&models.Workspace{},
+		&models.WorkspaceMember{},
+		&models.Invitation{},
+		&models.APIKey{},
 		&models.Agent{},
+		&models.AgentVersion{},
 		&models.AgentCarouselImage{},
 		&models.SelfService{},
 		&models.DocumentCategory{},
 		&models.Document{},
+		&models.DocumentChunk{},
 		&models.DocumentTag{},
 		&models.Tag{},
 		&models.FAQCategory{},
 		&models.FAQ{},
+		&models.Conversation{},
+		&models.Message{},
+		&models.Setting{},
 	)
 
 	// 检查是否已存在默认用户
@@ -50,9 +62,17 @@ func main() {
 		Username: "admin",
 		Password: hashedPassword,
 		Email:    "admin@example.com",
+		Role:     models.RoleSuperAdmin,
 	}
 
-	if err := config.DB.Create(&defaultUser).Error; err != nil {
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&defaultUser).Error; err != nil {
+			return err
+		}
+		_, err := services.CreatePersonalWorkspace(tx, &defaultUser)
+		return err
+	})
+	if err != nil {
 		log.Fatal("创建默认用户失败:", err)
 	}
 
@@ -0,0 +1,41 @@
+// oidc_stub 本地调试单点登录使用的OIDC身份提供方，不做用户认证，授权请求直接以命令行指定的用户登录。
+//
+//	go run ./scripts/oidc_stub -addr :9000 -email alice@example.com -groups ai-admins
+//
+// 实现位于 scripts/oidc_stub/idp，单点登录的测试也使用该实现
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"strings"
+
+	"ai-assistant-backend/scripts/oidc_stub/idp"
+)
+
+func main() {
+	addr := flag.String("addr", ":9000", "监听地址")
+	issuer := flag.String("issuer", "http://localhost:9000", "issuer，需与后端oidc.issuer一致")
+	clientID := flag.String("client-id", "ai-assistant", "客户端ID")
+	clientSecret := flag.String("client-secret", "stub-secret", "客户端密钥，为空时不校验")
+	email := flag.String("email", "alice@example.com", "登录用户邮箱")
+	name := flag.String("name", "Alice", "登录用户姓名")
+	groups := flag.String("groups", "ai-admins", "登录用户所属用户组，逗号分隔")
+	flag.Parse()
+
+	provider, err := idp.New(*issuer, *clientID, *clientSecret)
+	if err != nil {
+		log.Fatal("生成签名密钥失败:", err)
+	}
+	provider.Email = *email
+	provider.Name = *name
+	for _, group := range strings.Split(*groups, ",") {
+		if group = strings.TrimSpace(group); group != "" {
+			provider.Groups = append(provider.Groups, group)
+		}
+	}
+
+	log.Printf("OIDC调试身份提供方已启动: %s（用户 %s，用户组 %v）", provider.Issuer, provider.Email, provider.Groups)
+	log.Fatal(http.ListenAndServe(*addr, provider.Handler()))
+}
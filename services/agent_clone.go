@@ -0,0 +1,250 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"ai-assistant-backend/config"
+	"ai-assistant-backend/models"
+	"ai-assistant-backend/utils"
+
+	"gorm.io/gorm"
+)
+
+// ErrAppIDExists AppID已被其他智能体使用
+var ErrAppIDExists = errors.New("AppID已存在")
+
+// CloneAgent 复制智能体及其轮播图、自助服务、文档分类、文档、标签、问答分类与常见问答，
+// 文档文件先在MinIO中复制为新对象，数据库记录再在同一事务中创建。
+// 新智能体处于下线且未发布状态，复制的文档会重新解析
+func CloneAgent(source *models.Agent, userID uint, appID, name string) (*models.Agent, error) {
+	uploader := utils.NewMinIOUploader()
+	return cloneAgent(source, userID, appID, name, uploader.CopyFile, uploader.DeleteFile)
+}
+
+// copiedDocument 已复制文件的文档及其新路径
+type copiedDocument struct {
+	document models.Document
+	path     string
+}
+
+// cloneAgent 复制智能体，文件复制不占用数据库事务，任一步骤失败时删除已复制的文件
+func cloneAgent(source *models.Agent, userID uint, appID, name string, copyFile func(src, dst string) error, deleteFile func(objectName string) error) (*models.Agent, error) {
+	var documents []models.Document
+	if err := config.DB.Omit("content").Where("agent_id = ?", source.ID).Order("id").Find(&documents).Error; err != nil {
+		return nil, err
+	}
+
+	var copiedObjects []string
+	cleanup := func() {
+		for _, object := range copiedObjects {
+			if err := deleteFile(object); err != nil {
+				log.Printf("[agent] 清理复制的文件 %s 失败: %v", object, err)
+			}
+		}
+	}
+
+	prefix := utils.WorkspaceObjectPrefix(source.WorkspaceID) + "uploads"
+	copies := make([]copiedDocument, 0, len(documents))
+	for _, document := range documents {
+		srcObject := utils.ObjectNameFromPath(document.Path)
+		dstObject := utils.GenerateObjectName(srcObject, prefix)
+		if err := copyFile(srcObject, dstObject); err != nil {
+			cleanup()
+			return nil, fmt.Errorf("复制文档《%s》失败: %v", document.Name, err)
+		}
+		copiedObjects = append(copiedObjects, dstObject)
+		copies = append(copies, copiedDocument{document: document, path: strings.Replace(document.Path, srcObject, dstObject, 1)})
+	}
+
+	clone := *source
+	clone.ID = 0
+	clone.AppID = appID
+	clone.Name = name
+	clone.UserID = userID
+	clone.Status = "offline"
+	clone.PublishedVersion = 0
+	clone.Link = AgentChatLink(appID)
+	clone.CarouselImages = nil
+	clone.SelfServices = nil
+	clone.CreatedAt = time.Time{}
+	clone.UpdatedAt = time.Time{}
+
+	var documentIDs []uint
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		// AppID上有唯一索引，并发复制时后提交的事务创建失败
+		var count int64
+		if err := tx.Model(&models.Agent{}).Where("app_id = ?", appID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrAppIDExists
+		}
+
+		// 零值字段需要显式写入，避免创建时使用数据库默认值
+		if err := tx.Select("*").Omit("id").Create(&clone).Error; err != nil {
+			return err
+		}
+
+		if err := cloneAgentDisplay(tx, source.ID, clone.ID); err != nil {
+			return err
+		}
+
+		documentCategories, err := cloneDocumentCategories(tx, source.ID, clone.ID)
+		if err != nil {
+			return err
+		}
+		faqCategories, err := cloneFAQCategories(tx, source.ID, clone.ID)
+		if err != nil {
+			return err
+		}
+
+		// 知识范围指向新智能体的分类
+		clone.DocumentCategoryIDs = remapIDs(source.DocumentCategoryIDs, documentCategories)
+		clone.FAQCategoryIDs = remapIDs(source.FAQCategoryIDs, faqCategories)
+		if err := tx.Model(&clone).Select("document_category_ids", "faq_category_ids").Updates(&clone).Error; err != nil {
+			return err
+		}
+
+		var tags []models.Tag
+		if err := tx.Where("agent_id = ?", source.ID).Find(&tags).Error; err != nil {
+			return err
+		}
+		for _, tag := range tags {
+			if err := tx.Create(&models.Tag{Name: tag.Name, AgentID: clone.ID}).Error; err != nil {
+				return err
+			}
+		}
+
+		for _, item := range copies {
+			document := item.document
+			copied := models.Document{
+				AgentID:    clone.ID,
+				CategoryID: documentCategories[document.CategoryID],
+				Name:       document.Name,
+				Format:     document.Format,
+				Size:       document.Size,
+				Path:       item.path,
+				Status:     models.DocumentStatusPending,
+				UploadTime: document.UploadTime,
+			}
+			if err := tx.Create(&copied).Error; err != nil {
+				return err
+			}
+			documentIDs = append(documentIDs, copied.ID)
+
+			var documentTags []models.DocumentTag
+			if err := tx.Where("document_id = ?", document.ID).Find(&documentTags).Error; err != nil {
+				return err
+			}
+			for _, documentTag := range documentTags {
+				if err := tx.Create(&models.DocumentTag{DocumentID: copied.ID, TagName: documentTag.TagName}).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		var faqs []models.FAQ
+		if err := tx.Where("agent_id = ?", source.ID).Order("id").Find(&faqs).Error; err != nil {
+			return err
+		}
+		for _, faq := range faqs {
+			copied := models.FAQ{
+				AgentID:        clone.ID,
+				CategoryID:     faqCategories[faq.CategoryID],
+				Question:       faq.Question,
+				Answer:         faq.Answer,
+				Embedding:      faq.Embedding,
+				EmbeddingModel: faq.EmbeddingModel,
+			}
+			if err := tx.Create(&copied).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		// 事务回滚后删除已复制的文件
+		cleanup()
+		return nil, err
+	}
+
+	for _, documentID := range documentIDs {
+		EnqueueIngestion(documentID)
+	}
+	log.Printf("[agent] 智能体 %d 已复制为 %d（%d 个文档）", source.ID, clone.ID, len(documentIDs))
+	return &clone, nil
+}
+
+// cloneAgentDisplay 复制轮播图与自助服务
+func cloneAgentDisplay(tx *gorm.DB, sourceID, cloneID uint) error {
+	var carouselImages []models.AgentCarouselImage
+	if err := tx.Where("agent_id = ?", sourceID).Order("sort").Find(&carouselImages).Error; err != nil {
+		return err
+	}
+	for _, img := range carouselImages {
+		if err := tx.Create(&models.AgentCarouselImage{AgentID: cloneID, ImageURL: img.ImageURL, Sort: img.Sort}).Error; err != nil {
+			return err
+		}
+	}
+
+	var selfServices []models.SelfService
+	if err := tx.Where("agent_id = ?", sourceID).Order("sort, id").Find(&selfServices).Error; err != nil {
+		return err
+	}
+	for _, service := range selfServices {
+		copied := models.SelfService{AgentID: cloneID, Name: service.Name, Link: service.Link, Icon: service.Icon, Sort: service.Sort}
+		if err := tx.Create(&copied).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cloneDocumentCategories 复制文档分类，返回原分类ID到新分类ID的映射
+func cloneDocumentCategories(tx *gorm.DB, sourceID, cloneID uint) (map[uint]uint, error) {
+	var categories []models.DocumentCategory
+	if err := tx.Where("agent_id = ?", sourceID).Order("id").Find(&categories).Error; err != nil {
+		return nil, err
+	}
+	mapping := map[uint]uint{}
+	for _, category := range categories {
+		copied := models.DocumentCategory{Name: category.Name, AgentID: cloneID, Sort: category.Sort}
+		if err := tx.Create(&copied).Error; err != nil {
+			return nil, err
+		}
+		mapping[category.ID] = copied.ID
+	}
+	return mapping, nil
+}
+
+// cloneFAQCategories 复制问答分类，返回原分类ID到新分类ID的映射
+func cloneFAQCategories(tx *gorm.DB, sourceID, cloneID uint) (map[uint]uint, error) {
+	var categories []models.FAQCategory
+	if err := tx.Where("agent_id = ?", sourceID).Order("id").Find(&categories).Error; err != nil {
+		return nil, err
+	}
+	mapping := map[uint]uint{}
+	for _, category := range categories {
+		copied := models.FAQCategory{Name: category.Name, AgentID: cloneID, Sort: category.Sort}
+		if err := tx.Create(&copied).Error; err != nil {
+			return nil, err
+		}
+		mapping[category.ID] = copied.ID
+	}
+	return mapping, nil
+}
+
+// remapIDs 按映射转换ID列表，映射中不存在的ID被丢弃
+func remapIDs(ids []uint, mapping map[uint]uint) []uint {
+	remapped := []uint{}
+	for _, id := range ids {
+		if newID, ok := mapping[id]; ok {
+			remapped = append(remapped, newID)
+		}
+	}
+	return remapped
+}
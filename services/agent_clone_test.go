@@ -0,0 +1,199 @@
+package services
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"ai-assistant-backend/config"
+	"ai-assistant-backend/models"
+)
+
+// fakeObjectStore 记录复制与删除的文件，failOn中的源文件复制失败
+type fakeObjectStore struct {
+	copied  []string
+	deleted []string
+	failOn  string
+}
+
+func (s *fakeObjectStore) copyFile(src, dst string) error {
+	if src == s.failOn {
+		return errors.New("NoSuchKey")
+	}
+	s.copied = append(s.copied, dst)
+	return nil
+}
+
+func (s *fakeObjectStore) deleteFile(objectName string) error {
+	s.deleted = append(s.deleted, objectName)
+	return nil
+}
+
+// createCloneSource 创建带有两个文档分类、两个问答分类及其文档与常见问答的智能体
+func createCloneSource(t *testing.T, appID string) *models.Agent {
+	t.Helper()
+	db := config.DB
+	must := func(err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	agent := &models.Agent{AppID: appID, Name: appID, WorkspaceID: 3, Status: "online", PublishedVersion: 2}
+	must(db.Create(agent).Error)
+	manual := &models.DocumentCategory{AgentID: agent.ID, Name: "手册", Sort: 1}
+	policy := &models.DocumentCategory{AgentID: agent.ID, Name: "政策", Sort: 2}
+	must(db.Create(manual).Error)
+	must(db.Create(policy).Error)
+	general := &models.FAQCategory{AgentID: agent.ID, Name: "通用"}
+	billing := &models.FAQCategory{AgentID: agent.ID, Name: "费用"}
+	must(db.Create(general).Error)
+	must(db.Create(billing).Error)
+
+	// 知识范围中包含已删除的分类999
+	agent.DocumentCategoryIDs = []uint{policy.ID, 999}
+	agent.FAQCategoryIDs = []uint{billing.ID, general.ID}
+	must(db.Model(agent).Select("document_category_ids", "faq_category_ids").Updates(agent).Error)
+
+	documents := []models.Document{
+		{AgentID: agent.ID, CategoryID: manual.ID, Name: "手册.pdf", Path: "workspaces/3/uploads/2024-01-01/manual.pdf", Status: models.DocumentStatusReady},
+		{AgentID: agent.ID, CategoryID: policy.ID, Name: "政策.pdf", Path: "/workspaces/3/uploads/2024-01-01/policy.pdf", Status: models.DocumentStatusReady},
+		{AgentID: agent.ID, Name: "未分类.txt", Path: "workspaces/3/uploads/2024-01-01/other.txt", Status: models.DocumentStatusFailed},
+	}
+	for i := range documents {
+		must(db.Create(&documents[i]).Error)
+	}
+	must(db.Create(&models.Tag{AgentID: agent.ID, Name: "产品"}).Error)
+	must(db.Create(&models.DocumentTag{DocumentID: documents[0].ID, TagName: "产品"}).Error)
+
+	must(db.Create(&models.FAQ{AgentID: agent.ID, CategoryID: general.ID, Question: "营业时间", Answer: "9点到18点"}).Error)
+	must(db.Create(&models.FAQ{AgentID: agent.ID, CategoryID: billing.ID, Question: "如何退款", Answer: "联系客服"}).Error)
+	must(db.Create(&models.FAQ{AgentID: agent.ID, Question: "未分类问题", Answer: "答案"}).Error)
+	return agent
+}
+
+func TestCloneAgentRemapsIDs(t *testing.T) {
+	source := createCloneSource(t, "clone-source")
+	store := &fakeObjectStore{}
+
+	clone, err := cloneAgent(source, 42, "clone-target", "副本", store.copyFile, store.deleteFile)
+	if err != nil {
+		t.Fatalf("复制失败: %v", err)
+	}
+	if clone.ID == source.ID || clone.AppID != "clone-target" || clone.UserID != 42 || clone.Status != "offline" || clone.PublishedVersion != 0 {
+		t.Errorf("新智能体 = %+v", clone)
+	}
+
+	// 原分类ID到新分类ID的映射，按名称对应
+	documentCategories := map[uint]uint{}
+	var sourceCategories, cloneCategories []models.DocumentCategory
+	config.DB.Where("agent_id = ?", source.ID).Order("id").Find(&sourceCategories)
+	config.DB.Where("agent_id = ?", clone.ID).Order("id").Find(&cloneCategories)
+	if len(cloneCategories) != len(sourceCategories) {
+		t.Fatalf("文档分类 = %+v", cloneCategories)
+	}
+	for i, category := range sourceCategories {
+		if cloneCategories[i].Name != category.Name || cloneCategories[i].Sort != category.Sort {
+			t.Errorf("文档分类 %d = %+v，期望 %+v", i, cloneCategories[i], category)
+		}
+		documentCategories[category.ID] = cloneCategories[i].ID
+	}
+	faqCategories := map[uint]uint{}
+	var sourceFAQCategories, cloneFAQCategories []models.FAQCategory
+	config.DB.Where("agent_id = ?", source.ID).Order("id").Find(&sourceFAQCategories)
+	config.DB.Where("agent_id = ?", clone.ID).Order("id").Find(&cloneFAQCategories)
+	if len(cloneFAQCategories) != len(sourceFAQCategories) {
+		t.Fatalf("问答分类 = %+v", cloneFAQCategories)
+	}
+	for i, category := range sourceFAQCategories {
+		faqCategories[category.ID] = cloneFAQCategories[i].ID
+	}
+
+	// 知识范围指向新分类，已删除的分类被丢弃
+	var reloaded models.Agent
+	config.DB.First(&reloaded, clone.ID)
+	wantDocumentScope := []uint{documentCategories[source.DocumentCategoryIDs[0]]}
+	wantFAQScope := []uint{faqCategories[source.FAQCategoryIDs[0]], faqCategories[source.FAQCategoryIDs[1]]}
+	if !reflect.DeepEqual(reloaded.DocumentCategoryIDs, wantDocumentScope) || !reflect.DeepEqual(reloaded.FAQCategoryIDs, wantFAQScope) {
+		t.Errorf("知识范围 = %v / %v，期望 %v / %v", reloaded.DocumentCategoryIDs, reloaded.FAQCategoryIDs, wantDocumentScope, wantFAQScope)
+	}
+
+	var sourceDocuments, cloneDocuments []models.Document
+	config.DB.Where("agent_id = ?", source.ID).Order("id").Find(&sourceDocuments)
+	config.DB.Where("agent_id = ?", clone.ID).Order("id").Find(&cloneDocuments)
+	if len(cloneDocuments) != len(sourceDocuments) || len(store.copied) != len(sourceDocuments) {
+		t.Fatalf("文档 = %+v，复制的文件 = %v", cloneDocuments, store.copied)
+	}
+	for i, document := range sourceDocuments {
+		copied := cloneDocuments[i]
+		if copied.Name != document.Name || copied.CategoryID != documentCategories[document.CategoryID] || copied.Status != models.DocumentStatusPending {
+			t.Errorf("文档 %s = %+v", document.Name, copied)
+		}
+		if copied.Path == document.Path || !strings.HasSuffix(copied.Path, store.copied[i]) {
+			t.Errorf("文档 %s 路径 = %s，复制的文件 = %s", document.Name, copied.Path, store.copied[i])
+		}
+	}
+	if cloneDocuments[2].CategoryID != 0 {
+		t.Errorf("未分类文档的分类 = %d", cloneDocuments[2].CategoryID)
+	}
+	var documentTags []models.DocumentTag
+	config.DB.Where("document_id = ?", cloneDocuments[0].ID).Find(&documentTags)
+	if len(documentTags) != 1 || documentTags[0].TagName != "产品" {
+		t.Errorf("文档标签 = %+v", documentTags)
+	}
+
+	var sourceFAQs, cloneFAQs []models.FAQ
+	config.DB.Where("agent_id = ?", source.ID).Order("id").Find(&sourceFAQs)
+	config.DB.Where("agent_id = ?", clone.ID).Order("id").Find(&cloneFAQs)
+	if len(cloneFAQs) != len(sourceFAQs) {
+		t.Fatalf("常见问答 = %+v", cloneFAQs)
+	}
+	for i, faq := range sourceFAQs {
+		if cloneFAQs[i].Question != faq.Question || cloneFAQs[i].CategoryID != faqCategories[faq.CategoryID] {
+			t.Errorf("常见问答 %s = %+v", faq.Question, cloneFAQs[i])
+		}
+	}
+	if len(store.deleted) != 0 {
+		t.Errorf("复制成功时不应删除文件: %v", store.deleted)
+	}
+}
+
+func TestCloneAgentCleansUpCopiedFiles(t *testing.T) {
+	source := createCloneSource(t, "clone-cleanup")
+
+	// 第二个文档的文件复制失败
+	store := &fakeObjectStore{failOn: "workspaces/3/uploads/2024-01-01/policy.pdf"}
+	if _, err := cloneAgent(source, 1, "clone-cleanup-copy", "副本", store.copyFile, store.deleteFile); err == nil || !strings.Contains(err.Error(), "政策.pdf") {
+		t.Fatalf("错误 = %v", err)
+	}
+	if len(store.copied) != 1 || !reflect.DeepEqual(store.deleted, store.copied) {
+		t.Errorf("复制的文件 = %v，删除的文件 = %v", store.copied, store.deleted)
+	}
+	var count int64
+	config.DB.Model(&models.Agent{}).Where("app_id = ?", "clone-cleanup-copy").Count(&count)
+	if count != 0 {
+		t.Error("复制失败时不应创建智能体")
+	}
+
+	// 文件全部复制后事务失败（AppID已存在），回滚并删除已复制的文件
+	store = &fakeObjectStore{}
+	if _, err := cloneAgent(source, 1, source.AppID, "副本", store.copyFile, store.deleteFile); !errors.Is(err, ErrAppIDExists) {
+		t.Fatalf("错误 = %v，期望 %v", err, ErrAppIDExists)
+	}
+	if len(store.copied) != 3 || !reflect.DeepEqual(store.deleted, store.copied) {
+		t.Errorf("复制的文件 = %v，删除的文件 = %v", store.copied, store.deleted)
+	}
+	config.DB.Model(&models.Agent{}).Where("app_id = ?", source.AppID).Count(&count)
+	if count != 1 {
+		t.Errorf("AppID为 %s 的智能体有 %d 个", source.AppID, count)
+	}
+}
+
+func TestAgentAppIDUnique(t *testing.T) {
+	createCloneSource(t, "clone-unique")
+	if err := config.DB.Create(&models.Agent{AppID: "clone-unique", Name: "重复"}).Error; err == nil {
+		t.Error("AppID重复时应创建失败")
+	}
+}
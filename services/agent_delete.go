@@ -0,0 +1,58 @@
+package services
+
+import (
+	"context"
+	"log"
+
+	"ai-assistant-backend/config"
+	"ai-assistant-backend/models"
+
+	"gorm.io/gorm"
+)
+
+// DeleteAgent 删除智能体及其轮播图、自助服务、发布版本、文档分类、文档、标签、问答分类、
+// 常见问答与对话记录。数据库记录在同一事务中删除，提交后再通过向量存储删除文档切片
+func DeleteAgent(ctx context.Context, agent *models.Agent) error {
+	var documentIDs []uint
+	err := config.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Document{}).Where("agent_id = ?", agent.ID).Pluck("id", &documentIDs).Error; err != nil {
+			return err
+		}
+		if len(documentIDs) > 0 {
+			if err := tx.Where("document_id IN ?", documentIDs).Delete(&models.DocumentTag{}).Error; err != nil {
+				return err
+			}
+		}
+
+		for _, model := range []interface{}{
+			&models.AgentCarouselImage{},
+			&models.SelfService{},
+			&models.AgentVersion{},
+			&models.Document{},
+			&models.DocumentCategory{},
+			&models.Tag{},
+			&models.FAQ{},
+			&models.FAQCategory{},
+			&models.Message{},
+			&models.Conversation{},
+		} {
+			if err := tx.Where("agent_id = ?", agent.ID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Delete(agent).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	// 文档记录已删除，切片删除失败只影响存储空间，不会再被检索到
+	store := GetVectorStore()
+	for _, documentID := range documentIDs {
+		if err := store.DeleteDocumentChunks(ctx, documentID); err != nil {
+			log.Printf("[agent] 删除文档 %d 的切片失败: %v", documentID, err)
+		}
+	}
+	log.Printf("[agent] 智能体 %d 已删除（%d 个文档）", agent.ID, len(documentIDs))
+	return nil
+}
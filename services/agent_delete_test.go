@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"ai-assistant-backend/config"
+	"ai-assistant-backend/models"
+)
+
+// recordingVectorStore 记录被删除切片的文档
+type recordingVectorStore struct {
+	DBVectorStore
+	deleted []uint
+}
+
+func (s *recordingVectorStore) DeleteDocumentChunks(ctx context.Context, documentID uint) error {
+	s.deleted = append(s.deleted, documentID)
+	return s.DBVectorStore.DeleteDocumentChunks(ctx, documentID)
+}
+
+// createTestAgentData 创建智能体及其知识库、发布版本与对话记录，返回智能体与文档ID
+func createTestAgentData(t *testing.T, appID string) (*models.Agent, uint) {
+	t.Helper()
+	db := config.DB
+	agent := &models.Agent{AppID: appID, Name: appID}
+	must := func(err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	must(db.Create(agent).Error)
+	must(db.Create(&models.AgentCarouselImage{AgentID: agent.ID, ImageURL: "https://example.com/1.png"}).Error)
+	must(db.Create(&models.SelfService{AgentID: agent.ID, Name: "官网", Link: "https://example.com"}).Error)
+	must(db.Create(&models.AgentVersion{AgentID: agent.ID, Version: 1}).Error)
+
+	category := &models.DocumentCategory{AgentID: agent.ID, Name: "手册"}
+	must(db.Create(category).Error)
+	document := &models.Document{AgentID: agent.ID, CategoryID: category.ID, Name: "手册.pdf", Status: models.DocumentStatusReady}
+	must(db.Create(document).Error)
+	must(db.Create(&models.DocumentTag{DocumentID: document.ID, TagName: "产品"}).Error)
+	must(db.Create(&models.Tag{AgentID: agent.ID, Name: "产品"}).Error)
+	must(db.Create(&models.DocumentChunk{AgentID: agent.ID, DocumentID: document.ID, Content: "内容"}).Error)
+
+	faqCategory := &models.FAQCategory{AgentID: agent.ID, Name: "常见问题"}
+	must(db.Create(faqCategory).Error)
+	must(db.Create(&models.FAQ{AgentID: agent.ID, CategoryID: faqCategory.ID, Question: "问题", Answer: "答案"}).Error)
+
+	conversation := &models.Conversation{AgentID: agent.ID, SessionID: appID + "-session"}
+	must(db.Create(conversation).Error)
+	must(db.Create(&models.Message{AgentID: agent.ID, ConversationID: conversation.ID, Role: "user", Content: "你好"}).Error)
+	return agent, document.ID
+}
+
+// countAgentRows 统计各表中属于智能体的记录数
+func countAgentRows(t *testing.T, agentID, documentID uint) map[string]int64 {
+	t.Helper()
+	counts := map[string]int64{}
+	for _, model := range []interface{}{
+		&models.Agent{},
+		&models.AgentCarouselImage{},
+		&models.SelfService{},
+		&models.AgentVersion{},
+		&models.DocumentCategory{},
+		&models.Document{},
+		&models.DocumentChunk{},
+		&models.Tag{},
+		&models.FAQCategory{},
+		&models.FAQ{},
+		&models.Conversation{},
+		&models.Message{},
+	} {
+		column := "agent_id"
+		if _, ok := model.(*models.Agent); ok {
+			column = "id"
+		}
+		var count int64
+		if err := config.DB.Model(model).Where(column+" = ?", agentID).Count(&count).Error; err != nil {
+			t.Fatal(err)
+		}
+		counts[fmt.Sprintf("%T", model)] = count
+	}
+	var tags int64
+	config.DB.Model(&models.DocumentTag{}).Where("document_id = ?", documentID).Count(&tags)
+	counts["*models.DocumentTag"] = tags
+	return counts
+}
+
+func TestDeleteAgentRemovesRelatedData(t *testing.T) {
+	store := &recordingVectorStore{}
+	previous := GetVectorStore()
+	SetVectorStore(store)
+	t.Cleanup(func() { SetVectorStore(previous) })
+
+	agent, documentID := createTestAgentData(t, "delete-target")
+	other, otherDocumentID := createTestAgentData(t, "delete-other")
+
+	if err := DeleteAgent(context.Background(), agent); err != nil {
+		t.Fatalf("删除智能体失败: %v", err)
+	}
+
+	for table, count := range countAgentRows(t, agent.ID, documentID) {
+		if count != 0 {
+			t.Errorf("%s 仍有 %d 条记录", table, count)
+		}
+	}
+	for table, count := range countAgentRows(t, other.ID, otherDocumentID) {
+		if count == 0 {
+			t.Errorf("其他智能体的 %s 记录被删除", table)
+		}
+	}
+	if len(store.deleted) != 1 || store.deleted[0] != documentID {
+		t.Errorf("通过向量存储删除的文档 = %v，期望 [%d]", store.deleted, documentID)
+	}
+}
@@ -0,0 +1,333 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+	"strings"
+
+	"ai-assistant-backend/config"
+	"ai-assistant-backend/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrAgentNotPublished 智能体尚未发布任何版本
+	ErrAgentNotPublished = errors.New("智能体尚未发布")
+	// ErrAgentVersionNotFound 版本不存在
+	ErrAgentVersionNotFound = errors.New("版本不存在")
+	// ErrNoDraftChanges 草稿与当前发布的版本一致
+	ErrNoDraftChanges = errors.New("草稿与当前发布的版本一致，无需发布")
+)
+
+// SnapshotChange 两个快照中取值不同的字段
+type SnapshotChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+// DraftSnapshot 读取智能体当前的草稿配置，轮播图与自助服务按显示顺序排列
+func DraftSnapshot(agent *models.Agent) (*models.AgentSnapshot, error) {
+	var carouselImages []models.AgentCarouselImage
+	if err := config.DB.Where("agent_id = ?", agent.ID).Order("sort").Find(&carouselImages).Error; err != nil {
+		return nil, err
+	}
+	var selfServices []models.SelfService
+	if err := config.DB.Where("agent_id = ?", agent.ID).Order("sort, id").Find(&selfServices).Error; err != nil {
+		return nil, err
+	}
+
+	snapshot := &models.AgentSnapshot{
+		WelcomeMsg:          agent.WelcomeMsg,
+		NoAnswerMsg:         agent.NoAnswerMsg,
+		Model:               agent.Model,
+		SystemPrompt:        agent.SystemPrompt,
+		Persona:             agent.Persona,
+		Temperature:         agent.Temperature,
+		MaxTokens:           agent.MaxTokens,
+		RetrievalTopK:       agent.RetrievalTopK,
+		RetrievalMinScore:   agent.RetrievalMinScore,
+		DocumentCategoryIDs: append([]uint{}, agent.DocumentCategoryIDs...),
+		FAQCategoryIDs:      append([]uint{}, agent.FAQCategoryIDs...),
+		CarouselImages:      []string{},
+		SelfServices:        []models.SnapshotSelfService{},
+	}
+	for _, img := range carouselImages {
+		snapshot.CarouselImages = append(snapshot.CarouselImages, img.ImageURL)
+	}
+	for _, service := range selfServices {
+		snapshot.SelfServices = append(snapshot.SelfServices, models.SnapshotSelfService{
+			Name: service.Name,
+			Link: service.Link,
+			Icon: service.Icon,
+		})
+	}
+	return snapshot, nil
+}
+
+// GetAgentVersion 获取智能体的指定版本
+func GetAgentVersion(agentID uint, version int) (*models.AgentVersion, error) {
+	var item models.AgentVersion
+	err := config.DB.Where("agent_id = ? AND version = ?", agentID, version).First(&item).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrAgentVersionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// ListAgentVersions 按版本号倒序列出智能体的发布记录，不含快照内容
+func ListAgentVersions(agentID uint) ([]models.AgentVersion, error) {
+	versions := []models.AgentVersion{}
+	err := config.DB.Omit("snapshot").Where("agent_id = ?", agentID).Order("version DESC").Find(&versions).Error
+	return versions, err
+}
+
+// HasDraftChanges 草稿是否与当前发布的版本不同，尚未发布时始终为true
+func HasDraftChanges(agent *models.Agent) (bool, error) {
+	if agent.PublishedVersion == 0 {
+		return true, nil
+	}
+	draft, err := DraftSnapshot(agent)
+	if err != nil {
+		return false, err
+	}
+	published, err := GetAgentVersion(agent.ID, agent.PublishedVersion)
+	if err != nil {
+		return false, err
+	}
+	changes, err := DiffSnapshots(&published.Snapshot, draft)
+	if err != nil {
+		return false, err
+	}
+	return len(changes) > 0, nil
+}
+
+// PublishAgent 将草稿发布为新版本，发布后访客立即使用新版本的配置
+func PublishAgent(agent *models.Agent, userID uint, note string) (*models.AgentVersion, error) {
+	draft, err := DraftSnapshot(agent)
+	if err != nil {
+		return nil, fmt.Errorf("读取草稿失败: %v", err)
+	}
+	if agent.PublishedVersion > 0 {
+		published, err := GetAgentVersion(agent.ID, agent.PublishedVersion)
+		if err != nil {
+			return nil, err
+		}
+		changes, err := DiffSnapshots(&published.Snapshot, draft)
+		if err != nil {
+			return nil, err
+		}
+		if len(changes) == 0 {
+			return nil, ErrNoDraftChanges
+		}
+	}
+
+	var version *models.AgentVersion
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		version, err = createAgentVersion(tx, agent, draft, userID, note, 0)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return version, nil
+}
+
+// RollbackAgent 将指定的历史版本重新发布为新版本，并把草稿恢复为该版本的配置，
+// 尚未发布的草稿修改会被丢弃
+func RollbackAgent(agent *models.Agent, target int, userID uint, note string) (*models.AgentVersion, error) {
+	source, err := GetAgentVersion(agent.ID, target)
+	if err != nil {
+		return nil, err
+	}
+	if note == "" {
+		note = fmt.Sprintf("回滚到版本%d", target)
+	}
+
+	var version *models.AgentVersion
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := restoreDraft(tx, agent, &source.Snapshot); err != nil {
+			return err
+		}
+		version, err = createAgentVersion(tx, agent, &source.Snapshot, userID, note, target)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return version, nil
+}
+
+// createAgentVersion 锁定智能体后分配下一个版本号并设为当前发布版本
+func createAgentVersion(tx *gorm.DB, agent *models.Agent, snapshot *models.AgentSnapshot, userID uint, note string, rollbackFrom int) (*models.AgentVersion, error) {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&models.Agent{}, agent.ID).Error; err != nil {
+		return nil, err
+	}
+
+	var latest int
+	if err := tx.Model(&models.AgentVersion{}).Where("agent_id = ?", agent.ID).Select("COALESCE(MAX(version), 0)").Scan(&latest).Error; err != nil {
+		return nil, err
+	}
+
+	version := &models.AgentVersion{
+		AgentID:      agent.ID,
+		Version:      latest + 1,
+		Snapshot:     *snapshot,
+		Note:         strings.TrimSpace(note),
+		PublishedBy:  userID,
+		RollbackFrom: rollbackFrom,
+	}
+	if err := tx.Create(version).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Model(agent).Update("published_version", version.Version).Error; err != nil {
+		return nil, err
+	}
+	agent.PublishedVersion = version.Version
+	return version, nil
+}
+
+// restoreDraft 用快照覆盖智能体的草稿配置、轮播图与自助服务
+func restoreDraft(tx *gorm.DB, agent *models.Agent, snapshot *models.AgentSnapshot) error {
+	applySnapshot(agent, snapshot)
+	err := tx.Model(agent).Select(
+		"welcome_msg", "no_answer_msg", "model", "system_prompt", "persona", "temperature", "max_tokens",
+		"retrieval_top_k", "retrieval_min_score", "document_category_ids", "faq_category_ids",
+	).Updates(agent).Error
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Where("agent_id = ?", agent.ID).Delete(&models.AgentCarouselImage{}).Error; err != nil {
+		return err
+	}
+	for i, imageURL := range snapshot.CarouselImages {
+		if err := tx.Create(&models.AgentCarouselImage{AgentID: agent.ID, ImageURL: imageURL, Sort: i + 1}).Error; err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Where("agent_id = ?", agent.ID).Delete(&models.SelfService{}).Error; err != nil {
+		return err
+	}
+	for i := range agent.SelfServices {
+		if err := tx.Create(&agent.SelfServices[i]).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ApplyPublishedVersion 用当前发布版本的配置覆盖智能体中的草稿字段，供访客对话使用。
+// 覆盖后的智能体不能再保存到数据库
+func ApplyPublishedVersion(agent *models.Agent) error {
+	if agent.PublishedVersion == 0 {
+		return ErrAgentNotPublished
+	}
+	version, err := GetAgentVersion(agent.ID, agent.PublishedVersion)
+	if err != nil {
+		return err
+	}
+	applySnapshot(agent, &version.Snapshot)
+	return nil
+}
+
+// applySnapshot 将快照中的配置写入智能体，轮播图与自助服务写入对应的非数据库字段
+func applySnapshot(agent *models.Agent, snapshot *models.AgentSnapshot) {
+	agent.WelcomeMsg = snapshot.WelcomeMsg
+	agent.NoAnswerMsg = snapshot.NoAnswerMsg
+	agent.Model = snapshot.Model
+	agent.SystemPrompt = snapshot.SystemPrompt
+	agent.Persona = snapshot.Persona
+	agent.Temperature = snapshot.Temperature
+	agent.MaxTokens = snapshot.MaxTokens
+	agent.RetrievalTopK = snapshot.RetrievalTopK
+	agent.RetrievalMinScore = snapshot.RetrievalMinScore
+	agent.DocumentCategoryIDs = append([]uint{}, snapshot.DocumentCategoryIDs...)
+	agent.FAQCategoryIDs = append([]uint{}, snapshot.FAQCategoryIDs...)
+	agent.CarouselImages = append([]string{}, snapshot.CarouselImages...)
+	agent.SelfServices = make([]models.SelfService, 0, len(snapshot.SelfServices))
+	for i, service := range snapshot.SelfServices {
+		agent.SelfServices = append(agent.SelfServices, models.SelfService{
+			AgentID: agent.ID,
+			Name:    service.Name,
+			Link:    service.Link,
+			Icon:    service.Icon,
+			Sort:    i + 1,
+		})
+	}
+}
+
+// DiffSnapshots 按快照字段的顺序列出from与to中取值不同的字段
+func DiffSnapshots(from, to *models.AgentSnapshot) ([]SnapshotChange, error) {
+	fromFields, err := snapshotFields(from)
+	if err != nil {
+		return nil, err
+	}
+	toFields, err := snapshotFields(to)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := []SnapshotChange{}
+	snapshotType := reflect.TypeOf(models.AgentSnapshot{})
+	for i := 0; i < snapshotType.NumField(); i++ {
+		field, _, _ := strings.Cut(snapshotType.Field(i).Tag.Get("json"), ",")
+		if !reflect.DeepEqual(fromFields[field], toFields[field]) {
+			changes = append(changes, SnapshotChange{Field: field, From: fromFields[field], To: toFields[field]})
+		}
+	}
+	return changes, nil
+}
+
+// snapshotFields 将快照转换为字段名到JSON取值的映射，空列表与nil视为相同
+func snapshotFields(snapshot *models.AgentSnapshot) (map[string]interface{}, error) {
+	normalized := *snapshot
+	if normalized.DocumentCategoryIDs == nil {
+		normalized.DocumentCategoryIDs = []uint{}
+	}
+	if normalized.FAQCategoryIDs == nil {
+		normalized.FAQCategoryIDs = []uint{}
+	}
+	if normalized.CarouselImages == nil {
+		normalized.CarouselImages = []string{}
+	}
+	if normalized.SelfServices == nil {
+		normalized.SelfServices = []models.SnapshotSelfService{}
+	}
+
+	data, err := json.Marshal(normalized)
+	if err != nil {
+		return nil, err
+	}
+	fields := map[string]interface{}{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// MigrateAgentVersions 为升级前已上线的智能体以当前配置发布初始版本，保证上线的智能体都有发布版本
+func MigrateAgentVersions() error {
+	var agents []models.Agent
+	if err := config.DB.Where("status = ? AND published_version = ?", "online", 0).Find(&agents).Error; err != nil {
+		return err
+	}
+	for i := range agents {
+		if _, err := PublishAgent(&agents[i], agents[i].UserID, "初始版本"); err != nil {
+			return err
+		}
+	}
+	if len(agents) > 0 {
+		log.Printf("[agent] 已为 %d 个上线的智能体发布初始版本", len(agents))
+	}
+	return nil
+}
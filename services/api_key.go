@@ -0,0 +1,137 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"ai-assistant-backend/config"
+	"ai-assistant-backend/models"
+	"ai-assistant-backend/utils"
+
+	"gorm.io/gorm"
+)
+
+const (
+	apiKeyPrefix = "ak_"
+	// apiKeyPrefixLength 用于识别密钥的前缀长度（含ak_）
+	apiKeyPrefixLength = 11
+	// apiKeyTouchInterval 最近使用时间的最小更新间隔，避免每次请求都写数据库
+	apiKeyTouchInterval = time.Minute
+)
+
+var (
+	// ErrAPIKeyInvalid API密钥不存在或已撤销
+	ErrAPIKeyInvalid = errors.New("无效的API密钥")
+	// ErrAPIKeyExpired API密钥已过期
+	ErrAPIKeyExpired = errors.New("API密钥已过期")
+)
+
+// APIKeyScopeError 权限范围无效或超出创建者的权限
+type APIKeyScopeError struct {
+	Scope string
+}
+
+func (e *APIKeyScopeError) Error() string {
+	return fmt.Sprintf("无效的权限范围: %s", e.Scope)
+}
+
+// CreateAPIKey 为用户在工作空间中创建API密钥，返回的明文密钥只展示一次。
+// 权限范围不能超出创建者的全局角色与成员角色的权限
+func CreateAPIKey(member *models.WorkspaceMember, user *models.User, name string, scopes []string, expiresAt *time.Time) (*models.APIKey, string, error) {
+	normalized, err := normalizeAPIKeyScopes(scopes, user.Role, member.Role)
+	if err != nil {
+		return nil, "", err
+	}
+
+	random, err := utils.GenerateRandomString(40)
+	if err != nil {
+		return nil, "", fmt.Errorf("生成API密钥失败: %v", err)
+	}
+	key := apiKeyPrefix + random
+
+	apiKey := models.APIKey{
+		WorkspaceID: member.WorkspaceID,
+		UserID:      user.ID,
+		Name:        strings.TrimSpace(name),
+		Prefix:      key[:apiKeyPrefixLength],
+		KeyHash:     utils.HashToken(key),
+		Scopes:      normalized,
+		ExpiresAt:   expiresAt,
+	}
+	if err := config.DB.Create(&apiKey).Error; err != nil {
+		return nil, "", fmt.Errorf("保存API密钥失败: %v", err)
+	}
+	return &apiKey, key, nil
+}
+
+// normalizeAPIKeyScopes 校验并去重权限范围，按APIKeyScopes的顺序返回
+func normalizeAPIKeyScopes(scopes []string, userRole, memberRole uint8) ([]string, error) {
+	requested := map[string]bool{}
+	for _, scope := range scopes {
+		scope = strings.TrimSpace(scope)
+		if !isAPIKeyScope(scope) {
+			return nil, &APIKeyScopeError{Scope: scope}
+		}
+		if userRole != models.RoleSuperAdmin &&
+			!(utils.HasPermission(userRole, utils.Permission(scope)) && utils.HasPermission(memberRole, utils.Permission(scope))) {
+			return nil, &APIKeyScopeError{Scope: scope}
+		}
+		requested[scope] = true
+	}
+
+	normalized := []string{}
+	for _, scope := range utils.APIKeyScopes {
+		if requested[string(scope)] {
+			normalized = append(normalized, string(scope))
+		}
+	}
+	return normalized, nil
+}
+
+func isAPIKeyScope(scope string) bool {
+	for _, item := range utils.APIKeyScopes {
+		if string(item) == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// AuthenticateAPIKey 按明文密钥查找API密钥及其创建者
+func AuthenticateAPIKey(key string) (*models.APIKey, *models.User, error) {
+	if !strings.HasPrefix(key, apiKeyPrefix) {
+		return nil, nil, ErrAPIKeyInvalid
+	}
+
+	var apiKey models.APIKey
+	err := config.DB.Where("key_hash = ?", utils.HashToken(key)).First(&apiKey).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, ErrAPIKeyInvalid
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if apiKey.Expired() {
+		return nil, nil, ErrAPIKeyExpired
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, apiKey.UserID).Error; err != nil {
+		return nil, nil, ErrAPIKeyInvalid
+	}
+	return &apiKey, &user, nil
+}
+
+// TouchAPIKey 记录密钥最近使用的时间与IP，每分钟最多更新一次
+func TouchAPIKey(apiKey *models.APIKey, ip string) {
+	now := time.Now()
+	if apiKey.LastUsedAt != nil && now.Sub(*apiKey.LastUsedAt) < apiKeyTouchInterval && apiKey.LastUsedIP == ip {
+		return
+	}
+	config.DB.Model(apiKey).UpdateColumns(map[string]interface{}{
+		"last_used_at": now,
+		"last_used_ip": ip,
+	})
+}
@@ -0,0 +1,317 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"ai-assistant-backend/config"
+	"ai-assistant-backend/models"
+	"ai-assistant-backend/utils"
+
+	"gorm.io/gorm"
+)
+
+// 对话角色
+const (
+	RoleSystem    = "system"
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+)
+
+// maxHistoryMessages 参与回复生成的最大历史消息数
+const maxHistoryMessages = 20
+
+var (
+	// ErrConversationNotFound 会话不存在
+	ErrConversationNotFound = errors.New("会话不存在")
+	// ErrMessageNotFound 消息不存在
+	ErrMessageNotFound = errors.New("消息不存在")
+)
+
+// StartConversation 创建会话，并以智能体欢迎语作为第一条消息
+func StartConversation(agent *models.Agent, visitorID string) (*models.Conversation, []models.Message, error) {
+	sessionID, err := utils.GenerateRandomString(32)
+	if err != nil {
+		return nil, nil, fmt.Errorf("生成会话ID失败: %v", err)
+	}
+
+	conversation := &models.Conversation{
+		AgentID:       agent.ID,
+		SessionID:     sessionID,
+		VisitorID:     visitorID,
+		LastMessageAt: time.Now(),
+	}
+	if err := config.DB.Create(conversation).Error; err != nil {
+		return nil, nil, fmt.Errorf("保存会话失败: %v", err)
+	}
+
+	messages := []models.Message{}
+	if agent.WelcomeMsg != "" {
+		welcome := &models.Message{Role: RoleAssistant, Content: agent.WelcomeMsg}
+		if err := appendMessage(conversation, welcome); err != nil {
+			return nil, nil, err
+		}
+		messages = append(messages, *welcome)
+	}
+
+	return conversation, messages, nil
+}
+
+// GetConversation 根据会话标识获取属于指定智能体的会话
+func GetConversation(agentID uint, sessionID string) (*models.Conversation, error) {
+	var conversation models.Conversation
+	if err := config.DB.Where("agent_id = ? AND session_id = ?", agentID, sessionID).First(&conversation).Error; err != nil {
+		return nil, ErrConversationNotFound
+	}
+	return &conversation, nil
+}
+
+// GetMessages 获取会话的全部消息
+func GetMessages(conversationID uint) ([]models.Message, error) {
+	var messages []models.Message
+	if err := config.DB.Where("conversation_id = ?", conversationID).Order("id").Find(&messages).Error; err != nil {
+		return nil, fmt.Errorf("获取会话消息失败: %v", err)
+	}
+	return messages, nil
+}
+
+// SetMessageFeedback 记录访客对智能体回复的评价，feedback为空表示取消评价
+func SetMessageFeedback(conversation *models.Conversation, messageID uint, feedback, comment string) (*models.Message, error) {
+	var message models.Message
+	if err := config.DB.Where("id = ? AND conversation_id = ? AND role = ?", messageID, conversation.ID, RoleAssistant).First(&message).Error; err != nil {
+		return nil, ErrMessageNotFound
+	}
+
+	if err := config.DB.Model(&message).Updates(map[string]interface{}{
+		"feedback":         feedback,
+		"feedback_comment": comment,
+	}).Error; err != nil {
+		return nil, fmt.Errorf("保存评价失败: %v", err)
+	}
+	return &message, nil
+}
+
+// DocumentCitation 回复引用的文档片段
+type DocumentCitation struct {
+	DocumentID   uint    `json:"document_id"`
+	DocumentName string  `json:"document_name"`
+	ChunkID      uint    `json:"chunk_id"`
+	ChunkIndex   int     `json:"chunk_index"`
+	Score        float64 `json:"score"`
+}
+
+// FAQCitation 回复引用的常见问答
+type FAQCitation struct {
+	FAQID    uint    `json:"faq_id"`
+	Question string  `json:"question"`
+	Score    float64 `json:"score"`
+}
+
+// ChatReply 智能体回复及其用量与引用
+type ChatReply struct {
+	Message   *models.Message    `json:"message"`
+	Usage     utils.LLMUsage     `json:"usage"`
+	Documents []DocumentCitation `json:"documents"`
+	FAQs      []FAQCitation      `json:"faqs"`
+}
+
+// replyPlan 回复生成计划：命中常见问答或未检索到资料时直接使用answer，否则调用大模型
+type replyPlan struct {
+	startedAt time.Time
+	answer    string
+	provider  utils.LLMProvider
+	request   utils.ChatCompletionRequest
+	faqID     uint // 直接命中的常见问答
+	documents []DocumentCitation
+	faqs      []FAQCitation
+}
+
+// SendMessage 记录访客消息并生成智能体回复
+func SendMessage(ctx context.Context, agent *models.Agent, conversation *models.Conversation, content string) (*ChatReply, error) {
+	plan, err := beginTurn(ctx, agent, conversation, content)
+	if err != nil {
+		return nil, err
+	}
+
+	if plan.provider == nil {
+		return finishTurn(conversation, plan, plan.answer, utils.LLMUsage{})
+	}
+
+	completion, err := plan.provider.ChatCompletion(ctx, plan.request)
+	if err != nil {
+		return nil, err
+	}
+	return finishTurn(conversation, plan, completion.Content, completion.Usage)
+}
+
+// StreamMessage 记录访客消息并流式生成回复，每段增量内容通过onDelta推送。
+// 访客断开连接（ctx取消）时停止生成，已生成的部分仍会保存到会话中
+func StreamMessage(ctx context.Context, agent *models.Agent, conversation *models.Conversation, content string, onDelta func(delta string)) (*ChatReply, error) {
+	plan, err := beginTurn(ctx, agent, conversation, content)
+	if err != nil {
+		return nil, err
+	}
+
+	if plan.provider == nil {
+		onDelta(plan.answer)
+		return finishTurn(conversation, plan, plan.answer, utils.LLMUsage{})
+	}
+
+	events, err := plan.provider.ChatCompletionStream(ctx, plan.request)
+	if err != nil {
+		return nil, err
+	}
+
+	var reply strings.Builder
+	var usage utils.LLMUsage
+	for event := range events {
+		if event.Err != nil {
+			return nil, event.Err
+		}
+		if event.Usage != nil {
+			usage = *event.Usage
+		}
+		if event.Delta != "" {
+			reply.WriteString(event.Delta)
+			onDelta(event.Delta)
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		if reply.Len() > 0 {
+			finishTurn(conversation, plan, reply.String(), usage)
+		}
+		return nil, err
+	}
+	return finishTurn(conversation, plan, reply.String(), usage)
+}
+
+// beginTurn 保存访客消息并确定回复方式
+func beginTurn(ctx context.Context, agent *models.Agent, conversation *models.Conversation, content string) (*replyPlan, error) {
+	startedAt := time.Now()
+
+	history, err := recentMessages(conversation.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := appendMessage(conversation, &models.Message{Role: RoleUser, Content: content}); err != nil {
+		return nil, err
+	}
+
+	plan, err := prepareReply(ctx, agent, history, content)
+	if err != nil {
+		return nil, err
+	}
+	plan.startedAt = startedAt
+	return plan, nil
+}
+
+// finishTurn 保存智能体回复及其用量与耗时
+func finishTurn(conversation *models.Conversation, plan *replyPlan, content string, usage utils.LLMUsage) (*ChatReply, error) {
+	message := &models.Message{
+		Role:             RoleAssistant,
+		Content:          content,
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		LatencyMs:        time.Since(plan.startedAt).Milliseconds(),
+		FAQID:            plan.faqID,
+	}
+	if err := appendMessage(conversation, message); err != nil {
+		return nil, err
+	}
+	if plan.faqID != 0 {
+		if err := RecordFAQHit(plan.faqID); err != nil {
+			log.Printf("[chat] 记录常见问答命中失败: %v", err)
+		}
+	}
+
+	return &ChatReply{
+		Message:   message,
+		Usage:     usage,
+		Documents: plan.documents,
+		FAQs:      plan.faqs,
+	}, nil
+}
+
+// recentMessages 获取最近的历史消息，按时间正序排列
+func recentMessages(conversationID uint) ([]models.Message, error) {
+	var messages []models.Message
+	if err := config.DB.Where("conversation_id = ?", conversationID).Order("id desc").Limit(maxHistoryMessages).Find(&messages).Error; err != nil {
+		return nil, fmt.Errorf("获取会话消息失败: %v", err)
+	}
+	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+		messages[i], messages[j] = messages[j], messages[i]
+	}
+	return messages, nil
+}
+
+// appendMessage 保存消息并更新会话的消息数与最后消息时间
+func appendMessage(conversation *models.Conversation, message *models.Message) error {
+	message.ConversationID = conversation.ID
+	message.AgentID = conversation.AgentID
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(message).Error; err != nil {
+			return err
+		}
+		return tx.Model(conversation).Updates(map[string]interface{}{
+			"message_count":   gorm.Expr("message_count + ?", 1),
+			"last_message_at": message.CreatedAt,
+		}).Error
+	})
+	if err != nil {
+		return fmt.Errorf("保存会话消息失败: %v", err)
+	}
+	return nil
+}
+
+// prepareReply 优先使用命中的常见问答答案，未命中时检索知识库并准备大模型请求
+func prepareReply(ctx context.Context, agent *models.Agent, history []models.Message, question string) (*replyPlan, error) {
+	plan := &replyPlan{documents: []DocumentCitation{}, faqs: []FAQCitation{}}
+
+	match, err := MatchFAQ(ctx, agent, question)
+	if err != nil {
+		return nil, err
+	}
+	if match != nil {
+		plan.answer = match.FAQ.Answer
+		plan.faqID = match.FAQ.ID
+		plan.faqs = append(plan.faqs, FAQCitation{FAQID: match.FAQ.ID, Question: match.FAQ.Question, Score: match.Score})
+		return plan, nil
+	}
+
+	retrieval, err := RetrieveKnowledge(ctx, agent, question)
+	if err != nil {
+		return nil, err
+	}
+	if retrieval.Empty() {
+		plan.answer = NoAnswerMessage(agent)
+		return plan, nil
+	}
+	plan.documents, plan.faqs = retrieval.Citations()
+
+	provider, request, err := buildChatRequest(agent, retrieval, history, question)
+	if err != nil {
+		return nil, err
+	}
+	plan.provider = provider
+	plan.request = request
+	return plan, nil
+}
+
+// normalizeQuestion 去除问题中的空白与标点，统一为小写
+func normalizeQuestion(question string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(question) {
+		if strings.ContainsRune(" \t\r\n?？!！。.,，、;；:：\"'“”‘’", r) {
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
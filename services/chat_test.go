@@ -0,0 +1,220 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"ai-assistant-backend/config"
+	"ai-assistant-backend/models"
+)
+
+const (
+	testChatDocument = "退货政策：商品签收后七天内可以无理由退货，退货运费由买家承担。质量问题退货运费由商家承担。"
+	testChatQuestion = "签收后多久可以退货"
+)
+
+// createChatAgent 创建带有一份已解析文档与一条常见问答的智能体，返回智能体与文档
+func createChatAgent(t *testing.T, appID string) (*models.Agent, *models.Document) {
+	t.Helper()
+	agent := &models.Agent{AppID: appID, Name: "客服", WelcomeMsg: "您好，我是客服", RetrievalMinScore: 0.1}
+	if err := config.DB.Create(agent).Error; err != nil {
+		t.Fatal(err)
+	}
+	document := &models.Document{AgentID: agent.ID, Name: "退货政策.txt", Status: models.DocumentStatusReady}
+	if err := config.DB.Create(document).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := indexDocument(document, testChatDocument); err != nil {
+		t.Fatalf("索引文档失败: %v", err)
+	}
+	faq := &models.FAQ{AgentID: agent.ID, Question: "如何修改收货地址？", Answer: "请在订单详情页点击“修改地址”。"}
+	if err := config.DB.Create(faq).Error; err != nil {
+		t.Fatal(err)
+	}
+	return agent, document
+}
+
+func startTestConversation(t *testing.T, agent *models.Agent) *models.Conversation {
+	t.Helper()
+	conversation, messages, err := StartConversation(agent, "visitor")
+	if err != nil {
+		t.Fatalf("创建会话失败: %v", err)
+	}
+	if len(messages) != 1 || messages[0].Content != agent.WelcomeMsg {
+		t.Fatalf("欢迎语 = %+v", messages)
+	}
+	return conversation
+}
+
+func TestSendMessageWithRetrieval(t *testing.T) {
+	agent, document := createChatAgent(t, "chat-send")
+	conversation := startTestConversation(t, agent)
+
+	reply, err := SendMessage(context.Background(), agent, conversation, testChatQuestion)
+	if err != nil {
+		t.Fatalf("发送消息失败: %v", err)
+	}
+
+	want := "您好，关于“" + testChatQuestion + "”，这是模拟模型的回复。"
+	if reply.Message.Content != want {
+		t.Errorf("回复 = %q，期望 %q", reply.Message.Content, want)
+	}
+	if reply.Usage.PromptTokens == 0 || reply.Usage.CompletionTokens != utf8.RuneCountInString(want) {
+		t.Errorf("用量 = %+v", reply.Usage)
+	}
+	if reply.Message.PromptTokens != reply.Usage.PromptTokens || reply.Message.CompletionTokens != reply.Usage.CompletionTokens {
+		t.Errorf("消息中记录的用量 = %d/%d", reply.Message.PromptTokens, reply.Message.CompletionTokens)
+	}
+	if len(reply.Documents) != 1 || reply.Documents[0].DocumentID != document.ID || reply.Documents[0].DocumentName != document.Name {
+		t.Errorf("文档引用 = %+v", reply.Documents)
+	} else if reply.Documents[0].Score < agent.RetrievalMinScore {
+		t.Errorf("引用的相似度 %f 低于阈值", reply.Documents[0].Score)
+	}
+	if reply.Message.FAQID != 0 {
+		t.Errorf("不应直接命中常见问答: %d", reply.Message.FAQID)
+	}
+
+	messages, err := GetMessages(conversation.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 3 || messages[1].Role != RoleUser || messages[1].Content != testChatQuestion || messages[2].Content != want {
+		t.Errorf("会话消息 = %+v", messages)
+	}
+	var reloaded models.Conversation
+	config.DB.First(&reloaded, conversation.ID)
+	if reloaded.MessageCount != 3 {
+		t.Errorf("消息数 = %d，期望 3", reloaded.MessageCount)
+	}
+}
+
+func TestPrepareReplyIncludesRetrievedKnowledge(t *testing.T) {
+	agent, _ := createChatAgent(t, "chat-prompt")
+
+	plan, err := prepareReply(context.Background(), agent, nil, testChatQuestion)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.provider == nil {
+		t.Fatal("检索到资料时应调用大模型")
+	}
+	if plan.request.Model != "mock-chat" {
+		t.Errorf("模型 = %s", plan.request.Model)
+	}
+	messages := plan.request.Messages
+	if len(messages) != 2 || messages[0].Role != RoleSystem || messages[1].Content != testChatQuestion {
+		t.Fatalf("请求消息 = %+v", messages)
+	}
+	if !strings.Contains(messages[0].Content, testChatDocument) || !strings.Contains(messages[0].Content, "《退货政策.txt》") {
+		t.Errorf("系统提示词中缺少检索到的文档: %s", messages[0].Content)
+	}
+}
+
+func TestStreamMessage(t *testing.T) {
+	agent, document := createChatAgent(t, "chat-stream")
+	conversation := startTestConversation(t, agent)
+
+	var deltas []string
+	reply, err := StreamMessage(context.Background(), agent, conversation, testChatQuestion, func(delta string) {
+		deltas = append(deltas, delta)
+	})
+	if err != nil {
+		t.Fatalf("流式回复失败: %v", err)
+	}
+
+	want := "您好，关于“" + testChatQuestion + "”，这是模拟模型的回复。"
+	if strings.Join(deltas, "") != want || reply.Message.Content != want {
+		t.Errorf("推送内容 = %q，保存的回复 = %q，期望 %q", strings.Join(deltas, ""), reply.Message.Content, want)
+	}
+	// 模拟模型逐字符推送
+	if len(deltas) != utf8.RuneCountInString(want) {
+		t.Errorf("推送了 %d 段，期望 %d 段", len(deltas), utf8.RuneCountInString(want))
+	}
+	if reply.Usage.CompletionTokens != utf8.RuneCountInString(want) {
+		t.Errorf("用量 = %+v", reply.Usage)
+	}
+	if len(reply.Documents) != 1 || reply.Documents[0].DocumentID != document.ID {
+		t.Errorf("文档引用 = %+v", reply.Documents)
+	}
+}
+
+func TestStreamMessageCanceled(t *testing.T) {
+	agent, _ := createChatAgent(t, "chat-cancel")
+	conversation := startTestConversation(t, agent)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var received strings.Builder
+	_, err := StreamMessage(ctx, agent, conversation, testChatQuestion, func(delta string) {
+		received.WriteString(delta)
+		// 收到前几个字后访客断开连接
+		if utf8.RuneCountInString(received.String()) == 3 {
+			cancel()
+		}
+	})
+	if err == nil {
+		t.Fatal("取消后应返回错误")
+	}
+
+	// 已生成的部分仍保存到会话中
+	messages, _ := GetMessages(conversation.ID)
+	last := messages[len(messages)-1]
+	if last.Role != RoleAssistant || last.Content == "" || !strings.HasPrefix(received.String(), last.Content) {
+		t.Errorf("保存的部分回复 = %q，已推送 %q", last.Content, received.String())
+	}
+}
+
+func TestSendMessageFAQShortCircuit(t *testing.T) {
+	agent, _ := createChatAgent(t, "chat-faq")
+	conversation := startTestConversation(t, agent)
+	var faq models.FAQ
+	config.DB.Where("agent_id = ?", agent.ID).First(&faq)
+
+	var deltas []string
+	reply, err := StreamMessage(context.Background(), agent, conversation, "如何修改收货地址", func(delta string) {
+		deltas = append(deltas, delta)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 直接使用常见问答的答案，不调用大模型
+	if reply.Message.Content != faq.Answer || len(deltas) != 1 || deltas[0] != faq.Answer {
+		t.Errorf("回复 = %q，推送 = %v", reply.Message.Content, deltas)
+	}
+	if reply.Usage.TotalTokens != 0 {
+		t.Errorf("直接命中常见问答不应产生用量: %+v", reply.Usage)
+	}
+	if reply.Message.FAQID != faq.ID || len(reply.FAQs) != 1 || reply.FAQs[0].FAQID != faq.ID || reply.FAQs[0].Score != 1 {
+		t.Errorf("常见问答引用 = %+v，消息FAQID = %d", reply.FAQs, reply.Message.FAQID)
+	}
+	if len(reply.Documents) != 0 {
+		t.Errorf("不应引用文档: %+v", reply.Documents)
+	}
+
+	var reloaded models.FAQ
+	config.DB.First(&reloaded, faq.ID)
+	if reloaded.HitCount != 1 || reloaded.LastHitAt == nil {
+		t.Errorf("命中次数 = %d", reloaded.HitCount)
+	}
+}
+
+func TestSendMessageNoAnswer(t *testing.T) {
+	agent := &models.Agent{AppID: "chat-empty", Name: "客服", WelcomeMsg: "您好", NoAnswerMsg: "请联系人工客服"}
+	if err := config.DB.Create(agent).Error; err != nil {
+		t.Fatal(err)
+	}
+	conversation := startTestConversation(t, agent)
+
+	reply, err := SendMessage(context.Background(), agent, conversation, testChatQuestion)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply.Message.Content != agent.NoAnswerMsg || reply.Usage.TotalTokens != 0 {
+		t.Errorf("回复 = %q，用量 = %+v", reply.Message.Content, reply.Usage)
+	}
+	if len(reply.Documents) != 0 || len(reply.FAQs) != 0 {
+		t.Errorf("不应有引用: %+v %+v", reply.Documents, reply.FAQs)
+	}
+}
@@ -0,0 +1,85 @@
+package services
+
+import "unicode"
+
+// TextChunk 文本切片，Start/End为切片在原文中的字符位置（按rune计，左闭右开）
+type TextChunk struct {
+	Content string
+	Start   int
+	End     int
+}
+
+// 切片断点的优先级，数值越小越优先
+var chunkBreakPriority = map[rune]int{
+	'\n': 0,
+	'。':  1, '！': 1, '？': 1, '!': 1, '?': 1, '.': 1,
+	'；': 2, ';': 2,
+	'，': 3, '、': 3, ',': 3, ' ': 3, '\t': 3,
+}
+
+// SplitText 将文本切分为相邻部分重叠的切片，尽量在段落或句子边界处断开
+func SplitText(text string, size, overlap int) []TextChunk {
+	if size <= 0 {
+		size = 500
+	}
+	if overlap < 0 || overlap >= size {
+		overlap = size / 5
+	}
+
+	runes := []rune(text)
+	var chunks []TextChunk
+	for start := 0; start < len(runes); {
+		end := start + size
+		if end >= len(runes) {
+			end = len(runes)
+		} else {
+			end = chunkBreak(runes, start+size/2, end)
+		}
+
+		// 去除首尾空白，同时修正偏移量
+		from, to := start, end
+		for from < to && unicode.IsSpace(runes[from]) {
+			from++
+		}
+		for to > from && unicode.IsSpace(runes[to-1]) {
+			to--
+		}
+		if from < to {
+			chunks = append(chunks, TextChunk{
+				Content: string(runes[from:to]),
+				Start:   from,
+				End:     to,
+			})
+		}
+
+		if end >= len(runes) {
+			break
+		}
+		next := end - overlap
+		if next <= start {
+			next = end
+		}
+		start = next
+	}
+	return chunks
+}
+
+// chunkBreak 在[min, max]范围内从后向前寻找优先级最高的断点，返回断点字符之后的位置
+func chunkBreak(runes []rune, min, max int) int {
+	best, bestPriority := max, len(chunkBreakPriority)
+	for i := max - 1; i >= min; i-- {
+		priority, ok := chunkBreakPriority[runes[i]]
+		if !ok || priority >= bestPriority {
+			continue
+		}
+		// 英文句点后需为空白，避免在小数或缩写处断开
+		if runes[i] == '.' && (i+1 >= len(runes) || !unicode.IsSpace(runes[i+1])) {
+			continue
+		}
+		best, bestPriority = i+1, priority
+		if priority == 0 {
+			break
+		}
+	}
+	return best
+}
@@ -0,0 +1,54 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"ai-assistant-backend/config"
+	"ai-assistant-backend/utils"
+)
+
+// EmbedTexts 使用默认服务商的向量模型将文本分批转换为向量，返回向量与所用模型
+func EmbedTexts(ctx context.Context, texts []string) ([][]float32, string, error) {
+	provider, model, err := utils.ResolveEmbeddingModel()
+	if err != nil {
+		return nil, "", err
+	}
+
+	batchSize := config.GlobalConfig.Knowledge.EmbedBatchSize
+	if batchSize <= 0 {
+		batchSize = 32
+	}
+
+	vectors := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += batchSize {
+		end := start + batchSize
+		if end > len(texts) {
+			end = len(texts)
+		}
+
+		requestCtx, cancel := context.WithTimeout(ctx, utils.LLMTimeout())
+		resp, err := provider.Embeddings(requestCtx, utils.EmbeddingRequest{
+			Model: model,
+			Input: texts[start:end],
+		})
+		cancel()
+		if err != nil {
+			return nil, "", fmt.Errorf("文本向量化失败: %v", err)
+		}
+		if len(resp.Vectors) != end-start {
+			return nil, "", fmt.Errorf("文本向量化失败: 返回向量数量不匹配")
+		}
+		vectors = append(vectors, resp.Vectors...)
+	}
+	return vectors, model, nil
+}
+
+// EmbedText 将单条文本转换为向量
+func EmbedText(ctx context.Context, text string) ([]float32, error) {
+	vectors, _, err := EmbedTexts(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return vectors[0], nil
+}
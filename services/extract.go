@@ -0,0 +1,179 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+// ErrNoTextExtracted 文档中没有可提取的文本（如扫描件）
+var ErrNoTextExtracted = errors.New("未能从文档中提取到文本")
+
+// DocumentFormat 根据文档格式字段或文件路径确定格式，返回不带点的小写扩展名
+func DocumentFormat(format, path string) string {
+	format = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(format), "."))
+	if format == "" {
+		format = strings.ToLower(strings.TrimPrefix(filepath.Ext(path), "."))
+	}
+	return format
+}
+
+// ExtractText 按格式提取文档中的纯文本，支持 txt、pdf、docx、doc
+func ExtractText(format string, data []byte) (string, error) {
+	var text string
+	var err error
+
+	switch format {
+	case "txt":
+		text, err = extractPlainText(data)
+	case "docx":
+		text, err = extractDocxText(data)
+	case "doc":
+		text, err = extractDocText(data)
+	case "pdf":
+		text, err = extractPDFText(data)
+	default:
+		return "", fmt.Errorf("不支持的文档格式: %s", format)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	text = cleanExtractedText(text)
+	if text == "" {
+		return "", ErrNoTextExtracted
+	}
+	return text, nil
+}
+
+// extractPlainText 解析纯文本，支持UTF-8、带BOM的UTF-16以及GB18030（兼容GBK）编码
+func extractPlainText(data []byte) (string, error) {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xEF, 0xBB, 0xBF}):
+		return string(data[3:]), nil
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}):
+		return decodeUTF16(data[2:], binary.LittleEndian), nil
+	case bytes.HasPrefix(data, []byte{0xFE, 0xFF}):
+		return decodeUTF16(data[2:], binary.BigEndian), nil
+	case utf8.Valid(data):
+		return string(data), nil
+	}
+
+	decoded, err := simplifiedchinese.GB18030.NewDecoder().Bytes(data)
+	if err != nil {
+		return "", fmt.Errorf("无法识别文本编码: %v", err)
+	}
+	return string(decoded), nil
+}
+
+// extractDocxText 读取 word/document.xml 中的文本，段落与换行转换为换行符
+func extractDocxText(data []byte) (string, error) {
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", fmt.Errorf("解析docx失败: %v", err)
+	}
+
+	var document *zip.File
+	for _, file := range reader.File {
+		if file.Name == "word/document.xml" {
+			document = file
+			break
+		}
+	}
+	if document == nil {
+		return "", errors.New("解析docx失败: 缺少word/document.xml")
+	}
+
+	rc, err := document.Open()
+	if err != nil {
+		return "", fmt.Errorf("解析docx失败: %v", err)
+	}
+	defer rc.Close()
+
+	var b strings.Builder
+	decoder := xml.NewDecoder(rc)
+	inText := false
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("解析docx失败: %v", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "t":
+				inText = true
+			case "tab":
+				b.WriteByte('\t')
+			case "br", "cr":
+				b.WriteByte('\n')
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				b.WriteByte('\n')
+			case "tc":
+				b.WriteByte('\t')
+			}
+		case xml.CharData:
+			if inText {
+				b.Write(t)
+			}
+		}
+	}
+	return b.String(), nil
+}
+
+// cleanExtractedText 去除控制字符，合并多余的空白行
+func cleanExtractedText(text string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+
+	var b strings.Builder
+	blankLines := 0
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimRightFunc(strings.Map(func(r rune) rune {
+			if r == '\t' || !unicode.IsControl(r) && r != utf8.RuneError {
+				return r
+			}
+			return -1
+		}, line), unicode.IsSpace)
+
+		if strings.TrimSpace(line) == "" {
+			blankLines++
+			if blankLines > 1 {
+				continue
+			}
+		} else {
+			blankLines = 0
+		}
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+	return strings.TrimSpace(b.String())
+}
+
+func decodeUTF16(data []byte, order binary.ByteOrder) string {
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		units[i] = order.Uint16(data[i*2:])
+	}
+	return string(utf16.Decode(units))
+}
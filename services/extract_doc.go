@@ -0,0 +1,330 @@
+package services
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+// 复合文档（OLE/CFB）中的特殊扇区号
+const (
+	cfbEndOfChain = 0xFFFFFFFE
+	cfbFreeSector = 0xFFFFFFFF
+)
+
+var errInvalidDoc = errors.New("解析doc失败: 文件格式无效")
+
+// compoundFile Word 97-2003 使用的复合文档容器
+type compoundFile struct {
+	data           []byte
+	sectorSize     int
+	miniSectorSize int
+	miniCutoff     uint32
+	fat            []uint32
+	miniFAT        []uint32
+	miniStream     []byte
+	streams        map[string]cfbEntry
+}
+
+type cfbEntry struct {
+	start uint32
+	size  uint32
+}
+
+// extractDocText 通过 WordDocument 流中的片段表（piece table）还原正文
+func extractDocText(data []byte) (string, error) {
+	cf, err := openCompoundFile(data)
+	if err != nil {
+		return "", err
+	}
+
+	wordDocument, err := cf.stream("WordDocument")
+	if err != nil {
+		return "", err
+	}
+	if len(wordDocument) < 0x01AA || binary.LittleEndian.Uint16(wordDocument) != 0xA5EC {
+		return "", errInvalidDoc
+	}
+
+	// FIB中的fWhichTblStm决定片段表位于0Table还是1Table
+	tableName := "0Table"
+	if binary.LittleEndian.Uint16(wordDocument[0x0A:])&0x0200 != 0 {
+		tableName = "1Table"
+	}
+	table, err := cf.stream(tableName)
+	if err != nil {
+		return "", err
+	}
+
+	fcClx := binary.LittleEndian.Uint32(wordDocument[0x01A2:])
+	lcbClx := binary.LittleEndian.Uint32(wordDocument[0x01A6:])
+	if uint64(fcClx)+uint64(lcbClx) > uint64(len(table)) {
+		return "", errInvalidDoc
+	}
+	clx := table[fcClx : fcClx+lcbClx]
+
+	// 跳过Prc结构，定位到Pcdt
+	pos := 0
+	for pos < len(clx) && clx[pos] == 0x01 {
+		if pos+3 > len(clx) {
+			return "", errInvalidDoc
+		}
+		pos += 3 + int(binary.LittleEndian.Uint16(clx[pos+1:]))
+	}
+	if pos+5 > len(clx) || clx[pos] != 0x02 {
+		return "", errInvalidDoc
+	}
+	plcPcd := clx[pos+5:]
+	if lcb := int(binary.LittleEndian.Uint32(clx[pos+1:])); lcb <= len(plcPcd) {
+		plcPcd = plcPcd[:lcb]
+	}
+
+	// PlcPcd由n+1个字符位置与n个8字节的片段描述组成
+	pieces := (len(plcPcd) - 4) / 12
+	if pieces <= 0 {
+		return "", errInvalidDoc
+	}
+
+	decoder := charmap.Windows1252.NewDecoder()
+	var b strings.Builder
+	for i := 0; i < pieces; i++ {
+		cpStart := binary.LittleEndian.Uint32(plcPcd[i*4:])
+		cpEnd := binary.LittleEndian.Uint32(plcPcd[(i+1)*4:])
+		if cpEnd <= cpStart {
+			continue
+		}
+		count := int(cpEnd - cpStart)
+
+		pcd := plcPcd[(pieces+1)*4+i*8:]
+		fc := binary.LittleEndian.Uint32(pcd[2:])
+		compressed := fc&0x40000000 != 0
+
+		if compressed {
+			offset := int((fc &^ 0x40000000) / 2)
+			if offset+count > len(wordDocument) {
+				return "", errInvalidDoc
+			}
+			decoded, err := decoder.Bytes(wordDocument[offset : offset+count])
+			if err != nil {
+				return "", fmt.Errorf("解析doc失败: %v", err)
+			}
+			b.Write(decoded)
+		} else {
+			offset := int(fc)
+			if offset+count*2 > len(wordDocument) {
+				return "", errInvalidDoc
+			}
+			units := make([]uint16, count)
+			for j := range units {
+				units[j] = binary.LittleEndian.Uint16(wordDocument[offset+j*2:])
+			}
+			b.WriteString(string(utf16.Decode(units)))
+		}
+	}
+
+	return cleanDocText(b.String()), nil
+}
+
+// cleanDocText 去除域代码，并将Word的特殊字符转换为普通空白
+func cleanDocText(text string) string {
+	var b strings.Builder
+	fieldDepth := 0
+	fieldResult := false
+	for _, r := range text {
+		switch r {
+		case 0x13: // 域开始
+			fieldDepth++
+			fieldResult = false
+			continue
+		case 0x14: // 域分隔符，之后为域结果
+			fieldResult = true
+			continue
+		case 0x15: // 域结束
+			if fieldDepth > 0 {
+				fieldDepth--
+			}
+			fieldResult = false
+			continue
+		}
+		if fieldDepth > 0 && !fieldResult {
+			continue
+		}
+
+		switch r {
+		case '\r', 0x0B, 0x0C:
+			b.WriteByte('\n')
+		case 0x07: // 单元格结束
+			b.WriteByte('\t')
+		case 0x1E, 0x1F:
+			// 不间断连字符、可选连字符
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// openCompoundFile 解析复合文档头、扇区分配表与目录
+func openCompoundFile(data []byte) (*compoundFile, error) {
+	signature := []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}
+	if len(data) < 512 || string(data[:8]) != string(signature) {
+		return nil, errInvalidDoc
+	}
+
+	sectorShift := binary.LittleEndian.Uint16(data[0x1E:])
+	miniSectorShift := binary.LittleEndian.Uint16(data[0x20:])
+	if sectorShift < 7 || sectorShift > 16 || miniSectorShift > sectorShift {
+		return nil, errInvalidDoc
+	}
+
+	cf := &compoundFile{
+		data:           data,
+		sectorSize:     1 << sectorShift,
+		miniSectorSize: 1 << miniSectorShift,
+		miniCutoff:     binary.LittleEndian.Uint32(data[0x38:]),
+		streams:        map[string]cfbEntry{},
+	}
+
+	// 收集FAT扇区：头部的109项DIFAT以及后续DIFAT扇区链
+	var fatSectors []uint32
+	for i := 0; i < 109; i++ {
+		sector := binary.LittleEndian.Uint32(data[0x4C+i*4:])
+		if sector != cfbFreeSector {
+			fatSectors = append(fatSectors, sector)
+		}
+	}
+	difatSector := binary.LittleEndian.Uint32(data[0x44:])
+	for visited := 0; difatSector != cfbEndOfChain && difatSector != cfbFreeSector; visited++ {
+		sector, err := cf.sector(difatSector)
+		if err != nil || visited > len(data)/cf.sectorSize {
+			return nil, errInvalidDoc
+		}
+		entries := cf.sectorSize/4 - 1
+		for i := 0; i < entries; i++ {
+			if value := binary.LittleEndian.Uint32(sector[i*4:]); value != cfbFreeSector {
+				fatSectors = append(fatSectors, value)
+			}
+		}
+		difatSector = binary.LittleEndian.Uint32(sector[entries*4:])
+	}
+	for _, index := range fatSectors {
+		sector, err := cf.sector(index)
+		if err != nil {
+			return nil, err
+		}
+		for i := 0; i < cf.sectorSize; i += 4 {
+			cf.fat = append(cf.fat, binary.LittleEndian.Uint32(sector[i:]))
+		}
+	}
+
+	// 目录
+	directory, err := cf.chain(binary.LittleEndian.Uint32(data[0x30:]), 0)
+	if err != nil {
+		return nil, err
+	}
+	var root cfbEntry
+	for offset := 0; offset+128 <= len(directory); offset += 128 {
+		entry := directory[offset : offset+128]
+		nameLength := int(binary.LittleEndian.Uint16(entry[64:]))
+		if nameLength < 2 || nameLength > 64 {
+			continue
+		}
+		units := make([]uint16, nameLength/2-1)
+		for i := range units {
+			units[i] = binary.LittleEndian.Uint16(entry[i*2:])
+		}
+		name := string(utf16.Decode(units))
+		item := cfbEntry{
+			start: binary.LittleEndian.Uint32(entry[116:]),
+			size:  binary.LittleEndian.Uint32(entry[120:]),
+		}
+		switch entry[66] {
+		case 5: // 根目录，其数据为迷你流
+			root = item
+		case 2: // 流
+			cf.streams[name] = item
+		}
+	}
+
+	// 迷你扇区分配表与迷你流，小于阈值的流存放在迷你流中
+	if first := binary.LittleEndian.Uint32(data[0x3C:]); first != cfbEndOfChain && first != cfbFreeSector {
+		miniFAT, err := cf.chain(first, 0)
+		if err != nil {
+			return nil, err
+		}
+		for i := 0; i+4 <= len(miniFAT); i += 4 {
+			cf.miniFAT = append(cf.miniFAT, binary.LittleEndian.Uint32(miniFAT[i:]))
+		}
+		if cf.miniStream, err = cf.chain(root.start, int(root.size)); err != nil {
+			return nil, err
+		}
+	}
+
+	return cf, nil
+}
+
+// stream 读取指定名称的流
+func (cf *compoundFile) stream(name string) ([]byte, error) {
+	entry, exists := cf.streams[name]
+	if !exists {
+		return nil, fmt.Errorf("解析doc失败: 缺少%s流", name)
+	}
+
+	if entry.size < cf.miniCutoff {
+		return cf.miniChain(entry.start, int(entry.size))
+	}
+	return cf.chain(entry.start, int(entry.size))
+}
+
+// sector 读取普通扇区，文件头占用第一个扇区的位置
+func (cf *compoundFile) sector(index uint32) ([]byte, error) {
+	offset := (int(index) + 1) * cf.sectorSize
+	if offset < 0 || offset+cf.sectorSize > len(cf.data) {
+		return nil, errInvalidDoc
+	}
+	return cf.data[offset : offset+cf.sectorSize], nil
+}
+
+// chain 按FAT读取扇区链，size为0时读取整条链
+func (cf *compoundFile) chain(start uint32, size int) ([]byte, error) {
+	var out []byte
+	for index, visited := start, 0; index != cfbEndOfChain; visited++ {
+		if int(index) >= len(cf.fat) || visited > len(cf.fat) {
+			return nil, errInvalidDoc
+		}
+		sector, err := cf.sector(index)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, sector...)
+		if size > 0 && len(out) >= size {
+			return out[:size], nil
+		}
+		index = cf.fat[index]
+	}
+	if size > len(out) {
+		return nil, errInvalidDoc
+	}
+	return out, nil
+}
+
+// miniChain 按迷你FAT读取迷你流中的扇区链
+func (cf *compoundFile) miniChain(start uint32, size int) ([]byte, error) {
+	var out []byte
+	for index, visited := start, 0; index != cfbEndOfChain && len(out) < size; visited++ {
+		offset := int(index) * cf.miniSectorSize
+		if int(index) >= len(cf.miniFAT) || visited > len(cf.miniFAT) || offset+cf.miniSectorSize > len(cf.miniStream) {
+			return nil, errInvalidDoc
+		}
+		out = append(out, cf.miniStream[offset:offset+cf.miniSectorSize]...)
+		index = cf.miniFAT[index]
+	}
+	if len(out) < size {
+		return nil, errInvalidDoc
+	}
+	return out[:size], nil
+}
@@ -0,0 +1,732 @@
+package services
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"ai-assistant-backend/config"
+)
+
+// defaultPDFDecodeLimit 未配置时PDF解压后流数据的总大小上限
+const defaultPDFDecodeLimit = 100 << 20
+
+// errPDFDecodeLimit 解压后的流数据超过上限，避免压缩炸弹耗尽内存
+var errPDFDecodeLimit = errors.New("解析pdf失败: 解压后的内容超过大小限制")
+
+// 该解析器只处理提取文本所需的最小子集：对象与对象流、FlateDecode、页面树、
+// 字体的ToUnicode映射以及文本绘制操作符。扫描件等不含文本层的PDF无法提取
+
+var (
+	pdfObjectHeader = regexp.MustCompile(`(\d+)\s+(\d+)\s+obj\b`)
+	pdfReference    = regexp.MustCompile(`^(\d+)\s+\d+\s+R`)
+	pdfReferences   = regexp.MustCompile(`(\d+)\s+\d+\s+R`)
+	pdfFontEntry    = regexp.MustCompile(`/([^\s/<>\[\]()]+)\s+(\d+)\s+\d+\s+R`)
+)
+
+// pdfObject PDF间接对象，dict为字典部分的原始文本，stream为解码后的流数据
+type pdfObject struct {
+	dict   string
+	stream []byte
+}
+
+type pdfDocument struct {
+	objects map[int]*pdfObject
+	cmaps   map[int]*pdfCMap
+	// remaining 剩余可解压的字节数
+	remaining int64
+}
+
+// extractPDFText 按页面顺序提取PDF中的文本
+func extractPDFText(data []byte) (string, error) {
+	if !bytes.HasPrefix(bytes.TrimSpace(data[:min(len(data), 1024)]), []byte("%PDF")) {
+		return "", errors.New("解析pdf失败: 文件格式无效")
+	}
+	if bytes.Contains(data, []byte("/Encrypt")) {
+		return "", errors.New("解析pdf失败: 不支持加密的PDF")
+	}
+
+	doc := &pdfDocument{objects: map[int]*pdfObject{}, cmaps: map[int]*pdfCMap{}, remaining: pdfDecodeLimit()}
+	if err := doc.parseObjects(data); err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	for _, page := range doc.pages() {
+		fonts := doc.pageFonts(page.resources)
+		for _, content := range pdfReferenceList(dictValue(page.object.dict, "Contents")) {
+			if object := doc.objects[content]; object != nil && object.stream != nil {
+				extractPDFContentText(&b, object.stream, fonts)
+			}
+		}
+		b.WriteString("\n\n")
+	}
+	return b.String(), nil
+}
+
+// pdfDecodeLimit 单个PDF解压后流数据的总大小上限
+func pdfDecodeLimit() int64 {
+	if config.GlobalConfig != nil && config.GlobalConfig.Knowledge.MaxDecodedSize > 0 {
+		return config.GlobalConfig.Knowledge.MaxDecodedSize
+	}
+	return defaultPDFDecodeLimit
+}
+
+// parseObjects 扫描文件中的全部间接对象，并展开对象流中的压缩对象，
+// 解压后的流数据超过上限时停止解析
+func (doc *pdfDocument) parseObjects(data []byte) error {
+	for _, match := range pdfObjectHeader.FindAllSubmatchIndex(data, -1) {
+		number, _ := strconv.Atoi(string(data[match[2]:match[3]]))
+		start := match[1]
+
+		end := bytes.Index(data[start:], []byte("endobj"))
+		if end < 0 {
+			continue
+		}
+		end += start
+
+		object := &pdfObject{}
+		streamStart := bytes.Index(data[start:end], []byte("stream"))
+		if streamStart < 0 {
+			object.dict = string(data[start:end])
+		} else {
+			streamStart += start
+			object.dict = string(data[start:streamStart])
+
+			dataStart := streamStart + len("stream")
+			if dataStart < len(data) && data[dataStart] == '\r' {
+				dataStart++
+			}
+			if dataStart < len(data) && data[dataStart] == '\n' {
+				dataStart++
+			}
+			streamEnd := bytes.Index(data[dataStart:], []byte("endstream"))
+			if streamEnd < 0 {
+				continue
+			}
+			stream, err := doc.decodeStream(object.dict, data[dataStart:dataStart+streamEnd])
+			if err != nil {
+				return err
+			}
+			object.stream = stream
+		}
+		doc.objects[number] = object
+	}
+
+	// 对象流（PDF 1.5+）中的对象没有独立的obj头
+	for _, object := range doc.objects {
+		if object.stream == nil || dictName(object.dict, "Type") != "ObjStm" {
+			continue
+		}
+		count, _ := strconv.Atoi(dictValue(object.dict, "N"))
+		first, _ := strconv.Atoi(dictValue(object.dict, "First"))
+		if first <= 0 || first > len(object.stream) {
+			continue
+		}
+
+		header := strings.Fields(string(object.stream[:first]))
+		for i := 0; i < count && i*2+1 < len(header); i++ {
+			number, _ := strconv.Atoi(header[i*2])
+			offset, _ := strconv.Atoi(header[i*2+1])
+			end := len(object.stream) - first
+			if i*2+3 < len(header) {
+				end, _ = strconv.Atoi(header[i*2+3])
+			}
+			if offset < 0 || first+end > len(object.stream) || offset > end {
+				continue
+			}
+			if _, exists := doc.objects[number]; !exists {
+				doc.objects[number] = &pdfObject{dict: string(object.stream[first+offset : first+end])}
+			}
+		}
+	}
+	return nil
+}
+
+// decodeStream 解码流数据，仅支持无过滤器与FlateDecode，其他过滤器（如图片）返回nil。
+// 解压后的数据计入文档的大小上限
+func (doc *pdfDocument) decodeStream(dict string, raw []byte) ([]byte, error) {
+	filter := dictValue(dict, "Filter")
+	switch {
+	case filter == "":
+		return raw, nil
+	case strings.Contains(filter, "FlateDecode") && !strings.Contains(filter, "DCTDecode"):
+		reader, err := zlib.NewReader(bytes.NewReader(raw))
+		if err != nil {
+			return nil, nil
+		}
+		defer reader.Close()
+		// 部分文件的压缩流尾部不完整，尽量保留已解压的内容
+		decoded, _ := io.ReadAll(io.LimitReader(reader, doc.remaining+1))
+		if int64(len(decoded)) > doc.remaining {
+			return nil, errPDFDecodeLimit
+		}
+		doc.remaining -= int64(len(decoded))
+		return decoded, nil
+	default:
+		return nil, nil
+	}
+}
+
+type pdfPage struct {
+	object    *pdfObject
+	resources string
+}
+
+// pages 从文档目录开始遍历页面树，资源字典可从父节点继承
+func (doc *pdfDocument) pages() []pdfPage {
+	var pages []pdfPage
+	visited := map[int]bool{}
+
+	var walk func(number int, resources string)
+	walk = func(number int, resources string) {
+		object := doc.objects[number]
+		if object == nil || visited[number] {
+			return
+		}
+		visited[number] = true
+
+		if value := dictValue(object.dict, "Resources"); value != "" {
+			resources = value
+		}
+		switch dictName(object.dict, "Type") {
+		case "Pages":
+			for _, kid := range pdfReferenceList(dictValue(object.dict, "Kids")) {
+				walk(kid, resources)
+			}
+		case "Page":
+			pages = append(pages, pdfPage{object: object, resources: resources})
+		}
+	}
+
+	for _, number := range doc.sortedObjectNumbers() {
+		if dictName(doc.objects[number].dict, "Type") == "Catalog" {
+			if root, ok := pdfReferenceNumber(dictValue(doc.objects[number].dict, "Pages")); ok {
+				walk(root, "")
+			}
+			break
+		}
+	}
+
+	// 页面树缺失或损坏时，按对象编号顺序处理所有页面
+	if len(pages) == 0 {
+		for _, number := range doc.sortedObjectNumbers() {
+			object := doc.objects[number]
+			if dictName(object.dict, "Type") == "Page" {
+				pages = append(pages, pdfPage{object: object, resources: dictValue(object.dict, "Resources")})
+			}
+		}
+	}
+	return pages
+}
+
+// pageFonts 返回页面资源中字体名称到ToUnicode映射的对应关系
+func (doc *pdfDocument) pageFonts(resources string) map[string]*pdfCMap {
+	fonts := map[string]*pdfCMap{}
+
+	resources = doc.resolve(resources)
+	fontDict := doc.resolve(dictValue(resources, "Font"))
+	for _, match := range pdfFontEntry.FindAllStringSubmatch(fontDict, -1) {
+		number, _ := strconv.Atoi(match[2])
+		font := doc.objects[number]
+		if font == nil {
+			continue
+		}
+		if cmapNumber, ok := pdfReferenceNumber(dictValue(font.dict, "ToUnicode")); ok {
+			fonts[match[1]] = doc.cmap(cmapNumber)
+		}
+	}
+	return fonts
+}
+
+// resolve 若value为间接引用则返回被引用对象的字典
+func (doc *pdfDocument) resolve(value string) string {
+	if number, ok := pdfReferenceNumber(value); ok {
+		if object := doc.objects[number]; object != nil {
+			return object.dict
+		}
+		return ""
+	}
+	return value
+}
+
+func (doc *pdfDocument) cmap(number int) *pdfCMap {
+	if cmap, exists := doc.cmaps[number]; exists {
+		return cmap
+	}
+	var cmap *pdfCMap
+	if object := doc.objects[number]; object != nil && object.stream != nil {
+		cmap = parsePDFCMap(object.stream)
+	}
+	doc.cmaps[number] = cmap
+	return cmap
+}
+
+func (doc *pdfDocument) sortedObjectNumbers() []int {
+	numbers := make([]int, 0, len(doc.objects))
+	for number := range doc.objects {
+		numbers = append(numbers, number)
+	}
+	sort.Ints(numbers)
+	return numbers
+}
+
+// pdfCMap ToUnicode映射表
+type pdfCMap struct {
+	codeLength int
+	mapping    map[uint32]string
+}
+
+// parsePDFCMap 解析ToUnicode映射中的codespacerange、bfchar与bfrange
+func parsePDFCMap(data []byte) *pdfCMap {
+	cmap := &pdfCMap{codeLength: 1, mapping: map[uint32]string{}}
+
+	lexer := &pdfLexer{data: data}
+	var operands []pdfToken
+	for {
+		token, ok := lexer.next()
+		if !ok {
+			break
+		}
+		if token.kind != pdfTokenOperator {
+			operands = append(operands, token)
+			continue
+		}
+
+		switch string(token.value) {
+		case "endcodespacerange":
+			if len(operands) > 0 && len(operands[0].value) > 0 {
+				cmap.codeLength = len(operands[0].value)
+			}
+		case "endbfchar":
+			for i := 0; i+1 < len(operands); i += 2 {
+				cmap.mapping[pdfCode(operands[i].value)] = decodeUTF16(operands[i+1].value, binary.BigEndian)
+			}
+		case "endbfrange":
+			for i := 0; i+2 < len(operands); i += 3 {
+				low, high := pdfCode(operands[i].value), pdfCode(operands[i+1].value)
+				if high < low || high-low > 0xFFFF {
+					continue
+				}
+				destination := operands[i+2]
+				// 以偏移量计数，避免high为0xFFFFFFFF时编码溢出导致死循环
+				for offset := 0; offset <= int(high-low); offset++ {
+					code := low + uint32(offset)
+					if destination.kind == pdfTokenArray {
+						if offset < len(destination.items) {
+							cmap.mapping[code] = decodeUTF16(destination.items[offset].value, binary.BigEndian)
+						}
+						continue
+					}
+					// 目标起始值的最后一个字节随编码递增
+					value := append([]byte(nil), destination.value...)
+					if len(value) > 0 {
+						last := int(value[len(value)-1]) + offset
+						value[len(value)-1] = byte(last)
+						if last > 0xFF && len(value) > 1 {
+							value[len(value)-2] += byte(last >> 8)
+						}
+					}
+					cmap.mapping[code] = decodeUTF16(value, binary.BigEndian)
+				}
+			}
+		}
+		operands = operands[:0]
+	}
+	return cmap
+}
+
+// decode 按映射表将字符串编码转换为文本
+func (cmap *pdfCMap) decode(data []byte) string {
+	var b strings.Builder
+	for i := 0; i+cmap.codeLength <= len(data); i += cmap.codeLength {
+		if text, exists := cmap.mapping[pdfCode(data[i:i+cmap.codeLength])]; exists {
+			b.WriteString(text)
+		}
+	}
+	return b.String()
+}
+
+// extractPDFContentText 执行页面内容流中的文本操作符
+func extractPDFContentText(b *strings.Builder, content []byte, fonts map[string]*pdfCMap) {
+	var cmap *pdfCMap
+	var operands []pdfToken
+	lastY := 0.0
+
+	show := func(data []byte) {
+		if cmap != nil {
+			b.WriteString(cmap.decode(data))
+		} else {
+			b.WriteString(decodePDFString(data))
+		}
+	}
+
+	lexer := &pdfLexer{data: content}
+	for {
+		token, ok := lexer.next()
+		if !ok {
+			return
+		}
+		if token.kind != pdfTokenOperator {
+			operands = append(operands, token)
+			continue
+		}
+
+		switch op := string(token.value); op {
+		case "Tf":
+			if len(operands) >= 2 && operands[0].kind == pdfTokenName {
+				cmap = fonts[string(operands[0].value)]
+			}
+		case "Tj":
+			if len(operands) >= 1 {
+				show(operands[0].value)
+			}
+		case "'", "\"":
+			b.WriteByte('\n')
+			if len(operands) >= 1 {
+				show(operands[len(operands)-1].value)
+			}
+		case "TJ":
+			if len(operands) >= 1 {
+				for _, item := range operands[0].items {
+					if item.kind == pdfTokenString {
+						show(item.value)
+					} else if item.kind == pdfTokenNumber && item.number < -200 {
+						b.WriteByte(' ')
+					}
+				}
+			}
+		case "Td", "TD":
+			if len(operands) >= 2 && operands[1].number != 0 {
+				b.WriteByte('\n')
+			}
+		case "Tm":
+			if len(operands) >= 6 {
+				if y := operands[5].number; y != lastY {
+					b.WriteByte('\n')
+					lastY = y
+				}
+			}
+		case "T*", "ET":
+			b.WriteByte('\n')
+		case "ID":
+			// 跳过内联图片数据
+			lexer.skipInlineImage()
+		}
+		operands = operands[:0]
+	}
+}
+
+// 词法单元类型
+const (
+	pdfTokenNumber = iota
+	pdfTokenString
+	pdfTokenName
+	pdfTokenArray
+	pdfTokenOperator
+	pdfTokenOther
+)
+
+type pdfToken struct {
+	kind   int
+	value  []byte
+	number float64
+	items  []pdfToken
+}
+
+// pdfLexer 内容流与CMap共用的词法分析器
+type pdfLexer struct {
+	data []byte
+	pos  int
+}
+
+func (l *pdfLexer) next() (pdfToken, bool) {
+	l.skipSpace()
+	if l.pos >= len(l.data) {
+		return pdfToken{}, false
+	}
+
+	c := l.data[l.pos]
+	switch {
+	case c == '(':
+		return pdfToken{kind: pdfTokenString, value: l.literalString()}, true
+	case c == '<' && l.pos+1 < len(l.data) && l.data[l.pos+1] == '<':
+		l.pos += 2
+		return pdfToken{kind: pdfTokenOther}, true
+	case c == '>' && l.pos+1 < len(l.data) && l.data[l.pos+1] == '>':
+		l.pos += 2
+		return pdfToken{kind: pdfTokenOther}, true
+	case c == '<':
+		return pdfToken{kind: pdfTokenString, value: l.hexString()}, true
+	case c == '[':
+		l.pos++
+		array := pdfToken{kind: pdfTokenArray}
+		for {
+			l.skipSpace()
+			if l.pos >= len(l.data) {
+				return array, true
+			}
+			if l.data[l.pos] == ']' {
+				l.pos++
+				return array, true
+			}
+			item, ok := l.next()
+			if !ok {
+				return array, true
+			}
+			array.items = append(array.items, item)
+		}
+	case c == ']' || c == ')' || c == '>' || c == '{' || c == '}':
+		l.pos++
+		return pdfToken{kind: pdfTokenOther}, true
+	case c == '/':
+		l.pos++
+		return pdfToken{kind: pdfTokenName, value: l.regular()}, true
+	}
+
+	word := l.regular()
+	if len(word) == 0 {
+		l.pos++
+		return pdfToken{kind: pdfTokenOther}, true
+	}
+	if number, err := strconv.ParseFloat(string(word), 64); err == nil {
+		return pdfToken{kind: pdfTokenNumber, value: word, number: number}, true
+	}
+	return pdfToken{kind: pdfTokenOperator, value: word}, true
+}
+
+func (l *pdfLexer) skipSpace() {
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		if c == '%' {
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+			continue
+		}
+		if !isPDFSpace(c) {
+			return
+		}
+		l.pos++
+	}
+}
+
+func (l *pdfLexer) regular() []byte {
+	start := l.pos
+	for l.pos < len(l.data) && !isPDFSpace(l.data[l.pos]) && !isPDFDelimiter(l.data[l.pos]) {
+		l.pos++
+	}
+	return l.data[start:l.pos]
+}
+
+func (l *pdfLexer) literalString() []byte {
+	l.pos++ // (
+	var out []byte
+	depth := 1
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return out
+			}
+		case '\\':
+			if l.pos >= len(l.data) {
+				return out
+			}
+			e := l.data[l.pos]
+			l.pos++
+			switch e {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r', '\n':
+				// 行尾续行
+				if e == '\r' && l.pos < len(l.data) && l.data[l.pos] == '\n' {
+					l.pos++
+				}
+				continue
+			default:
+				if e >= '0' && e <= '7' {
+					value := int(e - '0')
+					for i := 0; i < 2 && l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '7'; i++ {
+						value = value*8 + int(l.data[l.pos]-'0')
+						l.pos++
+					}
+					c = byte(value)
+				} else {
+					c = e
+				}
+			}
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+func (l *pdfLexer) hexString() []byte {
+	l.pos++ // <
+	var digits []byte
+	for l.pos < len(l.data) && l.data[l.pos] != '>' {
+		if c := l.data[l.pos]; isHexDigit(c) {
+			digits = append(digits, c)
+		}
+		l.pos++
+	}
+	l.pos++ // >
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+
+	out := make([]byte, len(digits)/2)
+	for i := range out {
+		value, _ := strconv.ParseUint(string(digits[i*2:i*2+2]), 16, 8)
+		out[i] = byte(value)
+	}
+	return out
+}
+
+// skipInlineImage 跳到内联图片结束标记EI之后
+func (l *pdfLexer) skipInlineImage() {
+	for l.pos+2 < len(l.data) {
+		if isPDFSpace(l.data[l.pos]) && l.data[l.pos+1] == 'E' && l.data[l.pos+2] == 'I' &&
+			(l.pos+3 >= len(l.data) || isPDFSpace(l.data[l.pos+3])) {
+			l.pos += 3
+			return
+		}
+		l.pos++
+	}
+	l.pos = len(l.data)
+}
+
+// dictValue 读取字典中指定键的原始值，支持嵌套字典、数组与间接引用
+func dictValue(dict, key string) string {
+	needle := "/" + key
+	for offset := 0; ; {
+		index := strings.Index(dict[offset:], needle)
+		if index < 0 {
+			return ""
+		}
+		index += offset + len(needle)
+		offset = index
+		// 避免/Font匹配到/FontDescriptor
+		if index < len(dict) && !isPDFSpace(dict[index]) && !isPDFDelimiter(dict[index]) {
+			continue
+		}
+
+		rest := strings.TrimLeft(dict[index:], " \t\r\n\f\x00")
+		switch {
+		case strings.HasPrefix(rest, "<<"):
+			return balanced(rest, "<<", ">>")
+		case strings.HasPrefix(rest, "["):
+			return balanced(rest, "[", "]")
+		case strings.HasPrefix(rest, "/"):
+			end := 1
+			for end < len(rest) && !isPDFSpace(rest[end]) && !isPDFDelimiter(rest[end]) {
+				end++
+			}
+			return rest[:end]
+		}
+		if match := pdfReference.FindString(rest); match != "" {
+			return match
+		}
+		end := 0
+		for end < len(rest) && !isPDFSpace(rest[end]) && !isPDFDelimiter(rest[end]) {
+			end++
+		}
+		return rest[:end]
+	}
+}
+
+// dictName 读取字典中名称类型的值，不含前导斜杠
+func dictName(dict, key string) string {
+	return strings.TrimPrefix(dictValue(dict, key), "/")
+}
+
+func balanced(text, open, close string) string {
+	depth := 0
+	for i := 0; i < len(text); {
+		switch {
+		case strings.HasPrefix(text[i:], open):
+			depth++
+			i += len(open)
+		case strings.HasPrefix(text[i:], close):
+			depth--
+			i += len(close)
+			if depth == 0 {
+				return text[:i]
+			}
+		default:
+			i++
+		}
+	}
+	return text
+}
+
+func pdfReferenceNumber(value string) (int, bool) {
+	match := pdfReference.FindStringSubmatch(strings.TrimSpace(value))
+	if match == nil {
+		return 0, false
+	}
+	number, err := strconv.Atoi(match[1])
+	return number, err == nil
+}
+
+func pdfReferenceList(value string) []int {
+	var numbers []int
+	for _, match := range pdfReferences.FindAllStringSubmatch(value, -1) {
+		if number, err := strconv.Atoi(match[1]); err == nil {
+			numbers = append(numbers, number)
+		}
+	}
+	return numbers
+}
+
+// decodePDFString 解码未指定ToUnicode的字符串：UTF-16BE（带BOM）或按Latin-1近似PDFDocEncoding
+func decodePDFString(data []byte) string {
+	if len(data) >= 2 && data[0] == 0xFE && data[1] == 0xFF {
+		return decodeUTF16(data[2:], binary.BigEndian)
+	}
+	runes := make([]rune, len(data))
+	for i, c := range data {
+		runes[i] = rune(c)
+	}
+	return string(runes)
+}
+
+func pdfCode(data []byte) uint32 {
+	var code uint32
+	for _, c := range data {
+		code = code<<8 | uint32(c)
+	}
+	return code
+}
+
+func isPDFSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == '\f' || c == 0
+}
+
+func isPDFDelimiter(c byte) bool {
+	return strings.IndexByte("()<>[]{}/%", c) >= 0
+}
+
+func isHexDigit(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'
+}
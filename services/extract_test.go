@@ -0,0 +1,383 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+	"unicode/utf16"
+
+	"ai-assistant-backend/config"
+)
+
+// buildTestPDF 生成测试用PDF：objects为对象编号1开始的对象内容，
+// 内容中的 {stream:...} 与 {flate:...} 分别生成未压缩与FlateDecode压缩的流
+func buildTestPDF(objects ...string) []byte {
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	for i, object := range objects {
+		fmt.Fprintf(&b, "%d 0 obj\n", i+1)
+		switch {
+		case strings.HasPrefix(object, "{stream:"):
+			data := strings.TrimSuffix(strings.TrimPrefix(object, "{stream:"), "}")
+			fmt.Fprintf(&b, "<< /Length %d >>\nstream\n%s\nendstream\n", len(data), data)
+		case strings.HasPrefix(object, "{flate:"):
+			data := deflate([]byte(strings.TrimSuffix(strings.TrimPrefix(object, "{flate:"), "}")))
+			fmt.Fprintf(&b, "<< /Length %d /Filter /FlateDecode >>\nstream\n", len(data))
+			b.Write(data)
+			b.WriteString("\nendstream\n")
+		default:
+			b.WriteString(object + "\n")
+		}
+		b.WriteString("endobj\n")
+	}
+	b.WriteString("trailer\n<< /Root 1 0 R >>\n%%EOF\n")
+	return b.Bytes()
+}
+
+func deflate(data []byte) []byte {
+	var b bytes.Buffer
+	writer := zlib.NewWriter(&b)
+	writer.Write(data)
+	writer.Close()
+	return b.Bytes()
+}
+
+// testPDF 两页PDF：第一页使用带ToUnicode映射的中文字体并压缩内容流，第二页使用标准字体
+func testPDF() []byte {
+	cmap := `/CIDInit /ProcSet findresource begin
+begincmap
+1 begincodespacerange <0000> <FFFF> endcodespacerange
+4 beginbfchar
+<0001> <4F60>
+<0002> <597D>
+<0003> <4E16>
+<0004> <754C>
+endbfchar
+1 beginbfrange
+<0005> <0007> <0041>
+endbfrange
+endcmap`
+	return buildTestPDF(
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R 4 0 R] /Count 2 /Resources << /Font << /F1 5 0 R /F2 6 0 R >> >> >>",
+		"<< /Type /Page /Parent 2 0 R /Contents 7 0 R >>",
+		"<< /Type /Page /Parent 2 0 R /Contents [8 0 R] >>",
+		"<< /Type /Font /Subtype /Type0 /BaseFont /SimSun /ToUnicode 9 0 R >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+		"{flate:BT /F1 12 Tf 72 720 Td <00010002> Tj 0 -14 Td <000300040005000600070008> Tj ET}",
+		"{stream:BT /F2 12 Tf 72 720 Td (Hello \\(PDF\\)) Tj 0 -14 Td [(Second) -300 (line)] TJ ET}",
+		"{flate:"+cmap+"}",
+	)
+}
+
+// testDocx 生成只包含正文的docx
+func testDocx(t *testing.T, body string) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	writer := zip.NewWriter(&b)
+	file, err := writer.Create("word/document.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Fprintf(file, `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>%s</w:body></w:document>`, body)
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+// testDocPiece doc片段表中的一个片段，compressed为true时按单字节（cp1252）存储
+type testDocPiece struct {
+	text       string
+	compressed bool
+}
+
+// testDoc 生成Word 97-2003文档：复合文档中包含WordDocument与0Table两个流，
+// 正文按片段表分段存放
+func testDoc(pieces ...testDocPiece) []byte {
+	const sectorSize = 512
+	const streamSectors = 8 // 每个流4096字节，不小于迷你流阈值
+
+	wordDocument := make([]byte, streamSectors*sectorSize)
+	binary.LittleEndian.PutUint16(wordDocument, 0xA5EC)
+
+	var table bytes.Buffer
+	var cps, pcds []byte
+	cp, offset := uint32(0), 0x0400
+	for _, piece := range pieces {
+		cps = binary.LittleEndian.AppendUint32(cps, cp)
+		pcd := make([]byte, 8)
+		if piece.compressed {
+			copy(wordDocument[offset:], piece.text)
+			binary.LittleEndian.PutUint32(pcd[2:], uint32(offset*2)|0x40000000)
+			cp += uint32(len(piece.text))
+			offset += len(piece.text)
+		} else {
+			units := utf16.Encode([]rune(piece.text))
+			binary.LittleEndian.PutUint32(pcd[2:], uint32(offset))
+			for _, unit := range units {
+				binary.LittleEndian.PutUint16(wordDocument[offset:], unit)
+				offset += 2
+			}
+			cp += uint32(len(units))
+		}
+		pcds = append(pcds, pcd...)
+	}
+	cps = binary.LittleEndian.AppendUint32(cps, cp)
+	plcPcd := append(cps, pcds...)
+	table.WriteByte(0x02)
+	binary.Write(&table, binary.LittleEndian, uint32(len(plcPcd)))
+	table.Write(plcPcd)
+	binary.LittleEndian.PutUint32(wordDocument[0x01A2:], 0)
+	binary.LittleEndian.PutUint32(wordDocument[0x01A6:], uint32(table.Len()))
+	tableStream := make([]byte, streamSectors*sectorSize)
+	copy(tableStream, table.Bytes())
+
+	// 文件头之后依次为：FAT扇区、目录扇区、WordDocument、0Table
+	header := make([]byte, sectorSize)
+	copy(header, []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1})
+	binary.LittleEndian.PutUint16(header[0x18:], 0x3E)
+	binary.LittleEndian.PutUint16(header[0x1A:], 3)
+	binary.LittleEndian.PutUint16(header[0x1C:], 0xFFFE)
+	binary.LittleEndian.PutUint16(header[0x1E:], 9)
+	binary.LittleEndian.PutUint16(header[0x20:], 6)
+	binary.LittleEndian.PutUint32(header[0x2C:], 1)
+	binary.LittleEndian.PutUint32(header[0x30:], 1)
+	binary.LittleEndian.PutUint32(header[0x38:], 4096)
+	binary.LittleEndian.PutUint32(header[0x3C:], cfbEndOfChain)
+	binary.LittleEndian.PutUint32(header[0x44:], cfbEndOfChain)
+	for i := 0; i < 109; i++ {
+		binary.LittleEndian.PutUint32(header[0x4C+i*4:], cfbFreeSector)
+	}
+	binary.LittleEndian.PutUint32(header[0x4C:], 0)
+
+	fat := make([]byte, sectorSize)
+	for i := 0; i < sectorSize/4; i++ {
+		binary.LittleEndian.PutUint32(fat[i*4:], cfbFreeSector)
+	}
+	binary.LittleEndian.PutUint32(fat[0:], 0xFFFFFFFD)
+	binary.LittleEndian.PutUint32(fat[4:], cfbEndOfChain)
+	for stream := 0; stream < 2; stream++ {
+		first := 2 + stream*streamSectors
+		for i := 0; i < streamSectors; i++ {
+			next := uint32(first + i + 1)
+			if i == streamSectors-1 {
+				next = cfbEndOfChain
+			}
+			binary.LittleEndian.PutUint32(fat[(first+i)*4:], next)
+		}
+	}
+
+	directory := make([]byte, sectorSize)
+	entry := func(index int, name string, kind byte, start, size uint32) {
+		e := directory[index*128 : (index+1)*128]
+		units := utf16.Encode([]rune(name))
+		for i, unit := range units {
+			binary.LittleEndian.PutUint16(e[i*2:], unit)
+		}
+		binary.LittleEndian.PutUint16(e[64:], uint16((len(units)+1)*2))
+		e[66] = kind
+		binary.LittleEndian.PutUint32(e[116:], start)
+		binary.LittleEndian.PutUint32(e[120:], size)
+	}
+	entry(0, "Root Entry", 5, cfbEndOfChain, 0)
+	entry(1, "WordDocument", 2, 2, uint32(len(wordDocument)))
+	entry(2, "0Table", 2, 2+streamSectors, uint32(len(tableStream)))
+
+	var b bytes.Buffer
+	for _, part := range [][]byte{header, fat, directory, wordDocument, tableStream} {
+		b.Write(part)
+	}
+	return b.Bytes()
+}
+
+func TestExtractText(t *testing.T) {
+	utf16LE := []byte{0xFF, 0xFE}
+	for _, unit := range utf16.Encode([]rune("你好")) {
+		utf16LE = binary.LittleEndian.AppendUint16(utf16LE, unit)
+	}
+
+	tests := []struct {
+		name   string
+		format string
+		data   []byte
+		want   string
+	}{
+		{"txt utf8", "txt", []byte("第一行\r\n\r\n\r\n\r\n第二行\t \n"), "第一行\n\n第二行"},
+		{"txt utf8 bom", "txt", []byte("\xEF\xBB\xBF你好"), "你好"},
+		{"txt utf16", "txt", utf16LE, "你好"},
+		{"txt gbk", "txt", []byte{0xC4, 0xE3, 0xBA, 0xC3}, "你好"},
+		{
+			"docx", "docx",
+			testDocx(t, `<w:p><w:r><w:t>你好，</w:t></w:r><w:r><w:t xml:space="preserve">世界</w:t></w:r></w:p>`+
+				`<w:p><w:r><w:t>第二段</w:t><w:tab/><w:t>制表</w:t><w:br/><w:t>换行</w:t></w:r></w:p>`),
+			"你好，世界\n第二段\t制表\n换行",
+		},
+		{"pdf", "pdf", testPDF(), "你好\n世界ABC\n\nHello (PDF)\nSecond line"},
+		{
+			"doc", "doc",
+			testDoc(
+				testDocPiece{text: "你好，世界\r"},
+				testDocPiece{text: "Caf\xe9 \x13 HYPERLINK \"https://example.com\" \x14link\x15\r", compressed: true},
+			),
+			"你好，世界\nCafé link",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExtractText(tt.format, tt.data)
+			if err != nil {
+				t.Fatalf("提取失败: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("提取结果 = %q，期望 %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractTextErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		data   []byte
+		want   error
+	}{
+		{"unsupported", "xls", []byte("x"), nil},
+		{"empty txt", "txt", []byte(" \n\t\n"), ErrNoTextExtracted},
+		{"not pdf", "pdf", []byte("hello"), nil},
+		{"encrypted pdf", "pdf", []byte("%PDF-1.4\n1 0 obj << /Encrypt 2 0 R >> endobj"), nil},
+		{"pdf without text", "pdf", buildTestPDF("<< /Type /Catalog /Pages 2 0 R >>", "<< /Type /Pages /Kids [] >>"), ErrNoTextExtracted},
+		{"docx without document", "docx", func() []byte {
+			var b bytes.Buffer
+			writer := zip.NewWriter(&b)
+			writer.Create("word/other.xml")
+			writer.Close()
+			return b.Bytes()
+		}(), nil},
+		{"docx broken xml", "docx", testDocx(t, "<w:p><w:t>未闭合"), nil},
+		{"not doc", "doc", bytes.Repeat([]byte{0}, 1024), errInvalidDoc},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ExtractText(tt.format, tt.data)
+			if err == nil {
+				t.Fatal("期望返回错误")
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("err = %v，期望 %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractPDFDecodeLimit(t *testing.T) {
+	previous := config.GlobalConfig.Knowledge.MaxDecodedSize
+	t.Cleanup(func() { config.GlobalConfig.Knowledge.MaxDecodedSize = previous })
+	config.GlobalConfig.Knowledge.MaxDecodedSize = 4096
+
+	text := "BT /F1 12 Tf (" + strings.Repeat("a", 1000) + ") Tj ET"
+	page := func(contents string) []byte {
+		return buildTestPDF(
+			"<< /Type /Catalog /Pages 2 0 R >>",
+			"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+			"<< /Type /Page /Parent 2 0 R /Contents "+contents+" >>",
+			"{flate:"+text+"}",
+			"{flate:"+text+"}",
+			"{flate:"+text+"}",
+			"{flate:"+text+"}",
+			"{flate:"+text+"}",
+		)
+	}
+
+	// 单个流解压后超过上限
+	bomb := buildTestPDF(
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /Contents 4 0 R >>",
+		"{flate:"+strings.Repeat(" ", 1<<20)+"}",
+	)
+	if len(bomb) > 4096 {
+		t.Fatalf("测试文件应远小于解压后的大小，实际 %d 字节", len(bomb))
+	}
+	if _, err := ExtractText("pdf", bomb); !errors.Is(err, errPDFDecodeLimit) {
+		t.Errorf("单个流超过上限 err = %v", err)
+	}
+
+	// 多个流合计超过上限
+	if _, err := ExtractText("pdf", page("4 0 R")); !errors.Is(err, errPDFDecodeLimit) {
+		t.Errorf("多个流合计超过上限 err = %v", err)
+	}
+
+	// 未超过上限时正常提取
+	config.GlobalConfig.Knowledge.MaxDecodedSize = 8192
+	got, err := ExtractText("pdf", page("4 0 R"))
+	if err != nil || got != strings.Repeat("a", 1000) {
+		t.Errorf("提取结果 = %d 个字符，err = %v", len(got), err)
+	}
+}
+
+// TestExtractTextMalformed 截断或损坏的文件返回错误或部分文本，不会panic
+func TestExtractTextMalformed(t *testing.T) {
+	fixtures := map[string][]byte{
+		"pdf":  testPDF(),
+		"docx": testDocx(t, `<w:p><w:r><w:t>你好</w:t></w:r></w:p>`),
+		"doc":  testDoc(testDocPiece{text: "你好\r"}, testDocPiece{text: "hello\r", compressed: true}),
+	}
+	random := rand.New(rand.NewSource(1))
+	for format, data := range fixtures {
+		extract := func(input []byte, desc string) {
+			defer func() {
+				if r := recover(); r != nil {
+					t.Fatalf("%s %s panic: %v", format, desc, r)
+				}
+			}()
+			ExtractText(format, input)
+		}
+
+		for length := 0; length < len(data); length += max(1, len(data)/200) {
+			extract(data[:length], fmt.Sprintf("截断到 %d 字节", length))
+		}
+		for i := 0; i < 500; i++ {
+			corrupted := append([]byte(nil), data...)
+			for j := 0; j < 1+random.Intn(8); j++ {
+				corrupted[random.Intn(len(corrupted))] = byte(random.Intn(256))
+			}
+			extract(corrupted, fmt.Sprintf("第 %d 次随机损坏", i))
+		}
+	}
+
+	for _, format := range []string{"docx", "doc"} {
+		truncated := fixtures[format][:len(fixtures[format])/2]
+		if _, err := ExtractText(format, truncated); err == nil {
+			t.Errorf("截断的%s应返回错误", format)
+		}
+	}
+}
+
+// TestParsePDFCMapBoundaries 编码范围到达上限时不会死循环
+func TestParsePDFCMapBoundaries(t *testing.T) {
+	cmap := parsePDFCMap([]byte("1 beginbfrange <FFFFFFFE> <FFFFFFFF> <0041> endbfrange 1 beginbfrange <0000> <FFFFFFFF> <0041> endbfrange"))
+	if cmap.mapping[0xFFFFFFFE] != "A" || cmap.mapping[0xFFFFFFFF] != "B" {
+		t.Errorf("映射 = %v", cmap.mapping)
+	}
+	if len(cmap.mapping) != 2 {
+		t.Errorf("超出范围的bfrange应被忽略，映射数 = %d", len(cmap.mapping))
+	}
+}
+
+func FuzzExtractText(f *testing.F) {
+	f.Add("pdf", testPDF())
+	f.Add("doc", testDoc(testDocPiece{text: "你好\r"}))
+	f.Add("txt", []byte("你好"))
+	f.Fuzz(func(t *testing.T, format string, data []byte) {
+		ExtractText(format, data)
+	})
+}
@@ -0,0 +1,195 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"ai-assistant-backend/config"
+	"ai-assistant-backend/models"
+	"ai-assistant-backend/utils"
+
+	"gorm.io/gorm"
+)
+
+// 常见问答匹配方式
+const (
+	FAQMatchExact     = "exact"
+	FAQMatchFuzzy     = "fuzzy"
+	FAQMatchEmbedding = "embedding"
+)
+
+// 未配置时使用的匹配阈值
+const (
+	defaultFAQMatchThreshold     = 0.85
+	defaultFAQEmbeddingThreshold = 0.92
+)
+
+// maxEditDistanceRunes 参与编辑距离计算的最大字符数，超出部分不计
+const maxEditDistanceRunes = 200
+
+// FAQMatch 直接命中的常见问答
+type FAQMatch struct {
+	FAQ    models.FAQ
+	Score  float64
+	Method string // exact, fuzzy, embedding
+}
+
+// MatchFAQ 在智能体知识范围内的常见问答中查找与问题足够相似的条目，命中时其答案可直接作为回复。
+// 依次尝试归一化后的完全匹配、文本相似度（字符二元组与编辑距离）以及可选的向量相似度
+func MatchFAQ(ctx context.Context, agent *models.Agent, question string) (*FAQMatch, error) {
+	normalized := normalizeQuestion(question)
+	if normalized == "" {
+		return nil, nil
+	}
+
+	var faqs []models.FAQ
+	if err := knowledgeScopeFAQs(agent).Find(&faqs).Error; err != nil {
+		return nil, fmt.Errorf("匹配常见问答失败: %v", err)
+	}
+	if len(faqs) == 0 {
+		return nil, nil
+	}
+
+	settings := config.GlobalConfig.Knowledge.FAQMatch
+	threshold := settings.Threshold
+	if threshold <= 0 {
+		threshold = defaultFAQMatchThreshold
+	}
+
+	var best *FAQMatch
+	for _, faq := range faqs {
+		candidate := normalizeQuestion(faq.Question)
+		if candidate == "" {
+			continue
+		}
+		if candidate == normalized {
+			return &FAQMatch{FAQ: faq, Score: 1, Method: FAQMatchExact}, nil
+		}
+
+		score := questionSimilarity(normalized, candidate)
+		if score >= threshold && (best == nil || score > best.Score) {
+			best = &FAQMatch{FAQ: faq, Score: score, Method: FAQMatchFuzzy}
+		}
+	}
+	if best != nil || !settings.UseEmbedding {
+		return best, nil
+	}
+
+	// 向量匹配失败不影响后续的检索回答
+	match, err := matchFAQByEmbedding(ctx, faqs, question, settings.EmbeddingThreshold)
+	if err != nil {
+		log.Printf("[faq] 智能体 %d 向量匹配常见问答失败: %v", agent.ID, err)
+		return nil, nil
+	}
+	return match, nil
+}
+
+// RecordFAQHit 记录常见问答被直接命中
+func RecordFAQHit(faqID uint) error {
+	return config.DB.Model(&models.FAQ{}).Where("id = ?", faqID).Updates(map[string]interface{}{
+		"hit_count":   gorm.Expr("hit_count + ?", 1),
+		"last_hit_at": time.Now(),
+	}).Error
+}
+
+// matchFAQByEmbedding 按问题向量的余弦相似度匹配。缺少向量或向量由其他模型生成的常见问答
+// 会先用当前向量模型重新生成并保存向量
+func matchFAQByEmbedding(ctx context.Context, faqs []models.FAQ, question string, threshold float64) (*FAQMatch, error) {
+	if threshold <= 0 {
+		threshold = defaultFAQEmbeddingThreshold
+	}
+	_, activeModel, err := utils.ResolveEmbeddingModel()
+	if err != nil {
+		return nil, err
+	}
+
+	var missing []int
+	var texts []string
+	for i, faq := range faqs {
+		if len(faq.Embedding) == 0 || faq.EmbeddingModel != activeModel {
+			missing = append(missing, i)
+			texts = append(texts, faq.Question)
+		}
+	}
+	if len(missing) > 0 {
+		vectors, model, err := EmbedTexts(ctx, texts)
+		if err != nil {
+			return nil, err
+		}
+		for j, i := range missing {
+			faqs[i].Embedding = vectors[j]
+			faqs[i].EmbeddingModel = model
+			config.DB.Model(&faqs[i]).UpdateColumns(map[string]interface{}{
+				"embedding":       faqs[i].Embedding,
+				"embedding_model": model,
+			})
+		}
+	}
+
+	vector, err := EmbedText(ctx, question)
+	if err != nil {
+		return nil, err
+	}
+
+	var best *FAQMatch
+	for _, faq := range faqs {
+		score, ok := CosineSimilarity(vector, faq.Embedding)
+		if ok && score >= threshold && (best == nil || score > best.Score) {
+			best = &FAQMatch{FAQ: faq, Score: score, Method: FAQMatchEmbedding}
+		}
+	}
+	return best, nil
+}
+
+// questionSimilarity 综合字符二元组与编辑距离计算两个归一化问题的相似度，取两者中的较大值
+func questionSimilarity(a, b string) float64 {
+	score := textSimilarity(a, b)
+	if edit := editSimilarity(a, b); edit > score {
+		score = edit
+	}
+	return score
+}
+
+// editSimilarity 基于编辑距离的相似度：1 - 距离/较长文本长度
+func editSimilarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	if len(ra) > maxEditDistanceRunes {
+		ra = ra[:maxEditDistanceRunes]
+	}
+	if len(rb) > maxEditDistanceRunes {
+		rb = rb[:maxEditDistanceRunes]
+	}
+
+	longest := len(ra)
+	if len(rb) > longest {
+		longest = len(rb)
+	}
+	if longest == 0 {
+		return 0
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(longest)
+}
+
+// levenshtein 计算两个字符序列的编辑距离
+func levenshtein(a, b []rune) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+
+	"ai-assistant-backend/config"
+	"ai-assistant-backend/models"
+)
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"", "abc", 3},
+		{"abc", "abc", 0},
+		{"kitten", "sitting", 3},
+		{"flaw", "lawn", 2},
+		{"如何重置密码", "如何重制密码", 1},
+		{"如何重置密码", "重置密码", 2},
+		{"退款", "退货", 1},
+		{"开发票", "发票开", 2},
+	}
+	for _, tt := range tests {
+		if got := levenshtein([]rune(tt.a), []rune(tt.b)); got != tt.want {
+			t.Errorf("levenshtein(%q, %q) = %d，期望 %d", tt.a, tt.b, got, tt.want)
+		}
+		if got := levenshtein([]rune(tt.b), []rune(tt.a)); got != tt.want {
+			t.Errorf("levenshtein(%q, %q) = %d，期望 %d", tt.b, tt.a, got, tt.want)
+		}
+	}
+}
+
+func TestEditSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{"", "", 0},
+		{"abc", "abc", 1},
+		{"退款", "退货", 0.5},
+		{"如何开票", "如何退票", 0.75},
+		{"如何重置密码", "如何重制密码", 1 - 1.0/6},
+		{"怎么修改收货地址", "怎么修改发货地址", 1 - 1.0/8},
+	}
+	for _, tt := range tests {
+		if got := editSimilarity(tt.a, tt.b); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("editSimilarity(%q, %q) = %f，期望 %f", tt.a, tt.b, got, tt.want)
+		}
+	}
+
+	// 超过长度上限的部分不参与计算
+	long := strings.Repeat("长", maxEditDistanceRunes)
+	if got := editSimilarity(long+"甲", long+"乙乙乙"); got != 1 {
+		t.Errorf("超出上限的字符应被忽略，相似度 = %f", got)
+	}
+}
+
+// TestQuestionSimilarityThreshold 短问题只差一个字时含义可能完全不同，不应达到默认阈值
+func TestQuestionSimilarityThreshold(t *testing.T) {
+	tests := []struct {
+		a, b  string
+		match bool
+	}{
+		{"退款", "退货", false},
+		{"如何开票", "如何退票", false},
+		{"怎么注册", "怎么注销", false},
+		{"如何重置密码", "如何重制密码", false},
+		{"如何修改收货地址", "如何修改收获地址", true},
+		{"会员积分怎么兑换礼品", "会员积分怎样兑换礼品", true},
+		{"会员积分怎么兑换礼品", "会员积分如何兑换礼品", false},
+		{"发票什么时候开", "什么时候开发票", false},
+	}
+	for _, tt := range tests {
+		score := questionSimilarity(normalizeQuestion(tt.a), normalizeQuestion(tt.b))
+		if got := score >= defaultFAQMatchThreshold; got != tt.match {
+			t.Errorf("questionSimilarity(%q, %q) = %f，是否命中 = %v，期望 %v", tt.a, tt.b, score, got, tt.match)
+		}
+	}
+}
+
+func TestMatchFAQShortQuestions(t *testing.T) {
+	agent := &models.Agent{AppID: "faq-short", Name: "faq-short"}
+	config.DB.Create(agent)
+	refund := &models.FAQ{AgentID: agent.ID, Question: "怎么退款？", Answer: "退款答案"}
+	config.DB.Create(refund)
+
+	for question, want := range map[string]uint{
+		"怎么退款":   refund.ID,
+		"怎么 退款！": refund.ID,
+		"怎么退货":   0,
+		"怎么":     0,
+	} {
+		match, err := MatchFAQ(context.Background(), agent, question)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := uint(0)
+		if match != nil {
+			got = match.FAQ.ID
+		}
+		if got != want {
+			t.Errorf("MatchFAQ(%q) = %d，期望 %d", question, got, want)
+		}
+	}
+}
+
+func TestMatchFAQByEmbeddingRegeneratesOnModelChange(t *testing.T) {
+	agent := &models.Agent{AppID: "faq-embedding", Name: "faq-embedding"}
+	config.DB.Create(agent)
+	// 旧模型生成的向量与当前模型的向量不可比较
+	stale := &models.FAQ{AgentID: agent.ID, Question: "营业时间是几点", Answer: "九点到六点", Embedding: models.Vector{1, 0}, EmbeddingModel: "old-embedding"}
+	config.DB.Create(stale)
+	vector, _ := EmbedText(context.Background(), "发货需要多久")
+	current := &models.FAQ{AgentID: agent.ID, Question: "发货需要多久", Answer: "两天内", Embedding: vector, EmbeddingModel: "mock-embedding"}
+	config.DB.Create(current)
+
+	var faqs []models.FAQ
+	config.DB.Where("agent_id = ?", agent.ID).Order("id").Find(&faqs)
+	match, err := matchFAQByEmbedding(context.Background(), faqs, "营业时间是几点", 0.9)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if match == nil || match.FAQ.ID != stale.ID || match.Method != FAQMatchEmbedding {
+		t.Fatalf("匹配结果 = %+v，期望重新生成向量后命中 %d", match, stale.ID)
+	}
+
+	var reloaded models.FAQ
+	config.DB.First(&reloaded, stale.ID)
+	if reloaded.EmbeddingModel != "mock-embedding" || len(reloaded.Embedding) == len(stale.Embedding) {
+		t.Errorf("向量未按当前模型重新生成: 模型 %s，维度 %d", reloaded.EmbeddingModel, len(reloaded.Embedding))
+	}
+
+	// 当前模型生成的向量不会重复生成
+	config.DB.Model(current).UpdateColumn("embedding", models.Vector{0, 1})
+	config.DB.Where("agent_id = ?", agent.ID).Order("id").Find(&faqs)
+	if _, err := matchFAQByEmbedding(context.Background(), faqs, "发货需要多久", 0.9); err != nil {
+		t.Fatal(err)
+	}
+	var unchanged models.FAQ
+	config.DB.First(&unchanged, current.ID)
+	if len(unchanged.Embedding) != 2 {
+		t.Errorf("当前模型的向量被重新生成，维度 %d", len(unchanged.Embedding))
+	}
+}
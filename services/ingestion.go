@@ -0,0 +1,236 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	"ai-assistant-backend/config"
+	"ai-assistant-backend/models"
+	"ai-assistant-backend/utils"
+)
+
+// errDocumentOutsideWorkspace 文档文件不在智能体所属工作空间内
+var errDocumentOutsideWorkspace = errors.New("文档文件不属于智能体所在的工作空间")
+
+// ingestionQueue 待解析的文档ID
+var ingestionQueue = make(chan uint, 100)
+
+// ingestionRequeueInterval 重新加入队列未能入队的pending文档的间隔
+const ingestionRequeueInterval = time.Minute
+
+// queuedDocuments 已在队列中等待的文档ID，避免重复入队
+var queuedDocuments sync.Map
+
+// StartIngestionWorkers 启动文档解析协程，并将服务重启前未完成的文档重新加入队列。
+// 队列已满时文档保持pending状态，由定时任务稍后重新加入队列
+func StartIngestionWorkers() {
+	workers := config.GlobalConfig.Knowledge.IngestWorkers
+	if workers <= 0 {
+		workers = 2
+	}
+
+	for i := 0; i < workers; i++ {
+		go func() {
+			for documentID := range ingestionQueue {
+				queuedDocuments.Delete(documentID)
+				if err := IngestDocument(documentID); err != nil {
+					log.Printf("[ingestion] 文档 %d 解析失败: %v", documentID, err)
+				}
+			}
+		}()
+	}
+
+	// 中断时处于processing的文档需要重新解析
+	config.DB.Model(&models.Document{}).
+		Where("status = ?", models.DocumentStatusProcessing).
+		Update("status", models.DocumentStatusPending)
+
+	reembedStaleDocuments()
+	pending := requeuePendingDocuments()
+	log.Printf("[ingestion] 文档解析任务已启动，并发数: %d，待解析文档: %d", workers, pending)
+
+	go func() {
+		ticker := time.NewTicker(ingestionRequeueInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			requeuePendingDocuments()
+		}
+	}()
+}
+
+// reembedStaleDocuments 更换向量模型后，将切片由其他模型生成的已解析文档改为pending重新解析。
+// 重新解析前这些切片不参与检索
+func reembedStaleDocuments() int {
+	_, model, err := utils.ResolveEmbeddingModel()
+	if err != nil {
+		log.Printf("[ingestion] 获取向量模型失败，跳过重新向量化: %v", err)
+		return 0
+	}
+
+	stale := config.DB.Model(&models.DocumentChunk{}).
+		Distinct("document_id").
+		Where("embedding_model <> ?", model)
+	result := config.DB.Model(&models.Document{}).
+		Where("status = ? AND id IN (?)", models.DocumentStatusReady, stale).
+		Update("status", models.DocumentStatusPending)
+	if result.Error != nil {
+		log.Printf("[ingestion] 查询需要重新向量化的文档失败: %v", result.Error)
+		return 0
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("[ingestion] 向量模型已更换为 %s，%d 个文档将重新向量化", model, result.RowsAffected)
+	}
+	return int(result.RowsAffected)
+}
+
+// requeuePendingDocuments 将不在队列中的pending文档加入队列，返回pending文档数
+func requeuePendingDocuments() int {
+	var documentIDs []uint
+	if err := config.DB.Model(&models.Document{}).
+		Where("status = ?", models.DocumentStatusPending).
+		Order("id").
+		Pluck("id", &documentIDs).Error; err != nil {
+		log.Printf("[ingestion] 查询待解析文档失败: %v", err)
+		return 0
+	}
+	for _, documentID := range documentIDs {
+		if !EnqueueIngestion(documentID) {
+			break
+		}
+	}
+	return len(documentIDs)
+}
+
+// EnqueueIngestion 将文档加入解析队列，文档状态需已为pending。
+// 队列已满时返回false，文档保持pending状态，由定时任务稍后重新加入队列
+func EnqueueIngestion(documentID uint) bool {
+	if _, queued := queuedDocuments.LoadOrStore(documentID, struct{}{}); queued {
+		return true
+	}
+	select {
+	case ingestionQueue <- documentID:
+		return true
+	default:
+		queuedDocuments.Delete(documentID)
+		log.Printf("[ingestion] 解析队列已满，文档 %d 稍后重新加入队列", documentID)
+		return false
+	}
+}
+
+// IngestDocument 下载文档、提取文本并切片向量化，处理结果记录在文档的解析状态中
+func IngestDocument(documentID uint) error {
+	// 只有pending状态的文档才会被处理，避免同一文档被重复解析
+	result := config.DB.Model(&models.Document{}).
+		Where("id = ? AND status = ?", documentID, models.DocumentStatusPending).
+		Updates(map[string]interface{}{
+			"status":       models.DocumentStatusProcessing,
+			"status_error": "",
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil
+	}
+
+	var document models.Document
+	if err := config.DB.First(&document, documentID).Error; err != nil {
+		return err
+	}
+
+	text, err := extractDocument(&document)
+	if err == nil {
+		err = indexDocument(&document, text)
+	}
+	if err != nil {
+		config.DB.Model(&document).Updates(map[string]interface{}{
+			"status":       models.DocumentStatusFailed,
+			"status_error": err.Error(),
+		})
+		return err
+	}
+
+	return config.DB.Model(&document).Updates(map[string]interface{}{
+		"status":       models.DocumentStatusReady,
+		"status_error": "",
+		"content":      text,
+		"char_count":   len([]rune(text)),
+		"ingested_at":  time.Now(),
+	}).Error
+}
+
+// extractDocument 从MinIO下载文档并提取文本
+func extractDocument(document *models.Document) (string, error) {
+	format := DocumentFormat(document.Format, document.Path)
+
+	// 只读取智能体所属工作空间内的文件
+	var agent models.Agent
+	if err := config.DB.Select("id", "workspace_id").First(&agent, document.AgentID).Error; err != nil {
+		return "", fmt.Errorf("智能体不存在: %v", err)
+	}
+	objectName := utils.ObjectNameFromPath(document.Path)
+	if !utils.ObjectInWorkspace(objectName, agent.WorkspaceID) {
+		return "", errDocumentOutsideWorkspace
+	}
+
+	reader, err := utils.NewMinIOUploader().DownloadFile(objectName)
+	if err != nil {
+		return "", err
+	}
+	if closer, ok := reader.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	limit := config.GlobalConfig.Upload.MaxFileSize
+	if limit <= 0 {
+		limit = 10 << 20
+	}
+	data, err := io.ReadAll(io.LimitReader(reader, limit+1))
+	if err != nil {
+		return "", fmt.Errorf("下载文档失败: %v", err)
+	}
+	if int64(len(data)) > limit {
+		return "", fmt.Errorf("文档大小超过限制")
+	}
+
+	return ExtractText(format, data)
+}
+
+// indexDocument 将文档文本切片、向量化并写入向量存储
+func indexDocument(document *models.Document, text string) error {
+	knowledge := config.GlobalConfig.Knowledge
+	pieces := SplitText(text, knowledge.ChunkSize, knowledge.ChunkOverlap)
+
+	contents := make([]string, len(pieces))
+	for i, piece := range pieces {
+		contents[i] = piece.Content
+	}
+	ctx := context.Background()
+	vectors, model, err := EmbedTexts(ctx, contents)
+	if err != nil {
+		return err
+	}
+
+	chunks := make([]models.DocumentChunk, len(pieces))
+	for i, piece := range pieces {
+		chunks[i] = models.DocumentChunk{
+			AgentID:        document.AgentID,
+			DocumentID:     document.ID,
+			ChunkIndex:     i,
+			Content:        piece.Content,
+			StartOffset:    piece.Start,
+			EndOffset:      piece.End,
+			Embedding:      vectors[i],
+			EmbeddingModel: model,
+		}
+	}
+	if err := GetVectorStore().ReplaceDocumentChunks(ctx, document.ID, chunks); err != nil {
+		return fmt.Errorf("保存文档切片失败: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"ai-assistant-backend/config"
+	"ai-assistant-backend/models"
+	"ai-assistant-backend/utils"
+
+	"gorm.io/gorm"
+)
+
+const (
+	invitationTokenPurpose   = "invitation"
+	defaultInviteExpireHours = 72
+)
+
+var (
+	// ErrInvitationInvalid 邀请令牌无效、已使用或已撤销
+	ErrInvitationInvalid = errors.New("邀请链接无效或已失效")
+	// ErrInvitationExpired 邀请已过期
+	ErrInvitationExpired = errors.New("邀请已过期")
+	// ErrInvitationEmail 接受邀请的账号邮箱与被邀请邮箱不一致
+	ErrInvitationEmail = errors.New("邀请邮箱与当前账号不一致")
+	// ErrAlreadyMember 被邀请用户已是工作空间成员
+	ErrAlreadyMember = errors.New("该用户已是工作空间成员")
+)
+
+// InviteExpireDuration 邀请有效时间
+func InviteExpireDuration() time.Duration {
+	hours := config.GlobalConfig.App.InviteExpireHours
+	if hours <= 0 {
+		hours = defaultInviteExpireHours
+	}
+	return time.Duration(hours) * time.Hour
+}
+
+// NormalizeEmail 邮箱统一为小写并去除首尾空白
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// InviteMember 创建邀请并发送邀请邮件。同一邮箱之前未接受的邀请会被撤销，
+// 邮件发送失败时删除本次邀请
+func InviteMember(ctx context.Context, workspace *models.Workspace, inviter *models.User, email string, role uint8) (*models.Invitation, error) {
+	email = NormalizeEmail(email)
+
+	var user models.User
+	if err := config.DB.Where("email = ?", email).First(&user).Error; err == nil {
+		if _, err := GetWorkspaceMember(workspace.ID, user.ID); err == nil {
+			return nil, ErrAlreadyMember
+		}
+	}
+
+	token, err := utils.GenerateSignedToken(invitationTokenPurpose)
+	if err != nil {
+		return nil, fmt.Errorf("生成邀请令牌失败: %v", err)
+	}
+
+	invitation := &models.Invitation{
+		WorkspaceID: workspace.ID,
+		Email:       email,
+		Role:        role,
+		TokenHash:   utils.HashToken(token),
+		Status:      models.InvitationPending,
+		InvitedBy:   inviter.ID,
+		ExpiresAt:   time.Now().Add(InviteExpireDuration()),
+	}
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.Invitation{}).
+			Where("workspace_id = ? AND email = ? AND status = ?", workspace.ID, email, models.InvitationPending).
+			Update("status", models.InvitationRevoked).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(invitation).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("创建邀请失败: %v", err)
+	}
+
+	if err := utils.SendMail(ctx, invitationMail(workspace, inviter, invitation, token)); err != nil {
+		config.DB.Delete(invitation)
+		return nil, fmt.Errorf("发送邀请邮件失败: %v", err)
+	}
+	return invitation, nil
+}
+
+// invitationMail 邀请邮件，链接指向前端的接受邀请页面
+func invitationMail(workspace *models.Workspace, inviter *models.User, invitation *models.Invitation, token string) utils.MailMessage {
+	link := strings.TrimRight(config.GlobalConfig.App.FrontendURL, "/") + "/invite?token=" + url.QueryEscape(token)
+	body := fmt.Sprintf("%s 邀请你以%s身份加入工作空间「%s」。\n\n打开以下链接接受邀请（%s前有效）：\n%s\n\n如果还没有账号，请使用 %s 注册后接受邀请。",
+		inviter.Username, utils.RoleNames[invitation.Role], workspace.Name,
+		invitation.ExpiresAt.Format("2006-01-02 15:04"), link, invitation.Email)
+
+	return utils.MailMessage{
+		To:      invitation.Email,
+		Subject: fmt.Sprintf("邀请你加入工作空间「%s」", workspace.Name),
+		Body:    body,
+	}
+}
+
+// FindInvitation 按令牌查找待接受的邀请
+func FindInvitation(tx *gorm.DB, token string) (*models.Invitation, error) {
+	if !utils.VerifySignedToken(invitationTokenPurpose, token) {
+		return nil, ErrInvitationInvalid
+	}
+
+	var invitation models.Invitation
+	err := tx.Where("token_hash = ? AND status = ?", utils.HashToken(token), models.InvitationPending).First(&invitation).Error
+	if err != nil {
+		return nil, ErrInvitationInvalid
+	}
+	if time.Now().After(invitation.ExpiresAt) {
+		return nil, ErrInvitationExpired
+	}
+	return &invitation, nil
+}
+
+// AcceptInvitation 接受邀请并加入工作空间，账号邮箱需与被邀请邮箱一致。
+// 已是成员时保留原有角色
+func AcceptInvitation(tx *gorm.DB, token string, user *models.User) (*models.Invitation, error) {
+	invitation, err := FindInvitation(tx, token)
+	if err != nil {
+		return nil, err
+	}
+	if NormalizeEmail(user.Email) != invitation.Email {
+		return nil, ErrInvitationEmail
+	}
+
+	// 条件更新保证令牌只能使用一次
+	now := time.Now()
+	result := tx.Model(&models.Invitation{}).
+		Where("id = ? AND status = ?", invitation.ID, models.InvitationPending).
+		Updates(map[string]interface{}{
+			"status":      models.InvitationAccepted,
+			"accepted_by": user.ID,
+			"accepted_at": now,
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrInvitationInvalid
+	}
+	invitation.Status = models.InvitationAccepted
+	invitation.AcceptedBy = user.ID
+	invitation.AcceptedAt = &now
+
+	var count int64
+	tx.Model(&models.WorkspaceMember{}).Where("workspace_id = ? AND user_id = ?", invitation.WorkspaceID, user.ID).Count(&count)
+	if count == 0 {
+		member := &models.WorkspaceMember{WorkspaceID: invitation.WorkspaceID, UserID: user.ID, Role: invitation.Role}
+		if err := tx.Create(member).Error; err != nil {
+			return nil, fmt.Errorf("添加工作空间成员失败: %v", err)
+		}
+	}
+	return invitation, nil
+}
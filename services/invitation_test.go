@@ -0,0 +1,132 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"ai-assistant-backend/config"
+	"ai-assistant-backend/models"
+	"ai-assistant-backend/utils"
+)
+
+// recordingMailer 只在测试中使用，保存已发送的邮件
+type recordingMailer struct {
+	mu   sync.Mutex
+	sent []utils.MailMessage
+}
+
+func (m *recordingMailer) Send(ctx context.Context, msg utils.MailMessage) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+// useRecordingMailer 在测试期间替换邮件发送器
+func useRecordingMailer(t *testing.T) *recordingMailer {
+	t.Helper()
+	mailer := &recordingMailer{}
+	previous := utils.GetMailer()
+	utils.SetMailer(mailer)
+	t.Cleanup(func() { utils.SetMailer(previous) })
+	return mailer
+}
+
+// inviteForTest 邀请邮箱加入新的工作空间，返回邀请与邮件中的令牌
+func inviteForTest(t *testing.T, name, email string) (*models.Invitation, string) {
+	t.Helper()
+	mailer := useRecordingMailer(t)
+	owner := createTestUser(t, name+"-owner", name+"-owner@example.com", models.RoleOwner)
+	workspace := &models.Workspace{Name: name, OwnerID: owner.ID}
+	if err := config.DB.Create(workspace).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	invitation, err := InviteMember(context.Background(), workspace, owner, email, models.RoleEditor)
+	if err != nil {
+		t.Fatalf("邀请失败: %v", err)
+	}
+	if len(mailer.sent) != 1 || mailer.sent[0].To != NormalizeEmail(email) {
+		t.Fatalf("邀请邮件 = %+v", mailer.sent)
+	}
+	_, query, ok := strings.Cut(mailer.sent[0].Body, "/invite?token=")
+	if !ok {
+		t.Fatalf("邀请邮件中没有链接: %s", mailer.sent[0].Body)
+	}
+	token, err := url.QueryUnescape(strings.Fields(query)[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return invitation, token
+}
+
+func TestAcceptInvitationSingleUse(t *testing.T) {
+	invitation, token := inviteForTest(t, "invite-once", "Invitee-Once@Example.com")
+	user := createTestUser(t, "invitee-once", "invitee-once@example.com", models.RoleViewer)
+
+	accepted, err := AcceptInvitation(config.DB, token, user)
+	if err != nil {
+		t.Fatalf("接受邀请失败: %v", err)
+	}
+	if accepted.Status != models.InvitationAccepted || accepted.AcceptedBy != user.ID {
+		t.Errorf("邀请状态 = %+v", accepted)
+	}
+	member, err := GetWorkspaceMember(invitation.WorkspaceID, user.ID)
+	if err != nil || member.Role != models.RoleEditor {
+		t.Fatalf("成员 = %+v, err = %v", member, err)
+	}
+
+	if _, err := AcceptInvitation(config.DB, token, user); !errors.Is(err, ErrInvitationInvalid) {
+		t.Errorf("重复使用令牌应失败，err = %v", err)
+	}
+	other := createTestUser(t, "invitee-once-2", "invitee-once@example.org", models.RoleViewer)
+	if _, err := AcceptInvitation(config.DB, token, other); !errors.Is(err, ErrInvitationInvalid) {
+		t.Errorf("已使用的令牌不能被其他账号使用，err = %v", err)
+	}
+}
+
+func TestAcceptInvitationExpired(t *testing.T) {
+	invitation, token := inviteForTest(t, "invite-expired", "invitee-expired@example.com")
+	user := createTestUser(t, "invitee-expired", "invitee-expired@example.com", models.RoleViewer)
+	config.DB.Model(invitation).Update("expires_at", time.Now().Add(-time.Minute))
+
+	if _, err := AcceptInvitation(config.DB, token, user); !errors.Is(err, ErrInvitationExpired) {
+		t.Errorf("过期邀请应失败，err = %v", err)
+	}
+	if _, err := GetWorkspaceMember(invitation.WorkspaceID, user.ID); err == nil {
+		t.Error("过期邀请不应加入工作空间")
+	}
+}
+
+func TestAcceptInvitationEmailMismatch(t *testing.T) {
+	invitation, token := inviteForTest(t, "invite-mismatch", "invitee-mismatch@example.com")
+	user := createTestUser(t, "invitee-mismatch", "someone-else@example.com", models.RoleViewer)
+
+	if _, err := AcceptInvitation(config.DB, token, user); !errors.Is(err, ErrInvitationEmail) {
+		t.Errorf("邮箱不一致应失败，err = %v", err)
+	}
+	if _, err := GetWorkspaceMember(invitation.WorkspaceID, user.ID); err == nil {
+		t.Error("邮箱不一致不应加入工作空间")
+	}
+
+	// 失败的尝试不会使邀请失效，被邀请人仍可接受
+	invitee := createTestUser(t, "invitee-mismatch-2", "invitee-mismatch@example.com", models.RoleViewer)
+	if _, err := AcceptInvitation(config.DB, token, invitee); err != nil {
+		t.Errorf("被邀请人接受邀请失败: %v", err)
+	}
+}
+
+func TestAcceptInvitationRejectsForgedToken(t *testing.T) {
+	_, token := inviteForTest(t, "invite-forged", "invitee-forged@example.com")
+	user := createTestUser(t, "invitee-forged", "invitee-forged@example.com", models.RoleViewer)
+
+	random, _, _ := strings.Cut(token, ".")
+	if _, err := AcceptInvitation(config.DB, random+".forged", user); !errors.Is(err, ErrInvitationInvalid) {
+		t.Errorf("签名不匹配的令牌应失败，err = %v", err)
+	}
+}
@@ -0,0 +1,178 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"ai-assistant-backend/config"
+)
+
+const (
+	defaultLoginMaxFailures   = 5
+	defaultLoginIPMaxFailures = 20
+	defaultLoginWindow        = 15 * time.Minute
+	defaultLoginLockout       = 15 * time.Minute
+	defaultLoginBaseDelay     = time.Second
+	defaultLoginMaxDelay      = time.Minute
+)
+
+// LoginBlockedError 登录因失败次数过多被暂时禁止
+type LoginBlockedError struct {
+	RetryAfter time.Duration
+	Locked     bool // true表示已锁定，false表示处于失败后的等待期
+}
+
+func (e *LoginBlockedError) Error() string {
+	if e.Locked {
+		return fmt.Sprintf("登录失败次数过多，请%d分钟后再试", int(math.Ceil(e.RetryAfter.Minutes())))
+	}
+	return fmt.Sprintf("登录过于频繁，请%d秒后再试", int(math.Ceil(e.RetryAfter.Seconds())))
+}
+
+// loginGuardSettings 登录防护参数，未配置的项使用默认值
+type loginGuardSettings struct {
+	maxFailures   int
+	ipMaxFailures int
+	window        time.Duration
+	lockout       time.Duration
+	baseDelay     time.Duration
+	maxDelay      time.Duration
+}
+
+func loginGuard() loginGuardSettings {
+	cfg := config.GlobalConfig.Security.LoginGuard
+	settings := loginGuardSettings{
+		maxFailures:   defaultLoginMaxFailures,
+		ipMaxFailures: defaultLoginIPMaxFailures,
+		window:        defaultLoginWindow,
+		lockout:       defaultLoginLockout,
+		baseDelay:     defaultLoginBaseDelay,
+		maxDelay:      defaultLoginMaxDelay,
+	}
+	if cfg.MaxFailures > 0 {
+		settings.maxFailures = cfg.MaxFailures
+	}
+	if cfg.IPMaxFailures > 0 {
+		settings.ipMaxFailures = cfg.IPMaxFailures
+	}
+	if cfg.WindowMinutes > 0 {
+		settings.window = time.Duration(cfg.WindowMinutes) * time.Minute
+	}
+	if cfg.LockoutMinutes > 0 {
+		settings.lockout = time.Duration(cfg.LockoutMinutes) * time.Minute
+	}
+	if cfg.BaseDelay > 0 {
+		settings.baseDelay = time.Duration(cfg.BaseDelay) * time.Second
+	}
+	if cfg.MaxDelay > 0 {
+		settings.maxDelay = time.Duration(cfg.MaxDelay) * time.Second
+	}
+	return settings
+}
+
+// loginScope 失败次数的统计对象，按用户名与按IP分别统计
+type loginScope struct {
+	name        string // user, ip
+	value       string
+	maxFailures int
+}
+
+func (s loginScope) key(kind string) string {
+	return fmt.Sprintf("login_%s:%s:%s", kind, s.name, s.value)
+}
+
+func loginScopes(username, ip string) []loginScope {
+	settings := loginGuard()
+	return []loginScope{
+		{name: "user", value: normalizeUsername(username), maxFailures: settings.maxFailures},
+		{name: "ip", value: ip, maxFailures: settings.ipMaxFailures},
+	}
+}
+
+func normalizeUsername(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
+
+// CheckLoginAllowed 校验用户名与IP当前是否允许尝试登录，被锁定或处于等待期时返回*LoginBlockedError。
+// Redis不可用时放行，避免影响正常登录
+func CheckLoginAllowed(ctx context.Context, username, ip string) error {
+	var blocked *LoginBlockedError
+	for _, scope := range loginScopes(username, ip) {
+		for _, kind := range []string{"lock", "wait"} {
+			ttl, err := config.RedisClient.PTTL(ctx, scope.key(kind)).Result()
+			if err != nil {
+				log.Printf("[login_guard] 读取登录限制失败: %v", err)
+				return nil
+			}
+			if ttl <= 0 {
+				continue
+			}
+			if blocked == nil || ttl > blocked.RetryAfter {
+				blocked = &LoginBlockedError{RetryAfter: ttl, Locked: kind == "lock"}
+			}
+		}
+	}
+	if blocked != nil {
+		return blocked
+	}
+	return nil
+}
+
+// RecordLoginFailure 记录一次登录失败。每次失败后需等待的时间按指数增长，
+// 达到失败次数上限时锁定，返回锁定信息
+func RecordLoginFailure(ctx context.Context, username, ip string) error {
+	settings := loginGuard()
+	var locked *LoginBlockedError
+	for _, scope := range loginScopes(username, ip) {
+		failures, err := config.RedisClient.Incr(ctx, scope.key("fail")).Result()
+		if err != nil {
+			log.Printf("[login_guard] 记录登录失败次数失败: %v", err)
+			return nil
+		}
+		if failures == 1 {
+			config.RedisClient.Expire(ctx, scope.key("fail"), settings.window)
+		}
+
+		if int(failures) >= scope.maxFailures {
+			config.RedisClient.Set(ctx, scope.key("lock"), failures, settings.lockout)
+			config.RedisClient.Del(ctx, scope.key("fail"), scope.key("wait"))
+			log.Printf("[login_guard] %s %s 连续登录失败 %d 次，锁定 %s", scope.name, scope.value, failures, settings.lockout)
+			locked = &LoginBlockedError{RetryAfter: settings.lockout, Locked: true}
+			continue
+		}
+
+		config.RedisClient.Set(ctx, scope.key("wait"), failures, loginDelay(settings, int(failures)))
+	}
+	if locked != nil {
+		return locked
+	}
+	return nil
+}
+
+// loginDelay 第n次失败后的等待时间：baseDelay * 2^(n-1)，不超过maxDelay
+func loginDelay(settings loginGuardSettings, failures int) time.Duration {
+	delay := settings.baseDelay
+	for i := 1; i < failures && delay < settings.maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > settings.maxDelay {
+		delay = settings.maxDelay
+	}
+	return delay
+}
+
+// ResetLoginFailures 登录成功后清除该用户名的失败记录，IP的失败记录保留到窗口结束
+func ResetLoginFailures(ctx context.Context, username string) error {
+	scope := loginScope{name: "user", value: normalizeUsername(username)}
+	return config.RedisClient.Del(ctx, scope.key("fail"), scope.key("wait"), scope.key("lock")).Err()
+}
+
+// UnlockLoginIP 解除IP的登录限制
+func UnlockLoginIP(ctx context.Context, ip string) error {
+	scope := loginScope{name: "ip", value: ip}
+	return config.RedisClient.Del(ctx, scope.key("fail"), scope.key("wait"), scope.key("lock")).Err()
+}
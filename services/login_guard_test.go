@@ -0,0 +1,159 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"ai-assistant-backend/config"
+)
+
+// useLoginGuard 使用指定的登录防护配置，测试结束后恢复默认值
+func useLoginGuard(t *testing.T, cfg config.LoginGuardConfig) {
+	t.Helper()
+	config.GlobalConfig.Security.LoginGuard = cfg
+	t.Cleanup(func() { config.GlobalConfig.Security.LoginGuard = config.LoginGuardConfig{} })
+}
+
+// loginBlocked 返回登录限制，未被限制时返回nil
+func loginBlocked(t *testing.T, username, ip string) *LoginBlockedError {
+	t.Helper()
+	err := CheckLoginAllowed(context.Background(), username, ip)
+	if err == nil {
+		return nil
+	}
+	var blocked *LoginBlockedError
+	if !errors.As(err, &blocked) {
+		t.Fatalf("错误类型 = %T: %v", err, err)
+	}
+	return blocked
+}
+
+func TestLoginDelay(t *testing.T) {
+	settings := loginGuardSettings{baseDelay: time.Second, maxDelay: 10 * time.Second}
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second, 10 * time.Second}
+	for i, delay := range want {
+		if got := loginDelay(settings, i+1); got != delay {
+			t.Errorf("第%d次失败后等待 %s，期望 %s", i+1, got, delay)
+		}
+	}
+}
+
+func TestLoginBackoffGrowth(t *testing.T) {
+	useLoginGuard(t, config.LoginGuardConfig{MaxFailures: 10, IPMaxFailures: 100, BaseDelay: 1, MaxDelay: 4})
+	ctx := context.Background()
+
+	if blocked := loginBlocked(t, "backoff", "10.0.0.1"); blocked != nil {
+		t.Fatalf("首次登录不应受限: %v", blocked)
+	}
+	for i, want := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 4 * time.Second} {
+		if err := RecordLoginFailure(ctx, "backoff", "10.0.0.1"); err != nil {
+			t.Fatalf("第%d次失败不应锁定: %v", i+1, err)
+		}
+		blocked := loginBlocked(t, "Backoff ", "10.0.0.1")
+		if blocked == nil || blocked.Locked || blocked.RetryAfter != want {
+			t.Fatalf("第%d次失败后的限制 = %+v，期望等待 %s", i+1, blocked, want)
+		}
+		// 等待期结束后允许再次尝试
+		testRedis.FastForward(want)
+		if blocked := loginBlocked(t, "backoff", "10.0.0.1"); blocked != nil {
+			t.Fatalf("第%d次失败的等待期结束后仍受限: %v", i+1, blocked)
+		}
+	}
+}
+
+func TestLoginLockout(t *testing.T) {
+	useLoginGuard(t, config.LoginGuardConfig{MaxFailures: 3, IPMaxFailures: 100, LockoutMinutes: 10, BaseDelay: 1, MaxDelay: 1})
+	ctx := context.Background()
+
+	for i := 1; i < 3; i++ {
+		if err := RecordLoginFailure(ctx, "lockout", "10.0.0.2"); err != nil {
+			t.Fatalf("第%d次失败不应锁定: %v", i, err)
+		}
+		testRedis.FastForward(time.Second)
+	}
+	err := RecordLoginFailure(ctx, "lockout", "10.0.0.2")
+	var locked *LoginBlockedError
+	if !errors.As(err, &locked) || !locked.Locked || locked.RetryAfter != 10*time.Minute {
+		t.Fatalf("第3次失败 = %v，期望锁定10分钟", err)
+	}
+
+	// 锁定按用户名生效，其他IP同样无法登录该用户，其他用户不受影响
+	if blocked := loginBlocked(t, "lockout", "10.0.0.3"); blocked == nil || !blocked.Locked {
+		t.Errorf("锁定期间 = %+v，期望已锁定", blocked)
+	}
+	if blocked := loginBlocked(t, "other", "10.0.0.3"); blocked != nil {
+		t.Errorf("其他用户受到限制: %v", blocked)
+	}
+
+	testRedis.FastForward(10 * time.Minute)
+	if blocked := loginBlocked(t, "lockout", "10.0.0.2"); blocked != nil {
+		t.Errorf("锁定结束后仍受限: %v", blocked)
+	}
+	// 锁定后失败次数重新计算
+	if err := RecordLoginFailure(ctx, "lockout", "10.0.0.2"); err != nil {
+		t.Errorf("解锁后首次失败不应锁定: %v", err)
+	}
+}
+
+func TestLoginIPLockout(t *testing.T) {
+	useLoginGuard(t, config.LoginGuardConfig{MaxFailures: 100, IPMaxFailures: 3, LockoutMinutes: 5, BaseDelay: 1, MaxDelay: 1})
+	ctx := context.Background()
+
+	// 同一IP尝试不同用户名同样计入失败次数
+	var err error
+	for _, username := range []string{"ip-a", "ip-b", "ip-c"} {
+		err = RecordLoginFailure(ctx, username, "10.0.0.4")
+		testRedis.FastForward(time.Second)
+	}
+	var locked *LoginBlockedError
+	if !errors.As(err, &locked) || !locked.Locked {
+		t.Fatalf("IP失败3次 = %v，期望锁定", err)
+	}
+	if blocked := loginBlocked(t, "ip-d", "10.0.0.4"); blocked == nil || !blocked.Locked {
+		t.Errorf("被锁定的IP登录其他用户 = %+v，期望已锁定", blocked)
+	}
+	if blocked := loginBlocked(t, "ip-d", "10.0.0.5"); blocked != nil {
+		t.Errorf("其他IP受到限制: %v", blocked)
+	}
+
+	if err := UnlockLoginIP(ctx, "10.0.0.4"); err != nil {
+		t.Fatal(err)
+	}
+	if blocked := loginBlocked(t, "ip-d", "10.0.0.4"); blocked != nil {
+		t.Errorf("解除IP限制后仍受限: %v", blocked)
+	}
+}
+
+func TestResetLoginFailures(t *testing.T) {
+	useLoginGuard(t, config.LoginGuardConfig{MaxFailures: 3, IPMaxFailures: 3, BaseDelay: 1, MaxDelay: 1})
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if err := RecordLoginFailure(ctx, "reset", "10.0.0.6"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if blocked := loginBlocked(t, "reset", "10.0.0.7"); blocked == nil {
+		t.Fatal("失败后应处于等待期")
+	}
+
+	// 登录成功后清除用户名的失败次数与等待期
+	if err := ResetLoginFailures(ctx, "RESET"); err != nil {
+		t.Fatal(err)
+	}
+	if blocked := loginBlocked(t, "reset", "10.0.0.7"); blocked != nil {
+		t.Errorf("登录成功后仍受限: %v", blocked)
+	}
+	if err := RecordLoginFailure(ctx, "reset", "10.0.0.7"); err != nil {
+		t.Errorf("重置后失败次数应重新计算: %v", err)
+	}
+
+	// IP的失败次数保留：10.0.0.6已失败2次，再失败一次即锁定
+	testRedis.FastForward(time.Second)
+	var locked *LoginBlockedError
+	if err := RecordLoginFailure(ctx, "reset-other", "10.0.0.6"); !errors.As(err, &locked) || !locked.Locked {
+		t.Errorf("IP第3次失败 = %v，期望锁定", err)
+	}
+}
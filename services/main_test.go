@@ -0,0 +1,92 @@
+package services
+
+import (
+	"log"
+	"os"
+	"testing"
+
+	"ai-assistant-backend/config"
+	"ai-assistant-backend/models"
+	"ai-assistant-backend/utils"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/glebarez/sqlite"
+	"github.com/go-redis/redis/v8"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testRedis 测试使用的内存Redis，可用于快进时间模拟过期
+var testRedis *miniredis.Miniredis
+
+// TestMain 使用内存SQLite与miniredis代替MySQL和Redis
+func TestMain(m *testing.M) {
+	config.GlobalConfig = &config.Config{}
+	config.GlobalConfig.JWT.Secret = "test-secret"
+	// 使用进程内的模拟大模型，相同输入总是得到相同输出
+	config.GlobalConfig.LLM.DefaultProvider = "mock"
+	config.GlobalConfig.LLM.Providers = []config.LLMProviderConfig{{
+		Name:           "mock",
+		Type:           "mock",
+		Models:         []string{"mock-chat"},
+		DefaultModel:   "mock-chat",
+		EmbeddingModel: "mock-embedding",
+	}}
+	if err := utils.InitLLM(); err != nil {
+		log.Fatal("初始化模拟大模型失败:", err)
+	}
+
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		log.Fatal("打开测试数据库失败:", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		log.Fatal("打开测试数据库失败:", err)
+	}
+	// 内存数据库只在同一连接内可见
+	sqlDB.SetMaxOpenConns(1)
+	err = db.AutoMigrate(
+		&models.User{},
+		&models.Workspace{},
+		&models.WorkspaceMember{},
+		&models.Invitation{},
+		&models.Agent{},
+		&models.AgentVersion{},
+		&models.AgentCarouselImage{},
+		&models.SelfService{},
+		&models.DocumentCategory{},
+		&models.Document{},
+		&models.DocumentChunk{},
+		&models.DocumentTag{},
+		&models.Tag{},
+		&models.FAQCategory{},
+		&models.FAQ{},
+		&models.Conversation{},
+		&models.Message{},
+	)
+	if err != nil {
+		log.Fatal("迁移测试数据库失败:", err)
+	}
+	config.DB = db
+
+	testRedis, err = miniredis.Run()
+	if err != nil {
+		log.Fatal("启动测试Redis失败:", err)
+	}
+	config.RedisClient = redis.NewClient(&redis.Options{Addr: testRedis.Addr()})
+
+	code := m.Run()
+	testRedis.Close()
+	os.Exit(code)
+}
+
+// createTestUser 创建测试用户
+func createTestUser(t *testing.T, username, email string, role uint8) *models.User {
+	t.Helper()
+	user := &models.User{Username: username, Password: "x", Email: email, Role: role}
+	if err := config.DB.Create(user).Error; err != nil {
+		t.Fatalf("创建用户失败: %v", err)
+	}
+	return user
+}
@@ -0,0 +1,224 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+
+	"ai-assistant-backend/config"
+	"ai-assistant-backend/models"
+	"ai-assistant-backend/utils"
+)
+
+// MigrateTagUniqueIndex 标签名称由全局唯一改为同一智能体内唯一，删除旧的唯一索引，
+// 复制智能体时需要在新智能体下创建同名标签
+func MigrateTagUniqueIndex() error {
+	migrator := config.DB.Migrator()
+	for _, name := range []string{"uni_tags_name", "name"} {
+		if migrator.HasIndex(&models.Tag{}, name) {
+			if err := migrator.DropIndex(&models.Tag{}, name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// MigrateAgentAppIDs 为AppID添加唯一索引前处理重复的AppID：保留最早创建的智能体，
+// 其余智能体的AppID追加「-ID」后缀，需在AutoMigrate之前执行
+func MigrateAgentAppIDs() error {
+	migrator := config.DB.Migrator()
+	if !migrator.HasTable(&models.Agent{}) || migrator.HasIndex(&models.Agent{}, "idx_agents_app_id") {
+		return nil
+	}
+
+	var duplicates []string
+	if err := config.DB.Model(&models.Agent{}).Group("app_id").Having("COUNT(*) > 1").Pluck("app_id", &duplicates).Error; err != nil {
+		return err
+	}
+	for _, appID := range duplicates {
+		var agents []models.Agent
+		if err := config.DB.Select("id").Where("app_id = ?", appID).Order("id").Find(&agents).Error; err != nil {
+			return err
+		}
+		for _, agent := range agents[1:] {
+			newAppID := fmt.Sprintf("%s-%d", appID, agent.ID)
+			updates := map[string]interface{}{"app_id": newAppID, "link": AgentChatLink(newAppID)}
+			if err := config.DB.Model(&models.Agent{}).Where("id = ?", agent.ID).Updates(updates).Error; err != nil {
+				return err
+			}
+			log.Printf("[migration] 智能体 %d 的AppID与其他智能体重复，已由 %s 改为 %s", agent.ID, appID, newAppID)
+		}
+	}
+	return nil
+}
+
+// MigrateLegacyObjects 将升级前上传到工作空间前缀之外的文件复制到所属智能体的工作空间下，
+// 并改写文档路径以及Logo、轮播图、自助服务图标的地址，需在MigrateWorkspaces之后执行。
+// 原文件保留，已位于工作空间内的记录会被跳过，可重复执行
+func MigrateLegacyObjects() error {
+	uploader := utils.NewMinIOUploader()
+	return migrateLegacyObjects(uploader.CopyFile, uploader.GetFileURL)
+}
+
+// legacyObjectMigration 一次迁移中已复制的文件，同一文件只复制一次
+type legacyObjectMigration struct {
+	copyFile func(src, dst string) error
+	fileURL  func(objectName string) (string, error)
+	copied   map[string]bool
+	failed   map[string]bool
+}
+
+func migrateLegacyObjects(copyFile func(src, dst string) error, fileURL func(string) (string, error)) error {
+	m := &legacyObjectMigration{copyFile: copyFile, fileURL: fileURL, copied: map[string]bool{}, failed: map[string]bool{}}
+
+	var agents []models.Agent
+	if err := config.DB.Where("workspace_id <> ?", 0).Find(&agents).Error; err != nil {
+		return err
+	}
+	updated := 0
+	for _, agent := range agents {
+		count, err := m.migrateAgent(&agent)
+		if err != nil {
+			return err
+		}
+		updated += count
+	}
+	if updated > 0 {
+		log.Printf("[migration] 已将 %d 条记录引用的文件迁移到工作空间，复制 %d 个文件", updated, len(m.copied))
+	}
+	return nil
+}
+
+// migrateAgent 迁移智能体的文档、Logo、轮播图与自助服务图标，返回改写的记录数
+func (m *legacyObjectMigration) migrateAgent(agent *models.Agent) (int, error) {
+	updated := 0
+
+	var documents []models.Document
+	if err := config.DB.Omit("content").Where("agent_id = ?", agent.ID).Find(&documents).Error; err != nil {
+		return updated, err
+	}
+	for _, document := range documents {
+		objectName, ok := legacyObjectName(document.Path, false)
+		if !ok {
+			continue
+		}
+		target, ok := m.copy(objectName, agent.WorkspaceID)
+		if !ok {
+			continue
+		}
+		values := map[string]interface{}{"path": target}
+		// 升级后因文件不在工作空间内而解析失败的文档重新解析
+		if document.Status == models.DocumentStatusFailed && document.StatusError == errDocumentOutsideWorkspace.Error() {
+			values["status"] = models.DocumentStatusPending
+			values["status_error"] = ""
+		}
+		if err := config.DB.Model(&document).Updates(values).Error; err != nil {
+			return updated, err
+		}
+		updated++
+	}
+
+	if fileURL, ok := m.migrateURL(agent.Logo, agent.WorkspaceID); ok {
+		if err := config.DB.Model(agent).Update("logo", fileURL).Error; err != nil {
+			return updated, err
+		}
+		updated++
+	}
+
+	var carouselImages []models.AgentCarouselImage
+	if err := config.DB.Where("agent_id = ?", agent.ID).Find(&carouselImages).Error; err != nil {
+		return updated, err
+	}
+	for _, img := range carouselImages {
+		if fileURL, ok := m.migrateURL(img.ImageURL, agent.WorkspaceID); ok {
+			if err := config.DB.Model(&img).Update("image_url", fileURL).Error; err != nil {
+				return updated, err
+			}
+			updated++
+		}
+	}
+
+	var selfServices []models.SelfService
+	if err := config.DB.Where("agent_id = ?", agent.ID).Find(&selfServices).Error; err != nil {
+		return updated, err
+	}
+	for _, service := range selfServices {
+		if fileURL, ok := m.migrateURL(service.Icon, agent.WorkspaceID); ok {
+			if err := config.DB.Model(&service).Update("icon", fileURL).Error; err != nil {
+				return updated, err
+			}
+			updated++
+		}
+	}
+	return updated, nil
+}
+
+// migrateURL 地址指向MinIO中工作空间之外的文件时复制文件并返回新的访问地址
+func (m *legacyObjectMigration) migrateURL(fileURL string, workspaceID uint) (string, bool) {
+	objectName, ok := legacyObjectName(fileURL, true)
+	if !ok {
+		return "", false
+	}
+	target, ok := m.copy(objectName, workspaceID)
+	if !ok {
+		return "", false
+	}
+	newURL, err := m.fileURL(target)
+	if err != nil {
+		log.Printf("[migration] 生成文件 %s 的访问地址失败: %v", target, err)
+		return "", false
+	}
+	return newURL, true
+}
+
+// copy 将文件复制到工作空间前缀下，保留原有的相对路径
+func (m *legacyObjectMigration) copy(objectName string, workspaceID uint) (string, bool) {
+	target := utils.WorkspaceObjectPrefix(workspaceID) + objectName
+	if m.copied[target] {
+		return target, true
+	}
+	if m.failed[target] {
+		return "", false
+	}
+	if err := m.copyFile(objectName, target); err != nil {
+		log.Printf("[migration] 复制文件 %s 失败，保留原地址: %v", objectName, err)
+		m.failed[target] = true
+		return "", false
+	}
+	m.copied[target] = true
+	return target, true
+}
+
+// legacyObjectName 路径或地址指向工作空间之外的文件时返回对象名称。
+// requireURL为true时只处理指向本服务MinIO的完整地址，其他网站的图片地址保持不变
+func legacyObjectName(path string, requireURL bool) (string, bool) {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return "", false
+	}
+	isURL := strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://")
+	if isURL {
+		parsed, err := url.Parse(path)
+		if err != nil || !isMinIOHost(parsed.Host) {
+			return "", false
+		}
+	} else if requireURL {
+		return "", false
+	}
+
+	objectName := utils.ObjectNameFromPath(path)
+	if objectName == "" || strings.HasPrefix(objectName, "workspaces/") || strings.Contains(objectName, "..") {
+		return "", false
+	}
+	return objectName, true
+}
+
+// isMinIOHost 地址是否指向配置的MinIO服务，兼容路径式与虚拟主机式地址
+func isMinIOHost(host string) bool {
+	cfg := config.GlobalConfig.MinIO
+	host = strings.ToLower(host)
+	endpoint := strings.ToLower(cfg.Endpoint)
+	return endpoint != "" && (host == endpoint || host == strings.ToLower(cfg.Bucket)+"."+endpoint)
+}
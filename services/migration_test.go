@@ -0,0 +1,146 @@
+package services
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+
+	"ai-assistant-backend/config"
+	"ai-assistant-backend/models"
+)
+
+func TestMigrateLegacyObjects(t *testing.T) {
+	config.GlobalConfig.MinIO.Endpoint = "minio.local:9000"
+	config.GlobalConfig.MinIO.Bucket = "upload"
+	t.Cleanup(func() { config.GlobalConfig.MinIO = config.MinIOConfig{} })
+
+	agent := &models.Agent{AppID: "legacy-objects", Name: "legacy", WorkspaceID: 7, Logo: "http://minio.local:9000/upload/uploads/2024-01-01/logo.png?X-Amz-Signature=old"}
+	if err := config.DB.Create(agent).Error; err != nil {
+		t.Fatal(err)
+	}
+	documents := []models.Document{
+		{AgentID: agent.ID, Name: "a.pdf", Path: "uploads/2024-01-01/a.pdf", Status: models.DocumentStatusFailed, StatusError: errDocumentOutsideWorkspace.Error()},
+		{AgentID: agent.ID, Name: "b.pdf", Path: "/uploads/2024-01-01/a.pdf", Status: models.DocumentStatusReady},
+		{AgentID: agent.ID, Name: "c.pdf", Path: "workspaces/7/uploads/2024-01-01/c.pdf", Status: models.DocumentStatusReady},
+		{AgentID: agent.ID, Name: "missing.pdf", Path: "uploads/missing.pdf", Status: models.DocumentStatusFailed, StatusError: errDocumentOutsideWorkspace.Error()},
+	}
+	for i := range documents {
+		if err := config.DB.Create(&documents[i]).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	carousel := []models.AgentCarouselImage{
+		{AgentID: agent.ID, ImageURL: "http://minio.local:9000/upload/uploads/2024-01-01/banner.png"},
+		{AgentID: agent.ID, ImageURL: "https://cdn.example.com/uploads/banner.png"},
+	}
+	for i := range carousel {
+		if err := config.DB.Create(&carousel[i]).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var copies [][2]string
+	copyFile := func(src, dst string) error {
+		if src == "uploads/missing.pdf" {
+			return errors.New("NoSuchKey")
+		}
+		copies = append(copies, [2]string{src, dst})
+		return nil
+	}
+	fileURL := func(objectName string) (string, error) {
+		return "http://minio.local:9000/upload/" + objectName + "?signed", nil
+	}
+	if err := migrateLegacyObjects(copyFile, fileURL); err != nil {
+		t.Fatalf("迁移失败: %v", err)
+	}
+
+	wantPaths := map[string]string{
+		"a.pdf":       "workspaces/7/uploads/2024-01-01/a.pdf",
+		"b.pdf":       "workspaces/7/uploads/2024-01-01/a.pdf",
+		"c.pdf":       "workspaces/7/uploads/2024-01-01/c.pdf",
+		"missing.pdf": "uploads/missing.pdf",
+	}
+	for _, document := range documents {
+		var reloaded models.Document
+		config.DB.First(&reloaded, document.ID)
+		if reloaded.Path != wantPaths[document.Name] {
+			t.Errorf("%s 路径 = %s，期望 %s", document.Name, reloaded.Path, wantPaths[document.Name])
+		}
+		switch document.Name {
+		case "a.pdf":
+			if reloaded.Status != models.DocumentStatusPending {
+				t.Errorf("迁移后的文档状态 = %s，期望重新解析", reloaded.Status)
+			}
+		case "missing.pdf":
+			if reloaded.Status != models.DocumentStatusFailed {
+				t.Errorf("复制失败的文档状态 = %s，期望保持失败", reloaded.Status)
+			}
+		}
+	}
+
+	var reloadedAgent models.Agent
+	config.DB.First(&reloadedAgent, agent.ID)
+	if reloadedAgent.Logo != "http://minio.local:9000/upload/workspaces/7/uploads/2024-01-01/logo.png?signed" {
+		t.Errorf("Logo = %s", reloadedAgent.Logo)
+	}
+	var images []models.AgentCarouselImage
+	config.DB.Where("agent_id = ?", agent.ID).Order("id").Find(&images)
+	if images[0].ImageURL != "http://minio.local:9000/upload/workspaces/7/uploads/2024-01-01/banner.png?signed" {
+		t.Errorf("轮播图 = %s", images[0].ImageURL)
+	}
+	if images[1].ImageURL != carousel[1].ImageURL {
+		t.Errorf("其他网站的图片地址被改写: %s", images[1].ImageURL)
+	}
+
+	// 同一文件只复制一次：a.pdf、logo、banner
+	if len(copies) != 3 {
+		t.Errorf("复制了 %d 个文件，期望 3: %v", len(copies), copies)
+	}
+
+	// 再次执行不会重复复制
+	copies = nil
+	if err := migrateLegacyObjects(copyFile, fileURL); err != nil {
+		t.Fatal(err)
+	}
+	if len(copies) != 0 {
+		t.Errorf("重复执行复制了 %v", copies)
+	}
+}
+
+func TestMigrateAgentAppIDs(t *testing.T) {
+	migrator := config.DB.Migrator()
+	// 模拟升级前没有唯一索引的数据
+	if err := migrator.DropIndex(&models.Agent{}, "idx_agents_app_id"); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := migrator.CreateIndex(&models.Agent{}, "idx_agents_app_id"); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	agents := []models.Agent{
+		{AppID: "duplicate-app", Name: "first"},
+		{AppID: "duplicate-app", Name: "second"},
+		{AppID: "duplicate-app", Name: "third"},
+		{AppID: "unique-app", Name: "unique"},
+	}
+	for i := range agents {
+		if err := config.DB.Create(&agents[i]).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := MigrateAgentAppIDs(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"duplicate-app", "duplicate-app-" + strconv.FormatUint(uint64(agents[1].ID), 10), "duplicate-app-" + strconv.FormatUint(uint64(agents[2].ID), 10), "unique-app"}
+	for i, agent := range agents {
+		var reloaded models.Agent
+		config.DB.First(&reloaded, agent.ID)
+		renamed := want[i] != agent.AppID
+		if reloaded.AppID != want[i] || (renamed && reloaded.Link != AgentChatLink(want[i])) {
+			t.Errorf("%s 的AppID = %s，链接 = %s，期望 %s", agent.Name, reloaded.AppID, reloaded.Link, want[i])
+		}
+	}
+}
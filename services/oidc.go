@@ -0,0 +1,280 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"ai-assistant-backend/config"
+	"ai-assistant-backend/models"
+	"ai-assistant-backend/utils"
+
+	"github.com/go-redis/redis/v8"
+	"gorm.io/gorm"
+)
+
+// oidcStateTTL 跳转到身份提供方后需在该时间内完成登录
+const oidcStateTTL = 10 * time.Minute
+
+var (
+	// ErrOIDCStateInvalid state无效、已使用或已过期
+	ErrOIDCStateInvalid = errors.New("单点登录请求已失效，请重新登录")
+	// ErrOIDCEmailMissing 身份提供方未返回邮箱
+	ErrOIDCEmailMissing = errors.New("身份提供方未返回邮箱，无法登录")
+	// ErrOIDCEmailUnverified 身份提供方标记邮箱未验证
+	ErrOIDCEmailUnverified = errors.New("邮箱未经身份提供方验证，无法登录")
+	// ErrOIDCAccountNotFound 未开启自动创建用户且邮箱未注册
+	ErrOIDCAccountNotFound = errors.New("该邮箱尚未开通账号，请联系管理员")
+	// ErrOIDCIdentityConflict 邮箱对应的用户已关联其他单点登录身份
+	ErrOIDCIdentityConflict = errors.New("该邮箱对应的账号已关联其他单点登录身份")
+)
+
+// rolePriority 角色权限从高到低，用户属于多个用户组时取权限最高的角色
+var rolePriority = []uint8{models.RoleSuperAdmin, models.RoleOwner, models.RoleEditor, models.RoleViewer}
+
+var usernameInvalidChars = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+
+// oidcState 发起登录时保存的PKCE校验码与nonce
+type oidcState struct {
+	CodeVerifier string `json:"code_verifier"`
+	Nonce        string `json:"nonce"`
+}
+
+func oidcStateKey(state string) string {
+	return fmt.Sprintf("oidc_state:%s", utils.HashToken(state))
+}
+
+// BeginOIDCLogin 生成state、nonce与PKCE校验码，返回身份提供方的授权地址
+func BeginOIDCLogin(ctx context.Context) (string, string, error) {
+	provider, err := utils.GetOIDCProvider()
+	if err != nil {
+		return "", "", err
+	}
+
+	state, err := utils.GenerateRandomString(32)
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := utils.GenerateRandomString(32)
+	if err != nil {
+		return "", "", err
+	}
+	verifier, challenge, err := utils.GeneratePKCE()
+	if err != nil {
+		return "", "", err
+	}
+
+	authURL, err := provider.AuthCodeURL(ctx, state, nonce, challenge)
+	if err != nil {
+		return "", "", err
+	}
+
+	data, err := json.Marshal(oidcState{CodeVerifier: verifier, Nonce: nonce})
+	if err != nil {
+		return "", "", err
+	}
+	if err := config.RedisClient.Set(ctx, oidcStateKey(state), data, oidcStateTTL).Err(); err != nil {
+		return "", "", fmt.Errorf("保存登录状态失败: %v", err)
+	}
+	return authURL, state, nil
+}
+
+// CompleteOIDCLogin 使用回调中的授权码完成登录，返回关联或自动创建的用户
+func CompleteOIDCLogin(ctx context.Context, code, state string) (*models.User, error) {
+	provider, err := utils.GetOIDCProvider()
+	if err != nil {
+		return nil, err
+	}
+
+	// state只能使用一次
+	key := oidcStateKey(state)
+	data, err := config.RedisClient.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrOIDCStateInvalid
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取登录状态失败: %v", err)
+	}
+	if deleted, err := config.RedisClient.Del(ctx, key).Result(); err != nil || deleted == 0 {
+		return nil, ErrOIDCStateInvalid
+	}
+	var saved oidcState
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, ErrOIDCStateInvalid
+	}
+
+	identity, err := provider.Exchange(ctx, code, saved.CodeVerifier, saved.Nonce)
+	if err != nil {
+		return nil, err
+	}
+	return linkOIDCUser(identity)
+}
+
+// linkOIDCUser 按sub查找已关联的用户，找不到时按邮箱关联已有用户或自动创建用户。
+// 用户组匹配到角色时同步更新用户角色
+func linkOIDCUser(identity *utils.OIDCIdentity) (*models.User, error) {
+	cfg := config.GlobalConfig.OIDC
+	mappedRole, mapped := MapOIDCGroupsToRole(identity.Groups)
+
+	var user models.User
+	err := config.DB.Where("oidc_subject = ?", identity.Subject).First(&user).Error
+	if err == nil {
+		return &user, syncOIDCRole(&user, mappedRole, mapped)
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	email := NormalizeEmail(identity.Email)
+	if email == "" {
+		return nil, ErrOIDCEmailMissing
+	}
+	if identity.EmailVerified != nil && !*identity.EmailVerified {
+		return nil, ErrOIDCEmailUnverified
+	}
+
+	err = config.DB.Where("email = ?", email).First(&user).Error
+	switch {
+	case err == nil:
+		// 按邮箱关联已有账号时要求身份提供方明确声明邮箱已验证，避免冒用邮箱接管账号
+		if identity.EmailVerified == nil || !*identity.EmailVerified {
+			return nil, ErrOIDCEmailUnverified
+		}
+		if user.OIDCSubject != nil && *user.OIDCSubject != identity.Subject {
+			return nil, ErrOIDCIdentityConflict
+		}
+		// 条件更新避免并发登录时重复关联
+		result := config.DB.Model(&models.User{}).
+			Where("id = ? AND oidc_subject IS NULL", user.ID).
+			Update("oidc_subject", identity.Subject)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil, ErrOIDCIdentityConflict
+		}
+		user.OIDCSubject = &identity.Subject
+		log.Printf("[oidc] 用户 %d 已关联单点登录身份", user.ID)
+		return &user, syncOIDCRole(&user, mappedRole, mapped)
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, err
+	case !cfg.AutoProvision:
+		return nil, ErrOIDCAccountNotFound
+	}
+
+	role := cfg.DefaultRole
+	if mapped {
+		role = mappedRole
+	}
+	if !models.ValidRole(role) {
+		role = models.RoleViewer
+	}
+	return provisionOIDCUser(identity, email, role)
+}
+
+// provisionOIDCUser 自动创建用户及其个人工作空间，密码随机生成，只能通过单点登录或重置密码登录
+func provisionOIDCUser(identity *utils.OIDCIdentity, email string, role uint8) (*models.User, error) {
+	random, err := utils.GenerateRandomString(48)
+	if err != nil {
+		return nil, err
+	}
+	hashedPassword, err := utils.HashPassword(random)
+	if err != nil {
+		return nil, fmt.Errorf("密码加密失败: %v", err)
+	}
+
+	subject := identity.Subject
+	user := models.User{
+		Password:    hashedPassword,
+		Email:       email,
+		Role:        role,
+		OIDCSubject: &subject,
+	}
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		username, err := uniqueUsername(tx, identity, email)
+		if err != nil {
+			return err
+		}
+		user.Username = username
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+		_, err = CreatePersonalWorkspace(tx, &user)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("创建用户失败: %v", err)
+	}
+
+	log.Printf("[oidc] 自动创建用户 %d (%s)，角色 %d", user.ID, user.Username, user.Role)
+	return &user, nil
+}
+
+// uniqueUsername 以preferred_username或邮箱前缀为基础生成未被占用的用户名
+func uniqueUsername(tx *gorm.DB, identity *utils.OIDCIdentity, email string) (string, error) {
+	base := identity.PreferredUsername
+	if base == "" || strings.Contains(base, "@") {
+		base, _, _ = strings.Cut(email, "@")
+	}
+	base = usernameInvalidChars.ReplaceAllString(base, "")
+	if base == "" {
+		base = "user"
+	}
+	if len(base) > 32 {
+		base = base[:32]
+	}
+
+	for i := 0; i < 10; i++ {
+		username := base
+		if i > 0 {
+			suffix, err := utils.GenerateRandomString(4)
+			if err != nil {
+				return "", err
+			}
+			username = base + "_" + suffix
+		}
+
+		var count int64
+		if err := tx.Model(&models.User{}).Where("username = ?", username).Count(&count).Error; err != nil {
+			return "", err
+		}
+		if count == 0 {
+			return username, nil
+		}
+	}
+	return "", errors.New("无法生成可用的用户名")
+}
+
+// syncOIDCRole 用户组匹配到角色且与当前角色不同时更新用户角色
+func syncOIDCRole(user *models.User, role uint8, mapped bool) error {
+	if !mapped || user.Role == role {
+		return nil
+	}
+	if err := config.DB.Model(user).Update("role", role).Error; err != nil {
+		return fmt.Errorf("同步用户角色失败: %v", err)
+	}
+	log.Printf("[oidc] 用户 %d 的角色按用户组同步为 %d", user.ID, role)
+	return nil
+}
+
+// MapOIDCGroupsToRole 按配置的映射将用户组转换为角色，取权限最高的角色；没有匹配时返回false
+func MapOIDCGroupsToRole(groups []string) (uint8, bool) {
+	mapping := config.GlobalConfig.OIDC.GroupRoles
+	matched := map[uint8]bool{}
+	for _, group := range groups {
+		if role, ok := mapping[group]; ok && models.ValidRole(role) {
+			matched[role] = true
+		}
+	}
+	for _, role := range rolePriority {
+		if matched[role] {
+			return role, true
+		}
+	}
+	return 0, false
+}
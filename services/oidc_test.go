@@ -0,0 +1,266 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"ai-assistant-backend/config"
+	"ai-assistant-backend/models"
+	"ai-assistant-backend/scripts/oidc_stub/idp"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	stubIdPOnce sync.Once
+	stubIdP     *idp.IdP
+)
+
+// setupOIDC 启动本地调试身份提供方并启用单点登录，身份提供方在全部测试中共用
+func setupOIDC(t *testing.T) *idp.IdP {
+	t.Helper()
+	stubIdPOnce.Do(func() {
+		provider, err := idp.New("", "ai-assistant", "stub-secret")
+		if err != nil {
+			t.Fatalf("创建身份提供方失败: %v", err)
+		}
+		server := httptest.NewServer(provider.Handler())
+		provider.Issuer = server.URL
+		stubIdP = provider
+
+		config.GlobalConfig.OIDC = config.OIDCConfig{
+			Enabled:      true,
+			Issuer:       server.URL,
+			ClientID:     "ai-assistant",
+			ClientSecret: "stub-secret",
+			RedirectURL:  "http://localhost:3000/oidc/callback",
+			GroupRoles: map[string]uint8{
+				"ai-admins":  models.RoleSuperAdmin,
+				"ai-editors": models.RoleEditor,
+				"broken":     9,
+			},
+			DefaultRole:   models.RoleViewer,
+			AutoProvision: true,
+		}
+	})
+
+	stubIdP.Groups = nil
+	t.Cleanup(func() {
+		stubIdP.ModifyClaims = nil
+		stubIdP.SigningKey = nil
+	})
+	return stubIdP
+}
+
+// authorizeOIDC 发起单点登录并访问授权地址，返回回调中的授权码与state
+func authorizeOIDC(t *testing.T, email string) (string, string) {
+	t.Helper()
+	authURL, state, err := BeginOIDCLogin(context.Background())
+	if err != nil {
+		t.Fatalf("发起单点登录失败: %v", err)
+	}
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(authURL + "&login_hint=" + url.QueryEscape(email))
+	if err != nil {
+		t.Fatalf("访问授权地址失败: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("授权请求返回 %d", resp.StatusCode)
+	}
+
+	callback, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatalf("解析回调地址失败: %v", err)
+	}
+	if callback.Query().Get("state") != state {
+		t.Fatalf("回调state不一致")
+	}
+	return callback.Query().Get("code"), state
+}
+
+// loginOIDC 以指定邮箱完成一次单点登录
+func loginOIDC(t *testing.T, email string) (*models.User, error) {
+	t.Helper()
+	code, state := authorizeOIDC(t, email)
+	return CompleteOIDCLogin(context.Background(), code, state)
+}
+
+func TestOIDCLoginProvisionsUser(t *testing.T) {
+	stub := setupOIDC(t)
+	stub.Groups = []string{"ai-editors"}
+
+	user, err := loginOIDC(t, "new.user@example.com")
+	if err != nil {
+		t.Fatalf("登录失败: %v", err)
+	}
+	if user.Email != "new.user@example.com" || user.Username != "new.user" {
+		t.Errorf("用户信息不正确: %s %s", user.Username, user.Email)
+	}
+	if user.Role != models.RoleEditor {
+		t.Errorf("角色 = %d，期望按用户组映射为 %d", user.Role, models.RoleEditor)
+	}
+	if user.OIDCSubject == nil || *user.OIDCSubject != "stub|new.user@example.com" {
+		t.Errorf("未关联单点登录身份")
+	}
+	if _, err := DefaultWorkspaceID(user.ID); err != nil {
+		t.Errorf("未创建个人工作空间: %v", err)
+	}
+
+	again, err := loginOIDC(t, "new.user@example.com")
+	if err != nil {
+		t.Fatalf("再次登录失败: %v", err)
+	}
+	if again.ID != user.ID {
+		t.Errorf("再次登录创建了新用户 %d，期望 %d", again.ID, user.ID)
+	}
+}
+
+func TestOIDCStateInvalid(t *testing.T) {
+	setupOIDC(t)
+	ctx := context.Background()
+
+	code, _ := authorizeOIDC(t, "state@example.com")
+	if _, err := CompleteOIDCLogin(ctx, code, "unknown-state"); !errors.Is(err, ErrOIDCStateInvalid) {
+		t.Errorf("未知state: err = %v，期望 %v", err, ErrOIDCStateInvalid)
+	}
+
+	code, state := authorizeOIDC(t, "state@example.com")
+	if _, err := CompleteOIDCLogin(ctx, code, state); err != nil {
+		t.Fatalf("登录失败: %v", err)
+	}
+	if _, err := CompleteOIDCLogin(ctx, code, state); !errors.Is(err, ErrOIDCStateInvalid) {
+		t.Errorf("重放state: err = %v，期望 %v", err, ErrOIDCStateInvalid)
+	}
+}
+
+func TestOIDCRejectsInvalidIDToken(t *testing.T) {
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(claims jwt.MapClaims)
+		key    *rsa.PrivateKey
+	}{
+		{name: "nonce不匹配", modify: func(claims jwt.MapClaims) { claims["nonce"] = "other-nonce" }},
+		{name: "缺少nonce", modify: func(claims jwt.MapClaims) { delete(claims, "nonce") }},
+		{name: "受众错误", modify: func(claims jwt.MapClaims) { claims["aud"] = "other-client" }},
+		{name: "签发方错误", modify: func(claims jwt.MapClaims) { claims["iss"] = "https://evil.example.com" }},
+		{name: "已过期", modify: func(claims jwt.MapClaims) { claims["exp"] = 1 }},
+		{name: "签名错误", key: otherKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := setupOIDC(t)
+			stub.ModifyClaims = tt.modify
+			stub.SigningKey = tt.key
+
+			user, err := loginOIDC(t, "invalid@example.com")
+			if err == nil {
+				t.Fatalf("期望登录失败，实际登录为用户 %d", user.ID)
+			}
+			if !strings.Contains(err.Error(), "ID令牌校验失败") {
+				t.Errorf("err = %v，期望ID令牌校验失败", err)
+			}
+		})
+	}
+}
+
+func TestOIDCLinkExistingUserRequiresVerifiedEmail(t *testing.T) {
+	stub := setupOIDC(t)
+	admin := createTestUser(t, "local-admin", "admin@example.com", models.RoleSuperAdmin)
+
+	// 身份提供方未返回email_verified时不能按邮箱接管已有账号
+	stub.ModifyClaims = func(claims jwt.MapClaims) { delete(claims, "email_verified") }
+	if _, err := loginOIDC(t, "admin@example.com"); !errors.Is(err, ErrOIDCEmailUnverified) {
+		t.Fatalf("err = %v，期望 %v", err, ErrOIDCEmailUnverified)
+	}
+	stub.ModifyClaims = func(claims jwt.MapClaims) { claims["email_verified"] = false }
+	if _, err := loginOIDC(t, "admin@example.com"); !errors.Is(err, ErrOIDCEmailUnverified) {
+		t.Fatalf("err = %v，期望 %v", err, ErrOIDCEmailUnverified)
+	}
+
+	var reloaded models.User
+	config.DB.First(&reloaded, admin.ID)
+	if reloaded.OIDCSubject != nil {
+		t.Fatalf("未验证的邮箱关联了已有账号")
+	}
+
+	// 部分身份提供方以字符串返回email_verified
+	stub.ModifyClaims = func(claims jwt.MapClaims) { claims["email_verified"] = "true" }
+	user, err := loginOIDC(t, "admin@example.com")
+	if err != nil {
+		t.Fatalf("登录失败: %v", err)
+	}
+	if user.ID != admin.ID {
+		t.Errorf("关联到用户 %d，期望 %d", user.ID, admin.ID)
+	}
+}
+
+func TestOIDCSyncsRoleFromGroups(t *testing.T) {
+	stub := setupOIDC(t)
+	stub.Groups = []string{"ai-editors"}
+	user, err := loginOIDC(t, "sync@example.com")
+	if err != nil {
+		t.Fatalf("登录失败: %v", err)
+	}
+
+	stub.Groups = []string{"ai-editors", "ai-admins"}
+	if _, err := loginOIDC(t, "sync@example.com"); err != nil {
+		t.Fatalf("登录失败: %v", err)
+	}
+	var reloaded models.User
+	config.DB.First(&reloaded, user.ID)
+	if reloaded.Role != models.RoleSuperAdmin {
+		t.Errorf("角色 = %d，期望同步为 %d", reloaded.Role, models.RoleSuperAdmin)
+	}
+
+	// 未匹配任何用户组时保留当前角色
+	stub.Groups = []string{"unknown"}
+	if _, err := loginOIDC(t, "sync@example.com"); err != nil {
+		t.Fatalf("登录失败: %v", err)
+	}
+	config.DB.First(&reloaded, user.ID)
+	if reloaded.Role != models.RoleSuperAdmin {
+		t.Errorf("角色 = %d，期望保持 %d", reloaded.Role, models.RoleSuperAdmin)
+	}
+}
+
+func TestMapOIDCGroupsToRole(t *testing.T) {
+	setupOIDC(t)
+
+	tests := []struct {
+		name   string
+		groups []string
+		role   uint8
+		mapped bool
+	}{
+		{name: "无用户组", groups: nil, mapped: false},
+		{name: "未配置的用户组", groups: []string{"unknown"}, mapped: false},
+		{name: "无效角色被忽略", groups: []string{"broken"}, mapped: false},
+		{name: "单个用户组", groups: []string{"ai-editors"}, role: models.RoleEditor, mapped: true},
+		{name: "取权限最高的角色", groups: []string{"ai-editors", "unknown", "ai-admins"}, role: models.RoleSuperAdmin, mapped: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			role, mapped := MapOIDCGroupsToRole(tt.groups)
+			if mapped != tt.mapped || role != tt.role {
+				t.Errorf("MapOIDCGroupsToRole(%v) = (%d, %v)，期望 (%d, %v)", tt.groups, role, mapped, tt.role, tt.mapped)
+			}
+		})
+	}
+}
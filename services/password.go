@@ -0,0 +1,194 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"ai-assistant-backend/config"
+	"ai-assistant-backend/models"
+	"ai-assistant-backend/utils"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	passwordResetTokenPurpose = "password_reset"
+	defaultResetTokenMinutes  = 30
+	// passwordResetCooldown 同一用户两次发送重置邮件的最小间隔
+	passwordResetCooldown = time.Minute
+)
+
+var (
+	// ErrWrongPassword 当前密码错误
+	ErrWrongPassword = errors.New("当前密码错误")
+	// ErrSamePassword 新密码与当前密码相同
+	ErrSamePassword = errors.New("新密码不能与当前密码相同")
+	// ErrResetTokenInvalid 重置令牌无效、已使用或已过期
+	ErrResetTokenInvalid = errors.New("重置链接无效或已过期")
+)
+
+func passwordResetKey(tokenHash string) string {
+	return fmt.Sprintf("password_reset:%s", tokenHash)
+}
+
+// passwordResetUserKey 用户当前有效的重置令牌摘要，重新申请时旧令牌作废
+func passwordResetUserKey(userID uint) string {
+	return fmt.Sprintf("password_reset_user:%d", userID)
+}
+
+func passwordResetCooldownKey(userID uint) string {
+	return fmt.Sprintf("password_reset_cooldown:%d", userID)
+}
+
+// ResetTokenLifetime 重置密码链接有效时间
+func ResetTokenLifetime() time.Duration {
+	minutes := config.GlobalConfig.Security.ResetTokenMinutes
+	if minutes <= 0 {
+		minutes = defaultResetTokenMinutes
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// setPassword 校验密码策略后保存新密码
+func setPassword(user *models.User, password string) error {
+	if err := utils.ValidatePassword(password, user.Username); err != nil {
+		return err
+	}
+
+	hashedPassword, err := utils.HashPassword(password)
+	if err != nil {
+		return fmt.Errorf("密码加密失败: %v", err)
+	}
+	if err := config.DB.Model(user).Update("password", hashedPassword).Error; err != nil {
+		return fmt.Errorf("保存密码失败: %v", err)
+	}
+	return nil
+}
+
+// ChangePassword 校验当前密码后修改密码，并注销除keepSessionID以外的全部会话
+func ChangePassword(user *models.User, currentPassword, newPassword, keepSessionID string) error {
+	if !utils.CheckPassword(currentPassword, user.Password) {
+		return ErrWrongPassword
+	}
+	if currentPassword == newPassword {
+		return ErrSamePassword
+	}
+	if err := setPassword(user, newPassword); err != nil {
+		return err
+	}
+
+	if _, err := utils.RevokeUserSessions(user.ID, keepSessionID); err != nil {
+		log.Printf("[password] 修改密码后注销用户 %d 的其他会话失败: %v", user.ID, err)
+	}
+	return nil
+}
+
+// RequestPasswordReset 向邮箱对应的用户发送重置密码邮件。邮箱未注册或发送过于频繁时静默忽略，
+// 避免通过该接口探测已注册的邮箱
+func RequestPasswordReset(ctx context.Context, email string) error {
+	var user models.User
+	if err := config.DB.Where("email = ?", NormalizeEmail(email)).First(&user).Error; err != nil {
+		return nil
+	}
+
+	ok, err := config.RedisClient.SetNX(ctx, passwordResetCooldownKey(user.ID), 1, passwordResetCooldown).Result()
+	if err != nil {
+		return fmt.Errorf("写入重置记录失败: %v", err)
+	}
+	if !ok {
+		return nil
+	}
+
+	token, err := utils.GenerateSignedToken(passwordResetTokenPurpose)
+	if err != nil {
+		return fmt.Errorf("生成重置令牌失败: %v", err)
+	}
+	tokenHash := utils.HashToken(token)
+	lifetime := ResetTokenLifetime()
+
+	previous, err := config.RedisClient.GetSet(ctx, passwordResetUserKey(user.ID), tokenHash).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return fmt.Errorf("写入重置记录失败: %v", err)
+	}
+	pipe := config.RedisClient.TxPipeline()
+	if previous != "" {
+		pipe.Del(ctx, passwordResetKey(previous))
+	}
+	pipe.Expire(ctx, passwordResetUserKey(user.ID), lifetime)
+	pipe.Set(ctx, passwordResetKey(tokenHash), user.ID, lifetime)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("写入重置记录失败: %v", err)
+	}
+
+	return utils.SendMail(ctx, passwordResetMail(&user, token, lifetime))
+}
+
+// passwordResetMail 重置密码邮件，链接指向前端的重置密码页面
+func passwordResetMail(user *models.User, token string, lifetime time.Duration) utils.MailMessage {
+	link := strings.TrimRight(config.GlobalConfig.App.FrontendURL, "/") + "/reset-password?token=" + url.QueryEscape(token)
+	body := fmt.Sprintf("%s，你好：\n\n我们收到了重置账号密码的申请。打开以下链接设置新密码（%d分钟内有效，只能使用一次）：\n%s\n\n如果不是你本人操作，请忽略本邮件，你的密码不会改变。",
+		user.Username, int(lifetime.Minutes()), link)
+
+	return utils.MailMessage{
+		To:      user.Email,
+		Subject: "重置密码",
+		Body:    body,
+	}
+}
+
+// ResetPassword 使用重置令牌设置新密码，令牌只能使用一次。
+// 重置后注销该用户的全部会话并清除登录失败记录
+func ResetPassword(ctx context.Context, token, newPassword string) error {
+	if !utils.VerifySignedToken(passwordResetTokenPurpose, token) {
+		return ErrResetTokenInvalid
+	}
+	tokenHash := utils.HashToken(token)
+
+	value, err := config.RedisClient.Get(ctx, passwordResetKey(tokenHash)).Result()
+	if errors.Is(err, redis.Nil) {
+		return ErrResetTokenInvalid
+	}
+	if err != nil {
+		return fmt.Errorf("读取重置令牌失败: %v", err)
+	}
+	userID, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return ErrResetTokenInvalid
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, userID).Error; err != nil {
+		return ErrResetTokenInvalid
+	}
+
+	// 先校验新密码，不符合策略时令牌仍可继续使用
+	if err := utils.ValidatePassword(newPassword, user.Username); err != nil {
+		return err
+	}
+
+	// 删除成功才视为本次请求使用了令牌，保证并发请求中只有一个生效
+	deleted, err := config.RedisClient.Del(ctx, passwordResetKey(tokenHash)).Result()
+	if err != nil {
+		return fmt.Errorf("读取重置令牌失败: %v", err)
+	}
+	if deleted == 0 {
+		return ErrResetTokenInvalid
+	}
+	config.RedisClient.Del(ctx, passwordResetUserKey(user.ID))
+
+	if err := setPassword(&user, newPassword); err != nil {
+		return err
+	}
+
+	if _, err := utils.RevokeUserSessions(user.ID, ""); err != nil {
+		log.Printf("[password] 重置密码后注销用户 %d 的会话失败: %v", user.ID, err)
+	}
+	ResetLoginFailures(ctx, user.Username)
+	return nil
+}
@@ -0,0 +1,103 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"ai-assistant-backend/models"
+	"ai-assistant-backend/utils"
+)
+
+// defaultSystemPrompt 智能体未设置回答要求时使用的提示词
+const defaultSystemPrompt = "请使用简洁、友好的中文回答访客的问题。"
+
+// PromptPreview 智能体针对某个问题实际使用的模型参数与系统提示词
+type PromptPreview struct {
+	Model        string             `json:"model"`
+	Temperature  *float64           `json:"temperature"`
+	MaxTokens    int                `json:"max_tokens"`
+	SystemPrompt string             `json:"system_prompt"`
+	Documents    []DocumentCitation `json:"documents"`
+	FAQs         []FAQCitation      `json:"faqs"`
+	FAQMatch     *FAQCitation       `json:"faq_match"`     // 问题将直接命中的常见问答
+	DirectAnswer string             `json:"direct_answer"` // 不调用大模型时的回复
+}
+
+// BuildSystemPrompt 依次组装智能体身份、人设、回答要求与检索到的参考资料
+func BuildSystemPrompt(agent *models.Agent, retrieval *Retrieval) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "你是智能体「%s」。", agent.Name)
+	if persona := strings.TrimSpace(agent.Persona); persona != "" {
+		fmt.Fprintf(&b, "\n你的人设：%s", persona)
+	}
+
+	instruction := strings.TrimSpace(agent.SystemPrompt)
+	if instruction == "" {
+		instruction = defaultSystemPrompt
+	}
+	b.WriteString("\n")
+	b.WriteString(instruction)
+
+	if retrieval != nil && !retrieval.Empty() {
+		b.WriteString("\n\n")
+		b.WriteString(buildKnowledgePrompt(agent, retrieval))
+	}
+	return b.String()
+}
+
+// PreviewPrompt 预览智能体回答问题时的模型参数与完整系统提示词，不会调用大模型
+func PreviewPrompt(ctx context.Context, agent *models.Agent, question string) (*PromptPreview, error) {
+	_, model, err := utils.ResolveLLMModel(agent.Model)
+	if err != nil {
+		return nil, err
+	}
+	preview := &PromptPreview{
+		Model:       model,
+		Temperature: agent.Temperature,
+		MaxTokens:   agent.MaxTokens,
+		Documents:   []DocumentCitation{},
+		FAQs:        []FAQCitation{},
+	}
+
+	match, err := MatchFAQ(ctx, agent, question)
+	if err != nil {
+		return nil, err
+	}
+	if match != nil {
+		preview.FAQMatch = &FAQCitation{FAQID: match.FAQ.ID, Question: match.FAQ.Question, Score: match.Score}
+		preview.DirectAnswer = match.FAQ.Answer
+	}
+
+	retrieval, err := RetrieveKnowledge(ctx, agent, question)
+	if err != nil {
+		return nil, err
+	}
+	preview.Documents, preview.FAQs = retrieval.Citations()
+	preview.SystemPrompt = BuildSystemPrompt(agent, retrieval)
+	if match == nil && retrieval.Empty() {
+		preview.DirectAnswer = NoAnswerMessage(agent)
+	}
+	return preview, nil
+}
+
+// buildChatRequest 按智能体的模型设置组装大模型请求
+func buildChatRequest(agent *models.Agent, retrieval *Retrieval, history []models.Message, question string) (utils.LLMProvider, utils.ChatCompletionRequest, error) {
+	provider, model, err := utils.ResolveLLMModel(agent.Model)
+	if err != nil {
+		return nil, utils.ChatCompletionRequest{}, err
+	}
+
+	messages := []utils.LLMMessage{{Role: RoleSystem, Content: BuildSystemPrompt(agent, retrieval)}}
+	for _, message := range history {
+		messages = append(messages, utils.LLMMessage{Role: message.Role, Content: message.Content})
+	}
+	messages = append(messages, utils.LLMMessage{Role: RoleUser, Content: question})
+
+	return provider, utils.ChatCompletionRequest{
+		Model:       model,
+		Messages:    messages,
+		Temperature: agent.Temperature,
+		MaxTokens:   agent.MaxTokens,
+	}, nil
+}
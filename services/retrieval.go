@@ -0,0 +1,265 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"ai-assistant-backend/config"
+	"ai-assistant-backend/models"
+
+	"gorm.io/gorm"
+)
+
+// 智能体未配置检索参数时使用的默认值
+const (
+	defaultRetrievalTopK = 5
+	defaultNoAnswerMsg   = "抱歉，暂时没有找到相关的答案，您可以换个问法或联系人工客服。"
+)
+
+// RetrievedChunk 检索到的文档切片
+type RetrievedChunk struct {
+	Chunk        models.DocumentChunk
+	DocumentName string
+	Score        float64
+}
+
+// RetrievedFAQ 检索到的常见问答
+type RetrievedFAQ struct {
+	FAQ   models.FAQ
+	Score float64
+}
+
+// Retrieval 一次检索的结果，均按相似度降序排列
+type Retrieval struct {
+	Chunks []RetrievedChunk
+	FAQs   []RetrievedFAQ
+}
+
+// Empty 是否未检索到任何资料
+func (r *Retrieval) Empty() bool {
+	return len(r.Chunks) == 0 && len(r.FAQs) == 0
+}
+
+// Citations 将检索结果转换为回复中的引用
+func (r *Retrieval) Citations() ([]DocumentCitation, []FAQCitation) {
+	documents := make([]DocumentCitation, 0, len(r.Chunks))
+	for _, item := range r.Chunks {
+		documents = append(documents, DocumentCitation{
+			DocumentID:   item.Chunk.DocumentID,
+			DocumentName: item.DocumentName,
+			ChunkID:      item.Chunk.ID,
+			ChunkIndex:   item.Chunk.ChunkIndex,
+			Score:        item.Score,
+		})
+	}
+	faqs := make([]FAQCitation, 0, len(r.FAQs))
+	for _, item := range r.FAQs {
+		faqs = append(faqs, FAQCitation{
+			FAQID:    item.FAQ.ID,
+			Question: item.FAQ.Question,
+			Score:    item.Score,
+		})
+	}
+	return documents, faqs
+}
+
+// RetrievalTopK 智能体每类资料的最大检索条数
+func RetrievalTopK(agent *models.Agent) int {
+	if agent.RetrievalTopK > 0 {
+		return agent.RetrievalTopK
+	}
+	return defaultRetrievalTopK
+}
+
+// NoAnswerMessage 未检索到资料时回复访客的内容
+func NoAnswerMessage(agent *models.Agent) string {
+	if strings.TrimSpace(agent.NoAnswerMsg) != "" {
+		return agent.NoAnswerMsg
+	}
+	return defaultNoAnswerMsg
+}
+
+// RetrieveKnowledge 从智能体知识范围内的文档切片与常见问答中检索与问题相关的资料，
+// 相似度低于智能体设置的阈值的结果会被过滤
+func RetrieveKnowledge(ctx context.Context, agent *models.Agent, question string) (*Retrieval, error) {
+	topK := RetrievalTopK(agent)
+	retrieval := &Retrieval{}
+
+	faqs, err := retrieveFAQs(agent, question, topK, agent.RetrievalMinScore)
+	if err != nil {
+		return nil, err
+	}
+	retrieval.FAQs = faqs
+
+	// 知识范围限定了文档分类但其中没有文档时无需检索
+	scopedDocumentIDs, err := knowledgeScopeDocumentIDs(agent)
+	if err != nil {
+		return nil, fmt.Errorf("检索文档失败: %v", err)
+	}
+	if len(agent.DocumentCategoryIDs) > 0 && len(scopedDocumentIDs) == 0 {
+		return retrieval, nil
+	}
+
+	// 向量化失败时仍可使用常见问答回答
+	vectors, model, err := EmbedTexts(ctx, []string{question})
+	if err != nil {
+		log.Printf("[retrieval] 智能体 %d 问题向量化失败: %v", agent.ID, err)
+		return retrieval, nil
+	}
+	// 只检索当前向量模型生成的切片，更换模型后尚未重新向量化的切片不参与检索
+	results, err := GetVectorStore().Search(ctx, VectorQuery{
+		AgentID:        agent.ID,
+		DocumentIDs:    scopedDocumentIDs,
+		Vector:         vectors[0],
+		TopK:           topK,
+		MinScore:       agent.RetrievalMinScore,
+		EmbeddingModel: model,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("检索文档失败: %v", err)
+	}
+	if len(results) == 0 {
+		return retrieval, nil
+	}
+
+	documentIDs := make([]uint, 0, len(results))
+	for _, result := range results {
+		documentIDs = append(documentIDs, result.Chunk.DocumentID)
+	}
+	var documents []models.Document
+	if err := config.DB.Select("id", "name").Where("id IN ?", documentIDs).Find(&documents).Error; err != nil {
+		return nil, fmt.Errorf("检索文档失败: %v", err)
+	}
+	names := make(map[uint]string, len(documents))
+	for _, document := range documents {
+		names[document.ID] = document.Name
+	}
+
+	for _, result := range results {
+		name, exists := names[result.Chunk.DocumentID]
+		if !exists {
+			// 文档已删除
+			continue
+		}
+		retrieval.Chunks = append(retrieval.Chunks, RetrievedChunk{
+			Chunk:        result.Chunk,
+			DocumentName: name,
+			Score:        result.Score,
+		})
+	}
+	return retrieval, nil
+}
+
+// knowledgeScopeDocumentIDs 知识范围内的文档ID，未限定文档分类时返回nil表示不限制
+func knowledgeScopeDocumentIDs(agent *models.Agent) ([]uint, error) {
+	if len(agent.DocumentCategoryIDs) == 0 {
+		return nil, nil
+	}
+	ids := []uint{}
+	err := config.DB.Model(&models.Document{}).
+		Where("agent_id = ? AND category_id IN ?", agent.ID, agent.DocumentCategoryIDs).
+		Pluck("id", &ids).Error
+	return ids, err
+}
+
+// knowledgeScopeFAQs 按智能体的知识范围筛选常见问答
+func knowledgeScopeFAQs(agent *models.Agent) *gorm.DB {
+	query := config.DB.Where("agent_id = ?", agent.ID)
+	if len(agent.FAQCategoryIDs) > 0 {
+		query = query.Where("category_id IN ?", agent.FAQCategoryIDs)
+	}
+	return query
+}
+
+// retrieveFAQs 按问题文本相似度检索知识范围内的常见问答
+func retrieveFAQs(agent *models.Agent, question string, topK int, minScore float64) ([]RetrievedFAQ, error) {
+	normalized := normalizeQuestion(question)
+	if normalized == "" {
+		return nil, nil
+	}
+
+	var faqs []models.FAQ
+	if err := knowledgeScopeFAQs(agent).Omit("embedding").Find(&faqs).Error; err != nil {
+		return nil, fmt.Errorf("检索常见问答失败: %v", err)
+	}
+
+	var results []RetrievedFAQ
+	for _, faq := range faqs {
+		score := textSimilarity(normalized, normalizeQuestion(faq.Question))
+		if score > 0 && score >= minScore {
+			results = append(results, RetrievedFAQ{FAQ: faq, Score: score})
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Score > results[j].Score })
+	if len(results) > topK {
+		results = results[:topK]
+	}
+	return results, nil
+}
+
+// textSimilarity 基于字符二元组的Dice系数计算两段文本的相似度，取值0~1
+func textSimilarity(a, b string) float64 {
+	if a == b {
+		if a == "" {
+			return 0
+		}
+		return 1
+	}
+
+	gramsA, gramsB := bigrams(a), bigrams(b)
+	total := 0
+	for _, count := range gramsA {
+		total += count
+	}
+	for _, count := range gramsB {
+		total += count
+	}
+	if total == 0 {
+		return 0
+	}
+
+	common := 0
+	for gram, count := range gramsA {
+		if other := gramsB[gram]; other < count {
+			common += other
+		} else {
+			common += count
+		}
+	}
+	return float64(2*common) / float64(total)
+}
+
+// bigrams 统计文本中的字符二元组，单字文本视为一个元组
+func bigrams(text string) map[string]int {
+	runes := []rune(text)
+	grams := map[string]int{}
+	if len(runes) == 1 {
+		grams[text]++
+		return grams
+	}
+	for i := 0; i+1 < len(runes); i++ {
+		grams[string(runes[i:i+2])]++
+	}
+	return grams
+}
+
+// buildKnowledgePrompt 将检索到的资料整理为系统提示词中的参考资料
+func buildKnowledgePrompt(agent *models.Agent, retrieval *Retrieval) string {
+	var b strings.Builder
+	b.WriteString("请仅根据以下参考资料回答访客的问题，不要编造参考资料之外的内容。")
+	fmt.Fprintf(&b, "如果参考资料中没有相关内容，请直接回复：%s\n\n参考资料：\n", NoAnswerMessage(agent))
+
+	index := 1
+	for _, item := range retrieval.FAQs {
+		fmt.Fprintf(&b, "[%d] 常见问答\n问：%s\n答：%s\n\n", index, item.FAQ.Question, item.FAQ.Answer)
+		index++
+	}
+	for _, item := range retrieval.Chunks {
+		fmt.Fprintf(&b, "[%d] 文档《%s》\n%s\n\n", index, item.DocumentName, item.Chunk.Content)
+		index++
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
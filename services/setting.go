@@ -0,0 +1,38 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+
+	"ai-assistant-backend/config"
+	"ai-assistant-backend/models"
+
+	"gorm.io/gorm"
+)
+
+// 系统设置键
+const (
+	SettingTwoFactorRoles = "two_factor_roles"
+)
+
+// GetSetting 读取系统设置并解析到value，设置不存在时返回false
+func GetSetting(key string, value interface{}) (bool, error) {
+	var setting models.Setting
+	err := config.DB.Where("`key` = ?", key).First(&setting).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, json.Unmarshal([]byte(setting.Value), value)
+}
+
+// SaveSetting 保存系统设置
+func SaveSetting(key string, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return config.DB.Save(&models.Setting{Key: key, Value: string(data)}).Error
+}
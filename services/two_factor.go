@@ -0,0 +1,333 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"ai-assistant-backend/config"
+	"ai-assistant-backend/models"
+	"ai-assistant-backend/utils"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	recoveryCodeCount = 10
+	// totpSetupTTL 生成密钥后需在该时间内完成确认
+	totpSetupTTL = 10 * time.Minute
+
+	loginChallengePurpose     = "login_challenge"
+	loginChallengeTTL         = 5 * time.Minute
+	loginChallengeMaxAttempts = 5
+)
+
+var (
+	// ErrTwoFactorEnabled 已启用双因素认证
+	ErrTwoFactorEnabled = errors.New("已启用双因素认证")
+	// ErrTwoFactorNotEnabled 未启用双因素认证
+	ErrTwoFactorNotEnabled = errors.New("未启用双因素认证")
+	// ErrTwoFactorSetupExpired 密钥已过期或未生成
+	ErrTwoFactorSetupExpired = errors.New("密钥已过期，请重新获取")
+	// ErrInvalidTwoFactorCode 验证码或恢复码错误
+	ErrInvalidTwoFactorCode = errors.New("验证码错误")
+	// ErrTwoFactorRequired 当前角色要求启用双因素认证
+	ErrTwoFactorRequired = errors.New("当前角色要求启用双因素认证")
+	// ErrLoginChallengeInvalid 登录验证令牌无效、已过期或尝试次数过多
+	ErrLoginChallengeInvalid = errors.New("登录验证已失效，请重新登录")
+)
+
+// TwoFactorRequiredRoles 要求启用双因素认证的角色，管理员未设置时使用配置文件中的默认值
+func TwoFactorRequiredRoles() []uint8 {
+	var roles []int
+	found, err := GetSetting(SettingTwoFactorRoles, &roles)
+	if err != nil || !found {
+		return config.GlobalConfig.Security.TwoFactorRoles
+	}
+
+	result := make([]uint8, 0, len(roles))
+	for _, role := range roles {
+		result = append(result, uint8(role))
+	}
+	return result
+}
+
+// SetTwoFactorRequiredRoles 设置要求启用双因素认证的角色
+func SetTwoFactorRequiredRoles(roles []uint8) error {
+	// []uint8会被JSON编码为Base64字符串，按整数数组保存
+	values := make([]int, 0, len(roles))
+	for _, role := range roles {
+		values = append(values, int(role))
+	}
+	return SaveSetting(SettingTwoFactorRoles, values)
+}
+
+// TwoFactorRequired 该角色是否要求启用双因素认证
+func TwoFactorRequired(role uint8) bool {
+	for _, item := range TwoFactorRequiredRoles() {
+		if item == role {
+			return true
+		}
+	}
+	return false
+}
+
+func totpSetupKey(userID uint) string {
+	return fmt.Sprintf("totp_setup:%d", userID)
+}
+
+// BeginTOTPSetup 生成待确认的TOTP密钥，确认前不会写入用户信息
+func BeginTOTPSetup(ctx context.Context, user *models.User) (string, string, error) {
+	if user.TOTPEnabled {
+		return "", "", ErrTwoFactorEnabled
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return "", "", fmt.Errorf("生成密钥失败: %v", err)
+	}
+	if err := config.RedisClient.Set(ctx, totpSetupKey(user.ID), secret, totpSetupTTL).Err(); err != nil {
+		return "", "", fmt.Errorf("保存密钥失败: %v", err)
+	}
+
+	account := user.Email
+	if account == "" {
+		account = user.Username
+	}
+	return secret, utils.TOTPURI(config.GlobalConfig.App.Name, account, secret), nil
+}
+
+// ConfirmTOTPSetup 使用验证器应用生成的验证码确认密钥并启用双因素认证，返回恢复码（只展示一次）
+func ConfirmTOTPSetup(ctx context.Context, user *models.User, code string) ([]string, error) {
+	if user.TOTPEnabled {
+		return nil, ErrTwoFactorEnabled
+	}
+
+	secret, err := config.RedisClient.Get(ctx, totpSetupKey(user.ID)).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrTwoFactorSetupExpired
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取密钥失败: %v", err)
+	}
+
+	step, ok := utils.ValidateTOTP(secret, code, time.Now())
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	err = config.DB.Model(user).Updates(map[string]interface{}{
+		"totp_enabled":    true,
+		"totp_enabled_at": now,
+		"totp_secret":     secret,
+		"totp_last_step":  step,
+		"recovery_codes":  hashes,
+	}).Error
+	if err != nil {
+		return nil, fmt.Errorf("启用双因素认证失败: %v", err)
+	}
+
+	config.RedisClient.Del(ctx, totpSetupKey(user.ID))
+	return codes, nil
+}
+
+// DisableTwoFactor 关闭双因素认证并清除密钥与恢复码
+func DisableTwoFactor(user *models.User) error {
+	return config.DB.Model(user).Updates(map[string]interface{}{
+		"totp_enabled":    false,
+		"totp_enabled_at": nil,
+		"totp_secret":     "",
+		"totp_last_step":  0,
+		"recovery_codes":  "",
+	}).Error
+}
+
+// RegenerateRecoveryCodes 重新生成恢复码，之前的恢复码全部作废
+func RegenerateRecoveryCodes(user *models.User) ([]string, error) {
+	if !user.TOTPEnabled {
+		return nil, ErrTwoFactorNotEnabled
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := config.DB.Model(user).Update("recovery_codes", hashes).Error; err != nil {
+		return nil, fmt.Errorf("保存恢复码失败: %v", err)
+	}
+	return codes, nil
+}
+
+// RecoveryCodesRemaining 剩余可用的恢复码数量
+func RecoveryCodesRemaining(user *models.User) int {
+	var hashes []string
+	if user.RecoveryCodes == "" || json.Unmarshal([]byte(user.RecoveryCodes), &hashes) != nil {
+		return 0
+	}
+	return len(hashes)
+}
+
+// newRecoveryCodes 生成恢复码及其摘要的JSON数组
+func newRecoveryCodes() ([]string, string, error) {
+	codes, err := utils.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, "", fmt.Errorf("生成恢复码失败: %v", err)
+	}
+
+	hashes := make([]string, 0, len(codes))
+	for _, code := range codes {
+		hashes = append(hashes, utils.HashToken(utils.NormalizeRecoveryCode(code)))
+	}
+	data, err := json.Marshal(hashes)
+	if err != nil {
+		return nil, "", err
+	}
+	return codes, string(data), nil
+}
+
+// VerifySecondFactor 校验TOTP验证码或恢复码。验证码不能重复使用，恢复码使用后作废
+func VerifySecondFactor(user *models.User, code string) error {
+	if !user.TOTPEnabled {
+		return ErrTwoFactorNotEnabled
+	}
+
+	if step, ok := utils.ValidateTOTP(user.TOTPSecret, code, time.Now()); ok {
+		// 条件更新保证同一时间步的验证码只能使用一次
+		result := config.DB.Model(&models.User{}).
+			Where("id = ? AND totp_last_step < ?", user.ID, step).
+			Update("totp_last_step", step)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInvalidTwoFactorCode
+		}
+		user.TOTPLastStep = step
+		return nil
+	}
+
+	return useRecoveryCode(user, code)
+}
+
+// useRecoveryCode 校验并作废恢复码
+func useRecoveryCode(user *models.User, code string) error {
+	var hashes []string
+	if user.RecoveryCodes == "" || json.Unmarshal([]byte(user.RecoveryCodes), &hashes) != nil {
+		return ErrInvalidTwoFactorCode
+	}
+
+	target := utils.HashToken(utils.NormalizeRecoveryCode(code))
+	for i, hash := range hashes {
+		if hash != target {
+			continue
+		}
+
+		remaining, err := json.Marshal(append(hashes[:i:i], hashes[i+1:]...))
+		if err != nil {
+			return err
+		}
+		// 以原值为条件更新，避免并发请求重复使用同一恢复码
+		result := config.DB.Model(&models.User{}).
+			Where("id = ? AND recovery_codes = ?", user.ID, user.RecoveryCodes).
+			Update("recovery_codes", string(remaining))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInvalidTwoFactorCode
+		}
+		user.RecoveryCodes = string(remaining)
+		return nil
+	}
+	return ErrInvalidTwoFactorCode
+}
+
+func loginChallengeKey(tokenHash string) string {
+	return fmt.Sprintf("login_challenge:%s", tokenHash)
+}
+
+func loginChallengeAttemptsKey(tokenHash string) string {
+	return fmt.Sprintf("login_challenge_attempts:%s", tokenHash)
+}
+
+// CreateLoginChallenge 密码验证通过后创建第二步验证令牌，凭该令牌与验证码完成登录
+func CreateLoginChallenge(ctx context.Context, user *models.User) (string, error) {
+	token, err := utils.GenerateSignedToken(loginChallengePurpose)
+	if err != nil {
+		return "", fmt.Errorf("生成登录验证令牌失败: %v", err)
+	}
+	if err := config.RedisClient.Set(ctx, loginChallengeKey(utils.HashToken(token)), user.ID, loginChallengeTTL).Err(); err != nil {
+		return "", fmt.Errorf("保存登录验证令牌失败: %v", err)
+	}
+	return token, nil
+}
+
+// LoginChallengeUser 查询登录验证令牌对应的用户，不消耗验证次数
+func LoginChallengeUser(ctx context.Context, token string) (*models.User, error) {
+	if !utils.VerifySignedToken(loginChallengePurpose, token) {
+		return nil, ErrLoginChallengeInvalid
+	}
+
+	value, err := config.RedisClient.Get(ctx, loginChallengeKey(utils.HashToken(token))).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrLoginChallengeInvalid
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取登录验证令牌失败: %v", err)
+	}
+	userID, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return nil, ErrLoginChallengeInvalid
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, userID).Error; err != nil {
+		return nil, ErrLoginChallengeInvalid
+	}
+	return &user, nil
+}
+
+// CompleteLoginChallenge 校验第二步验证码，成功后令牌作废并返回登录用户。
+// 验证码错误时返回ErrInvalidTwoFactorCode及对应用户，每个令牌最多尝试loginChallengeMaxAttempts次
+func CompleteLoginChallenge(ctx context.Context, token, code string) (*models.User, error) {
+	user, err := LoginChallengeUser(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	tokenHash := utils.HashToken(token)
+
+	attempts, err := config.RedisClient.Incr(ctx, loginChallengeAttemptsKey(tokenHash)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("记录验证次数失败: %v", err)
+	}
+	if attempts == 1 {
+		config.RedisClient.Expire(ctx, loginChallengeAttemptsKey(tokenHash), loginChallengeTTL)
+	}
+	if attempts > loginChallengeMaxAttempts {
+		config.RedisClient.Del(ctx, loginChallengeKey(tokenHash), loginChallengeAttemptsKey(tokenHash))
+		return nil, ErrLoginChallengeInvalid
+	}
+
+	if err := VerifySecondFactor(user, code); err != nil {
+		if errors.Is(err, ErrTwoFactorNotEnabled) {
+			return nil, ErrLoginChallengeInvalid
+		}
+		return user, err
+	}
+
+	// 删除成功才视为本次请求使用了令牌，保证并发请求中只有一个生效
+	deleted, err := config.RedisClient.Del(ctx, loginChallengeKey(tokenHash)).Result()
+	if err != nil || deleted == 0 {
+		return nil, ErrLoginChallengeInvalid
+	}
+	config.RedisClient.Del(ctx, loginChallengeAttemptsKey(tokenHash))
+	return user, nil
+}
@@ -0,0 +1,117 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"ai-assistant-backend/config"
+	"ai-assistant-backend/models"
+	"ai-assistant-backend/utils"
+)
+
+// enableTestTwoFactor 为用户完成双因素认证的启用流程，返回恢复码
+func enableTestTwoFactor(t *testing.T, user *models.User) []string {
+	t.Helper()
+	ctx := context.Background()
+
+	secret, _, err := BeginTOTPSetup(ctx, user)
+	if err != nil {
+		t.Fatalf("生成密钥失败: %v", err)
+	}
+	code, err := utils.TOTPCode(secret, utils.TOTPStep(time.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	codes, err := ConfirmTOTPSetup(ctx, user, code)
+	if err != nil {
+		t.Fatalf("启用双因素认证失败: %v", err)
+	}
+	if err := config.DB.First(user, user.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	return codes
+}
+
+func TestVerifySecondFactorRejectsReplay(t *testing.T) {
+	user := createTestUser(t, "totp-replay", "totp-replay@example.com", models.RoleOwner)
+	enableTestTwoFactor(t, user)
+
+	// 启用时使用的验证码已记录在totp_last_step中，不能再用于登录
+	step := utils.TOTPStep(time.Now())
+	if user.TOTPLastStep < step-1 {
+		t.Fatalf("totp_last_step = %d，期望记录启用时的时间步", user.TOTPLastStep)
+	}
+	used, _ := utils.TOTPCode(user.TOTPSecret, user.TOTPLastStep)
+	if err := VerifySecondFactor(user, used); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Fatalf("重放启用时的验证码: err = %v，期望 %v", err, ErrInvalidTwoFactorCode)
+	}
+
+	// 下一个时间步的验证码在允许偏差内，只能使用一次
+	config.DB.Model(user).Update("totp_last_step", step-1)
+	user.TOTPLastStep = step - 1
+	next, _ := utils.TOTPCode(user.TOTPSecret, step+1)
+	if err := VerifySecondFactor(user, next); err != nil {
+		t.Fatalf("验证失败: %v", err)
+	}
+	if user.TOTPLastStep != step+1 {
+		t.Errorf("totp_last_step = %d，期望 %d", user.TOTPLastStep, step+1)
+	}
+	if err := VerifySecondFactor(user, next); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Errorf("重放验证码: err = %v，期望 %v", err, ErrInvalidTwoFactorCode)
+	}
+
+	// 较早时间步的验证码同样被拒绝，即使使用未刷新的用户信息
+	var stale models.User
+	config.DB.First(&stale, user.ID)
+	stale.TOTPLastStep = 0
+	current, _ := utils.TOTPCode(user.TOTPSecret, step)
+	if err := VerifySecondFactor(&stale, current); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Errorf("较早的验证码: err = %v，期望 %v", err, ErrInvalidTwoFactorCode)
+	}
+}
+
+func TestRecoveryCodesSingleUse(t *testing.T) {
+	user := createTestUser(t, "totp-recovery", "totp-recovery@example.com", models.RoleOwner)
+	codes := enableTestTwoFactor(t, user)
+	if len(codes) != recoveryCodeCount || RecoveryCodesRemaining(user) != recoveryCodeCount {
+		t.Fatalf("恢复码数量 = %d/%d，期望 %d", len(codes), RecoveryCodesRemaining(user), recoveryCodeCount)
+	}
+
+	var stale models.User
+	config.DB.First(&stale, user.ID)
+
+	// 恢复码不区分大小写与分隔符
+	if err := VerifySecondFactor(user, strings.ToUpper(strings.ReplaceAll(codes[0], "-", ""))); err != nil {
+		t.Fatalf("使用恢复码失败: %v", err)
+	}
+	if RecoveryCodesRemaining(user) != recoveryCodeCount-1 {
+		t.Errorf("剩余恢复码 = %d，期望 %d", RecoveryCodesRemaining(user), recoveryCodeCount-1)
+	}
+	if err := VerifySecondFactor(user, codes[0]); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Errorf("重复使用恢复码: err = %v，期望 %v", err, ErrInvalidTwoFactorCode)
+	}
+	// 并发请求持有使用前的用户信息时同样不能再次使用
+	if err := VerifySecondFactor(&stale, codes[0]); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Errorf("并发使用恢复码: err = %v，期望 %v", err, ErrInvalidTwoFactorCode)
+	}
+
+	if err := VerifySecondFactor(user, codes[1]); err != nil {
+		t.Errorf("使用其他恢复码失败: %v", err)
+	}
+
+	// 重新生成后旧恢复码全部作废
+	regenerated, err := RegenerateRecoveryCodes(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.DB.First(user, user.ID)
+	if err := VerifySecondFactor(user, codes[2]); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Errorf("使用作废的恢复码: err = %v，期望 %v", err, ErrInvalidTwoFactorCode)
+	}
+	if err := VerifySecondFactor(user, regenerated[0]); err != nil {
+		t.Errorf("使用新恢复码失败: %v", err)
+	}
+}
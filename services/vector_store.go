@@ -0,0 +1,164 @@
+package services
+
+import (
+	"context"
+	"math"
+	"sort"
+
+	"ai-assistant-backend/config"
+	"ai-assistant-backend/models"
+
+	"gorm.io/gorm"
+)
+
+// VectorQuery 向量检索条件
+type VectorQuery struct {
+	AgentID     uint
+	DocumentIDs []uint // 限定检索的文档，为空时检索智能体的全部文档
+	Vector      []float32
+	TopK        int
+	MinScore    float64 // 余弦相似度下限
+	// EmbeddingModel 只检索该向量模型生成的切片，不同模型的向量不可比较；为空时不限制
+	EmbeddingModel string
+}
+
+// VectorSearchResult 检索命中的切片及相似度
+type VectorSearchResult struct {
+	Chunk models.DocumentChunk
+	Score float64
+}
+
+// VectorStore 切片向量存储，默认实现基于数据库，可替换为外部向量数据库
+type VectorStore interface {
+	// ReplaceDocumentChunks 用新的切片替换文档已有的全部切片
+	ReplaceDocumentChunks(ctx context.Context, documentID uint, chunks []models.DocumentChunk) error
+	// DeleteDocumentChunks 删除文档的全部切片
+	DeleteDocumentChunks(ctx context.Context, documentID uint) error
+	// Search 按余弦相似度检索智能体知识库中最相近的切片，结果按相似度降序排列
+	Search(ctx context.Context, query VectorQuery) ([]VectorSearchResult, error)
+}
+
+var vectorStore VectorStore = &DBVectorStore{}
+
+// GetVectorStore 获取当前使用的向量存储
+func GetVectorStore() VectorStore {
+	return vectorStore
+}
+
+// SetVectorStore 替换向量存储实现
+func SetVectorStore(store VectorStore) {
+	vectorStore = store
+}
+
+// DBVectorStore 将向量保存在document_chunks表中，检索时在内存中计算余弦相似度
+type DBVectorStore struct{}
+
+// searchBatchSize 检索时每批加载的切片数量
+const searchBatchSize = 500
+
+// ReplaceDocumentChunks 实现 VectorStore
+func (s *DBVectorStore) ReplaceDocumentChunks(ctx context.Context, documentID uint, chunks []models.DocumentChunk) error {
+	return config.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("document_id = ?", documentID).Delete(&models.DocumentChunk{}).Error; err != nil {
+			return err
+		}
+		if len(chunks) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(chunks, 100).Error
+	})
+}
+
+// DeleteDocumentChunks 实现 VectorStore
+func (s *DBVectorStore) DeleteDocumentChunks(ctx context.Context, documentID uint) error {
+	return config.DB.WithContext(ctx).Where("document_id = ?", documentID).Delete(&models.DocumentChunk{}).Error
+}
+
+// Search 实现 VectorStore
+func (s *DBVectorStore) Search(ctx context.Context, query VectorQuery) ([]VectorSearchResult, error) {
+	if query.TopK <= 0 || len(query.Vector) == 0 {
+		return nil, nil
+	}
+
+	type scoredChunk struct {
+		id    uint
+		score float64
+	}
+	var top []scoredChunk
+
+	// 分批加载向量，仅保留得分最高的TopK个切片
+	var batch []models.DocumentChunk
+	db := config.DB.WithContext(ctx).Select("id", "embedding").Where("agent_id = ?", query.AgentID)
+	if len(query.DocumentIDs) > 0 {
+		db = db.Where("document_id IN ?", query.DocumentIDs)
+	}
+	if query.EmbeddingModel != "" {
+		db = db.Where("embedding_model = ?", query.EmbeddingModel)
+	}
+	err := db.
+		FindInBatches(&batch, searchBatchSize, func(tx *gorm.DB, _ int) error {
+			for _, chunk := range batch {
+				score, ok := CosineSimilarity(query.Vector, chunk.Embedding)
+				if !ok || score < query.MinScore {
+					continue
+				}
+				if len(top) == query.TopK && score <= top[len(top)-1].score {
+					continue
+				}
+				position := sort.Search(len(top), func(i int) bool { return top[i].score < score })
+				top = append(top, scoredChunk{})
+				copy(top[position+1:], top[position:])
+				top[position] = scoredChunk{id: chunk.ID, score: score}
+				if len(top) > query.TopK {
+					top = top[:query.TopK]
+				}
+			}
+			return ctx.Err()
+		}).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(top) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]uint, len(top))
+	for i, item := range top {
+		ids[i] = item.id
+	}
+	var chunks []models.DocumentChunk
+	if err := config.DB.WithContext(ctx).Omit("embedding").Where("id IN ?", ids).Find(&chunks).Error; err != nil {
+		return nil, err
+	}
+	chunkMap := make(map[uint]models.DocumentChunk, len(chunks))
+	for _, chunk := range chunks {
+		chunkMap[chunk.ID] = chunk
+	}
+
+	results := make([]VectorSearchResult, 0, len(top))
+	for _, item := range top {
+		if chunk, exists := chunkMap[item.id]; exists {
+			results = append(results, VectorSearchResult{Chunk: chunk, Score: item.score})
+		}
+	}
+	return results, nil
+}
+
+// CosineSimilarity 计算两个向量的余弦相似度，维度不一致或存在零向量时返回false
+func CosineSimilarity(a, b []float32) (float64, bool) {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0, false
+	}
+
+	var dot, normA, normB float64
+	for i := range a {
+		x, y := float64(a[i]), float64(b[i])
+		dot += x * y
+		normA += x * x
+		normB += y * y
+	}
+	if normA == 0 || normB == 0 {
+		return 0, false
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB)), true
+}
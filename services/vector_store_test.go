@@ -0,0 +1,104 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"ai-assistant-backend/config"
+	"ai-assistant-backend/models"
+)
+
+func TestDBVectorStoreSearchFiltersEmbeddingModel(t *testing.T) {
+	agent := &models.Agent{AppID: "vector-model", Name: "vector-model"}
+	if err := config.DB.Create(agent).Error; err != nil {
+		t.Fatal(err)
+	}
+	chunks := []models.DocumentChunk{
+		{AgentID: agent.ID, DocumentID: 9001, Content: "当前模型", Embedding: models.Vector{1, 0, 0}, EmbeddingModel: "mock-embedding"},
+		{AgentID: agent.ID, DocumentID: 9002, Content: "旧模型", Embedding: models.Vector{1, 0, 0}, EmbeddingModel: "old-embedding"},
+		{AgentID: agent.ID, DocumentID: 9003, Content: "其他智能体", Embedding: models.Vector{1, 0, 0}, EmbeddingModel: "mock-embedding"},
+	}
+	chunks[2].AgentID = agent.ID + 1000
+	if err := config.DB.Create(&chunks).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	store := &DBVectorStore{}
+	query := VectorQuery{AgentID: agent.ID, Vector: []float32{1, 0, 0}, TopK: 10, EmbeddingModel: "mock-embedding"}
+	results, err := store.Search(context.Background(), query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Chunk.DocumentID != 9001 {
+		t.Errorf("检索结果 = %+v，期望只有当前模型的切片", results)
+	}
+
+	// 未指定模型时不限制
+	query.EmbeddingModel = ""
+	results, err = store.Search(context.Background(), query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Errorf("检索到 %d 个切片，期望 2", len(results))
+	}
+}
+
+func TestRetrieveKnowledgeIgnoresStaleEmbeddings(t *testing.T) {
+	agent, document := createChatAgent(t, "vector-stale")
+	// 以其他向量模型生成、内容与问题完全一致的切片不应被检索到
+	stale := &models.Document{AgentID: agent.ID, Name: "旧模型.txt", Status: models.DocumentStatusReady}
+	config.DB.Create(stale)
+	vector, _ := EmbedText(context.Background(), testChatQuestion)
+	config.DB.Create(&models.DocumentChunk{AgentID: agent.ID, DocumentID: stale.ID, Content: testChatQuestion, Embedding: vector, EmbeddingModel: "old-embedding"})
+
+	retrieval, err := RetrieveKnowledge(context.Background(), agent, testChatQuestion)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(retrieval.Chunks) != 1 || retrieval.Chunks[0].Chunk.DocumentID != document.ID {
+		t.Errorf("检索结果 = %+v，期望只有当前模型的文档 %d", retrieval.Chunks, document.ID)
+	}
+}
+
+func TestReembedStaleDocuments(t *testing.T) {
+	agent := &models.Agent{AppID: "vector-reembed", Name: "vector-reembed"}
+	config.DB.Create(agent)
+	documents := map[string]*models.Document{}
+	for _, name := range []string{"current", "stale", "mixed", "failed"} {
+		status := models.DocumentStatusReady
+		if name == "failed" {
+			status = models.DocumentStatusFailed
+		}
+		document := &models.Document{AgentID: agent.ID, Name: name, Status: status}
+		config.DB.Create(document)
+		documents[name] = document
+	}
+	for name, embeddingModels := range map[string][]string{
+		"current": {"mock-embedding", "mock-embedding"},
+		"stale":   {"old-embedding"},
+		"mixed":   {"mock-embedding", "old-embedding"},
+		"failed":  {"old-embedding"},
+	} {
+		for _, model := range embeddingModels {
+			config.DB.Create(&models.DocumentChunk{AgentID: agent.ID, DocumentID: documents[name].ID, EmbeddingModel: model})
+		}
+	}
+
+	if count := reembedStaleDocuments(); count < 2 {
+		t.Errorf("重新向量化 %d 个文档，期望至少包含本测试的 2 个", count)
+	}
+	want := map[string]string{
+		"current": models.DocumentStatusReady,
+		"stale":   models.DocumentStatusPending,
+		"mixed":   models.DocumentStatusPending,
+		"failed":  models.DocumentStatusFailed,
+	}
+	for name, document := range documents {
+		var reloaded models.Document
+		config.DB.First(&reloaded, document.ID)
+		if reloaded.Status != want[name] {
+			t.Errorf("%s 状态 = %s，期望 %s", name, reloaded.Status, want[name])
+		}
+	}
+}
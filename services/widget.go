@@ -0,0 +1,53 @@
+package services
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"strings"
+
+	"ai-assistant-backend/config"
+)
+
+// WidgetEmbed 智能体的嵌入方式
+type WidgetEmbed struct {
+	Link      string `json:"link"`       // 托管对话页地址，可直接分享或嵌入iframe
+	ScriptURL string `json:"script_url"` // 对话窗口脚本地址
+	Snippet   string `json:"snippet"`    // 粘贴到客户网站</body>前的嵌入代码
+}
+
+// PublicBaseURL 后端对外访问地址，未配置时使用本机地址
+func PublicBaseURL() string {
+	if base := strings.TrimRight(config.GlobalConfig.App.PublicBaseURL, "/"); base != "" {
+		return base
+	}
+	port := config.GlobalConfig.Server.Port
+	if port == 0 {
+		port = 8080
+	}
+	return fmt.Sprintf("http://localhost:%d", port)
+}
+
+// PublicOrigin 后端对外访问地址的Origin，托管对话页发起的请求携带该来源
+func PublicOrigin() string {
+	parsed, err := url.Parse(PublicBaseURL())
+	if err != nil || parsed.Host == "" {
+		return ""
+	}
+	return parsed.Scheme + "://" + parsed.Host
+}
+
+// AgentChatLink 智能体托管对话页地址
+func AgentChatLink(appID string) string {
+	return PublicBaseURL() + "/chat/" + url.PathEscape(appID)
+}
+
+// AgentWidgetEmbed 生成智能体的对话页地址、脚本地址与嵌入代码
+func AgentWidgetEmbed(appID string) WidgetEmbed {
+	scriptURL := PublicBaseURL() + "/widget.js"
+	return WidgetEmbed{
+		Link:      AgentChatLink(appID),
+		ScriptURL: scriptURL,
+		Snippet:   fmt.Sprintf(`<script src="%s" data-app-id="%s" async></script>`, html.EscapeString(scriptURL), html.EscapeString(appID)),
+	}
+}
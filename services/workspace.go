@@ -0,0 +1,100 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"ai-assistant-backend/config"
+	"ai-assistant-backend/models"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrNotWorkspaceMember 用户不是工作空间成员
+	ErrNotWorkspaceMember = errors.New("不是该工作空间的成员")
+	// ErrNoWorkspace 用户没有可用的工作空间
+	ErrNoWorkspace = errors.New("没有可用的工作空间")
+	// ErrLastWorkspaceOwner 工作空间至少需要保留一名所有者
+	ErrLastWorkspaceOwner = errors.New("工作空间至少需要保留一名所有者")
+)
+
+// CreateWorkspace 创建工作空间，创建者成为所有者
+func CreateWorkspace(tx *gorm.DB, name string, ownerID uint) (*models.Workspace, error) {
+	workspace := &models.Workspace{Name: name, OwnerID: ownerID}
+	if err := tx.Create(workspace).Error; err != nil {
+		return nil, fmt.Errorf("创建工作空间失败: %v", err)
+	}
+
+	member := &models.WorkspaceMember{WorkspaceID: workspace.ID, UserID: ownerID, Role: models.RoleOwner}
+	if err := tx.Create(member).Error; err != nil {
+		return nil, fmt.Errorf("添加工作空间成员失败: %v", err)
+	}
+	return workspace, nil
+}
+
+// CreatePersonalWorkspace 为用户创建默认的个人工作空间
+func CreatePersonalWorkspace(tx *gorm.DB, user *models.User) (*models.Workspace, error) {
+	return CreateWorkspace(tx, user.Username+"的工作空间", user.ID)
+}
+
+// GetWorkspaceMember 获取用户在工作空间中的成员信息
+func GetWorkspaceMember(workspaceID, userID uint) (*models.WorkspaceMember, error) {
+	var member models.WorkspaceMember
+	if err := config.DB.Where("workspace_id = ? AND user_id = ?", workspaceID, userID).First(&member).Error; err != nil {
+		return nil, ErrNotWorkspaceMember
+	}
+	return &member, nil
+}
+
+// DefaultWorkspaceID 用户未指定工作空间时使用最早加入的工作空间
+func DefaultWorkspaceID(userID uint) (uint, error) {
+	var member models.WorkspaceMember
+	if err := config.DB.Where("user_id = ?", userID).Order("id").First(&member).Error; err != nil {
+		return 0, ErrNoWorkspace
+	}
+	return member.WorkspaceID, nil
+}
+
+// CountWorkspaceOwners 统计工作空间的所有者数量
+func CountWorkspaceOwners(workspaceID uint) int64 {
+	var count int64
+	config.DB.Model(&models.WorkspaceMember{}).Where("workspace_id = ? AND role = ?", workspaceID, models.RoleOwner).Count(&count)
+	return count
+}
+
+// MigrateWorkspaces 为没有工作空间的用户创建个人工作空间，并将未归属工作空间的智能体
+// 迁移到创建者的默认工作空间，用于升级前已有的数据
+func MigrateWorkspaces() error {
+	var users []models.User
+	err := config.DB.Where("id NOT IN (?)", config.DB.Model(&models.WorkspaceMember{}).Select("user_id")).Find(&users).Error
+	if err != nil {
+		return err
+	}
+	for i := range users {
+		if _, err := CreatePersonalWorkspace(config.DB, &users[i]); err != nil {
+			return err
+		}
+	}
+
+	var agents []models.Agent
+	if err := config.DB.Where("workspace_id = ?", 0).Find(&agents).Error; err != nil {
+		return err
+	}
+	for _, agent := range agents {
+		workspaceID, err := DefaultWorkspaceID(agent.UserID)
+		if err != nil {
+			log.Printf("[workspace] 智能体 %d 的创建者没有工作空间，跳过迁移", agent.ID)
+			continue
+		}
+		if err := config.DB.Model(&agent).Update("workspace_id", workspaceID).Error; err != nil {
+			return err
+		}
+	}
+
+	if len(users) > 0 || len(agents) > 0 {
+		log.Printf("[workspace] 已创建 %d 个个人工作空间，迁移 %d 个智能体", len(users), len(agents))
+	}
+	return nil
+}
@@ -3,12 +3,17 @@ package utils
 import (
 	"errors"
 
+	"ai-assistant-backend/models"
+
 	"github.com/gin-gonic/gin"
 )
 
 // 上下文键常量
 const (
-	UserKey = "user" // 新增：存储完整用户对象的键
+	UserKey  = "user"  // 新增：存储完整用户对象的键
+	AgentKey = "agent" // 当前请求操作的智能体
+	// WorkspaceKey 当前工作空间中的成员信息
+	WorkspaceKey = "workspace"
 )
 
 // SetUserContextToContext 将用户上下文对象存储到上下文中
@@ -31,3 +36,45 @@ func GetUserFromContext(c *gin.Context) (*Claims, error) {
 
 	return user, nil
 }
+
+// SetAgentToContext 将已校验权限的智能体存储到上下文中
+func SetAgentToContext(c *gin.Context, agent *models.Agent) {
+	if agent != nil {
+		c.Set(AgentKey, agent)
+	}
+}
+
+// GetAgentFromContext 从上下文中获取当前请求操作的智能体
+func GetAgentFromContext(c *gin.Context) (*models.Agent, error) {
+	agentInterface, exists := c.Get(AgentKey)
+	if !exists {
+		return nil, errors.New("智能体不存在于上下文中")
+	}
+	agent, ok := agentInterface.(*models.Agent)
+	if !ok {
+		return nil, errors.New("智能体类型不匹配")
+	}
+
+	return agent, nil
+}
+
+// SetWorkspaceToContext 将当前用户在当前工作空间中的成员信息存储到上下文中
+func SetWorkspaceToContext(c *gin.Context, member *models.WorkspaceMember) {
+	if member != nil {
+		c.Set(WorkspaceKey, member)
+	}
+}
+
+// GetWorkspaceFromContext 从上下文中获取当前工作空间的成员信息
+func GetWorkspaceFromContext(c *gin.Context) (*models.WorkspaceMember, error) {
+	memberInterface, exists := c.Get(WorkspaceKey)
+	if !exists {
+		return nil, errors.New("工作空间不存在于上下文中")
+	}
+	member, ok := memberInterface.(*models.WorkspaceMember)
+	if !ok {
+		return nil, errors.New("工作空间类型不匹配")
+	}
+
+	return member, nil
+}
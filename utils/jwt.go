@@ -1,9 +1,7 @@
 package utils
 
 import (
-	"context"
 	"errors"
-	"fmt"
 	"time"
 
 	"ai-assistant-backend/config"
@@ -15,22 +13,41 @@ type Claims struct {
 	UserID   uint   `json:"user_id"`
 	Username string `json:"username"`
 	Role     uint8  `json:"role"`
+	// WorkspaceID 当前工作空间，请求头X-Workspace-ID可临时覆盖
+	WorkspaceID uint `json:"workspace_id,omitempty"`
+	// SessionID 令牌所属的登录会话，会话注销后令牌随即失效
+	SessionID string `json:"sid"`
+	// APIKeyID 通过X-API-Key认证时为密钥ID，Scopes为密钥的权限范围，二者不写入令牌
+	APIKeyID uint     `json:"-"`
+	Scopes   []string `json:"-"`
 	jwt.RegisteredClaims
 }
 
-// GenerateToken 生成JWT令牌并存储到Redis
-func GenerateToken(userID uint, username string, role uint8) (string, error) {
+// HasScope API密钥是否包含指定权限范围
+func (c *Claims) HasScope(permission Permission) bool {
+	for _, scope := range c.Scopes {
+		if scope == string(permission) {
+			return true
+		}
+	}
+	return false
+}
+
+// GenerateToken 为登录会话生成短期的JWT访问令牌，过期后使用刷新令牌换取新令牌
+func GenerateToken(userID uint, username string, role uint8, workspaceID uint, sessionID string) (string, error) {
 	if config.GlobalConfig == nil {
 		return "", errors.New("配置未加载")
 	}
 
 	// 创建声明
 	claims := Claims{
-		UserID:   userID,
-		Username: username,
-		Role:     role,
+		UserID:      userID,
+		Username:    username,
+		Role:        role,
+		WorkspaceID: workspaceID,
+		SessionID:   sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Duration(config.GlobalConfig.JWT.ExpireHours) * time.Hour)),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(AccessTokenLifetime())),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
 		},
@@ -38,22 +55,7 @@ func GenerateToken(userID uint, username string, role uint8) (string, error) {
 
 	// 创建token
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(config.GlobalConfig.JWT.Secret))
-	if err != nil {
-		return "", err
-	}
-
-	// 将token存储到Redis，设置过期时间
-	ctx := context.Background()
-	redisKey := fmt.Sprintf("token:%s", tokenString)
-	expiration := time.Duration(config.GlobalConfig.JWT.ExpireHours) * time.Hour
-
-	err = config.RedisClient.Set(ctx, redisKey, userID, expiration).Err()
-	if err != nil {
-		return "", fmt.Errorf("存储token到Redis失败: %v", err)
-	}
-
-	return tokenString, nil
+	return token.SignedString([]byte(config.GlobalConfig.JWT.Secret))
 }
 
 // ParseToken 解析JWT令牌
@@ -77,53 +79,7 @@ func ParseToken(tokenString string) (*Claims, error) {
 	return nil, errors.New("无效的token")
 }
 
-// ValidateToken 验证token是否在Redis中存在且有效
-func ValidateToken(userID uint, tokenString string) (bool, error) {
-	if config.RedisClient == nil {
-		return false, errors.New("Redis客户端未初始化")
-	}
-
-	ctx := context.Background()
-	redisKey := fmt.Sprintf("token:%s", tokenString)
-
-	// 从Redis获取存储的token
-	storedUserID, err := config.RedisClient.Get(ctx, redisKey).Result()
-	if err != nil {
-		return false, fmt.Errorf("从Redis获取token失败: %v", err)
-	}
-
-	// 比较token是否一致
-	if storedUserID != fmt.Sprintf("%d", userID) {
-		return false, errors.New("token不匹配")
-	}
-
-	return true, nil
-}
-
-// InvalidateToken 使token失效（从Redis中删除）
-func InvalidateToken(userID uint) error {
-	if config.RedisClient == nil {
-		return errors.New("Redis客户端未初始化")
-	}
-
-	ctx := context.Background()
-	redisKey := fmt.Sprintf("token:%d", userID)
-
-	err := config.RedisClient.Del(ctx, redisKey).Err()
-	if err != nil {
-		return fmt.Errorf("从Redis删除token失败: %v", err)
-	}
-
-	return nil
-}
-
-// RefreshToken 刷新token
-func RefreshToken(userID uint, username string, role uint8) (string, error) {
-	// 先使旧token失效
-	if err := InvalidateToken(userID); err != nil {
-		return "", fmt.Errorf("使旧token失效失败: %v", err)
-	}
-
-	// 生成新token
-	return GenerateToken(userID, username, role)
+// IsTokenExpired 令牌是否因过期而无效，客户端收到后应使用刷新令牌换取新令牌
+func IsTokenExpired(err error) bool {
+	return errors.Is(err, jwt.ErrTokenExpired)
 }
@@ -0,0 +1,228 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"ai-assistant-backend/config"
+)
+
+// LLMMessage 大模型对话消息
+type LLMMessage struct {
+	Role    string `json:"role"` // system, user, assistant
+	Content string `json:"content"`
+}
+
+// LLMUsage 令牌用量
+type LLMUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// ChatCompletionRequest 对话补全请求
+type ChatCompletionRequest struct {
+	Model       string       `json:"model"`
+	Messages    []LLMMessage `json:"messages"`
+	Temperature *float64     `json:"temperature,omitempty"` // 为空时使用服务商默认值
+	MaxTokens   int          `json:"max_tokens,omitempty"`  // 为0时使用服务商默认值
+}
+
+// ChatCompletionResponse 对话补全结果
+type ChatCompletionResponse struct {
+	Model   string   `json:"model"`
+	Content string   `json:"content"`
+	Usage   LLMUsage `json:"usage"`
+}
+
+// ChatStreamEvent 流式补全事件，Delta为增量内容，Usage仅在结束时返回
+type ChatStreamEvent struct {
+	Delta string
+	Usage *LLMUsage
+	Err   error
+}
+
+// EmbeddingRequest 向量化请求
+type EmbeddingRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+// EmbeddingResponse 向量化结果，Vectors与Input一一对应
+type EmbeddingResponse struct {
+	Model   string      `json:"model"`
+	Vectors [][]float32 `json:"vectors"`
+	Usage   LLMUsage    `json:"usage"`
+}
+
+// LLMProvider 大模型服务接口
+type LLMProvider interface {
+	// ChatCompletion 一次性返回完整回复
+	ChatCompletion(ctx context.Context, req ChatCompletionRequest) (*ChatCompletionResponse, error)
+	// ChatCompletionStream 流式返回回复，事件通道在结束或出错后关闭
+	ChatCompletionStream(ctx context.Context, req ChatCompletionRequest) (<-chan ChatStreamEvent, error)
+	// Embeddings 将文本转换为向量
+	Embeddings(ctx context.Context, req EmbeddingRequest) (*EmbeddingResponse, error)
+}
+
+// llmEntry 已注册的服务商
+type llmEntry struct {
+	provider LLMProvider
+	config   config.LLMProviderConfig
+}
+
+var (
+	llmProviders       = map[string]*llmEntry{}
+	llmDefaultProvider string
+)
+
+// ErrLLMModelNotFound 模型未在任何服务商中配置
+var ErrLLMModelNotFound = errors.New("模型未配置")
+
+// InitLLM 根据配置初始化大模型服务商
+func InitLLM() error {
+	if config.GlobalConfig == nil {
+		return errors.New("配置未加载")
+	}
+
+	cfg := config.GlobalConfig.LLM
+	providers := make(map[string]*llmEntry, len(cfg.Providers))
+	for _, providerConfig := range cfg.Providers {
+		if providerConfig.Name == "" {
+			return errors.New("大模型服务商名称不能为空")
+		}
+		if _, exists := providers[providerConfig.Name]; exists {
+			return fmt.Errorf("大模型服务商重复: %s", providerConfig.Name)
+		}
+
+		provider, err := NewLLMProvider(providerConfig)
+		if err != nil {
+			return err
+		}
+		providers[providerConfig.Name] = &llmEntry{provider: provider, config: providerConfig}
+	}
+
+	defaultProvider := cfg.DefaultProvider
+	if defaultProvider == "" && len(cfg.Providers) > 0 {
+		defaultProvider = cfg.Providers[0].Name
+	}
+	if _, exists := providers[defaultProvider]; !exists {
+		return fmt.Errorf("默认大模型服务商不存在: %s", defaultProvider)
+	}
+
+	llmProviders = providers
+	llmDefaultProvider = defaultProvider
+
+	log.Printf("大模型服务初始化成功，默认服务商: %s", defaultProvider)
+	return nil
+}
+
+// NewLLMProvider 根据服务商类型创建实例
+func NewLLMProvider(cfg config.LLMProviderConfig) (LLMProvider, error) {
+	switch cfg.Type {
+	case "openai":
+		return NewOpenAIProvider(cfg), nil
+	case "mock":
+		return NewMockLLMProvider(), nil
+	default:
+		return nil, fmt.Errorf("不支持的大模型服务商类型: %s", cfg.Type)
+	}
+}
+
+// GetLLMProvider 获取指定名称的服务商，名称为空时返回默认服务商
+func GetLLMProvider(name string) (LLMProvider, error) {
+	if name == "" {
+		name = llmDefaultProvider
+	}
+	entry, exists := llmProviders[name]
+	if !exists {
+		return nil, fmt.Errorf("大模型服务商不存在: %s", name)
+	}
+	return entry.provider, nil
+}
+
+// ResolveLLMModel 查找提供指定模型的服务商，模型为空时使用默认服务商的默认模型
+func ResolveLLMModel(model string) (LLMProvider, string, error) {
+	if model == "" {
+		entry, exists := llmProviders[llmDefaultProvider]
+		if !exists {
+			return nil, "", errors.New("大模型服务未初始化")
+		}
+		model = entry.config.DefaultModel
+		if model == "" && len(entry.config.Models) > 0 {
+			model = entry.config.Models[0]
+		}
+		return entry.provider, model, nil
+	}
+
+	// 优先匹配默认服务商
+	if entry, exists := llmProviders[llmDefaultProvider]; exists && containsString(entry.config.Models, model) {
+		return entry.provider, model, nil
+	}
+	for _, entry := range llmProviders {
+		if containsString(entry.config.Models, model) {
+			return entry.provider, model, nil
+		}
+	}
+	return nil, "", ErrLLMModelNotFound
+}
+
+// ResolveEmbeddingModel 获取默认服务商的向量模型
+func ResolveEmbeddingModel() (LLMProvider, string, error) {
+	entry, exists := llmProviders[llmDefaultProvider]
+	if !exists {
+		return nil, "", errors.New("大模型服务未初始化")
+	}
+	if entry.config.EmbeddingModel == "" {
+		return nil, "", fmt.Errorf("大模型服务商未配置向量模型: %s", entry.config.Name)
+	}
+	return entry.provider, entry.config.EmbeddingModel, nil
+}
+
+// LLMModelInfo 可供智能体选择的模型
+type LLMModelInfo struct {
+	Provider string `json:"provider"`
+	Model    string `json:"model"`
+	Default  bool   `json:"default"` // 是否为默认服务商的默认模型
+}
+
+// ListLLMModels 按配置顺序列出所有服务商的对话模型
+func ListLLMModels() []LLMModelInfo {
+	_, defaultModel, _ := ResolveLLMModel("")
+
+	models := []LLMModelInfo{}
+	for _, providerConfig := range config.GlobalConfig.LLM.Providers {
+		if _, exists := llmProviders[providerConfig.Name]; !exists {
+			continue
+		}
+		for _, model := range providerConfig.Models {
+			models = append(models, LLMModelInfo{
+				Provider: providerConfig.Name,
+				Model:    model,
+				Default:  providerConfig.Name == llmDefaultProvider && model == defaultModel,
+			})
+		}
+	}
+	return models
+}
+
+// LLMTimeout 非流式请求的超时时间
+func LLMTimeout() time.Duration {
+	seconds := 60
+	if config.GlobalConfig != nil && config.GlobalConfig.LLM.TimeoutSeconds > 0 {
+		seconds = config.GlobalConfig.LLM.TimeoutSeconds
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+func containsString(items []string, target string) bool {
+	for _, item := range items {
+		if item == target {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,149 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// mockEmbeddingDimension 模拟向量维度
+const mockEmbeddingDimension = 256
+
+// MockLLMProvider 进程内的确定性模拟服务，相同输入总是得到相同输出，
+// 供测试与无法访问外部模型的开发环境使用
+type MockLLMProvider struct{}
+
+// NewMockLLMProvider 创建模拟服务商
+func NewMockLLMProvider() *MockLLMProvider {
+	return &MockLLMProvider{}
+}
+
+// ChatCompletion 根据最后一条用户消息生成固定格式的回复
+func (p *MockLLMProvider) ChatCompletion(ctx context.Context, req ChatCompletionRequest) (*ChatCompletionResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	content := mockReply(req)
+	return &ChatCompletionResponse{
+		Model:   req.Model,
+		Content: content,
+		Usage:   mockUsage(req.Messages, content),
+	}, nil
+}
+
+// ChatCompletionStream 将模拟回复逐字符推送
+func (p *MockLLMProvider) ChatCompletionStream(ctx context.Context, req ChatCompletionRequest) (<-chan ChatStreamEvent, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	content := mockReply(req)
+	events := make(chan ChatStreamEvent)
+	go func() {
+		defer close(events)
+		for _, r := range content {
+			select {
+			case events <- ChatStreamEvent{Delta: string(r)}:
+			case <-ctx.Done():
+				return
+			}
+		}
+		usage := mockUsage(req.Messages, content)
+		select {
+		case events <- ChatStreamEvent{Usage: &usage}:
+		case <-ctx.Done():
+		}
+	}()
+	return events, nil
+}
+
+// Embeddings 使用字符及相邻字符组合的哈希生成归一化向量，字面相近的文本向量也相近
+func (p *MockLLMProvider) Embeddings(ctx context.Context, req EmbeddingRequest) (*EmbeddingResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	vectors := make([][]float32, len(req.Input))
+	tokens := 0
+	for i, input := range req.Input {
+		vectors[i] = mockEmbedding(input)
+		tokens += mockTokenCount(input)
+	}
+	return &EmbeddingResponse{
+		Model:   req.Model,
+		Vectors: vectors,
+		Usage:   LLMUsage{PromptTokens: tokens, TotalTokens: tokens},
+	}, nil
+}
+
+func mockReply(req ChatCompletionRequest) string {
+	question := ""
+	for i := len(req.Messages) - 1; i >= 0; i-- {
+		if req.Messages[i].Role == "user" {
+			question = strings.TrimSpace(req.Messages[i].Content)
+			break
+		}
+	}
+	if question == "" {
+		return "您好，请问有什么可以帮您？"
+	}
+	return fmt.Sprintf("您好，关于“%s”，这是模拟模型的回复。", question)
+}
+
+func mockUsage(messages []LLMMessage, completion string) LLMUsage {
+	prompt := 0
+	for _, message := range messages {
+		prompt += mockTokenCount(message.Content)
+	}
+	completionTokens := mockTokenCount(completion)
+	return LLMUsage{
+		PromptTokens:     prompt,
+		CompletionTokens: completionTokens,
+		TotalTokens:      prompt + completionTokens,
+	}
+}
+
+// mockTokenCount 以字符数近似令牌数
+func mockTokenCount(text string) int {
+	return len([]rune(text))
+}
+
+func mockEmbedding(text string) []float32 {
+	vector := make([]float32, mockEmbeddingDimension)
+
+	var runes []rune
+	for _, r := range strings.ToLower(text) {
+		if unicode.IsLetter(r) || unicode.IsNumber(r) {
+			runes = append(runes, r)
+		}
+	}
+
+	add := func(feature string) {
+		h := fnv.New32a()
+		h.Write([]byte(feature))
+		vector[h.Sum32()%mockEmbeddingDimension]++
+	}
+	for i, r := range runes {
+		add(string(r))
+		if i+1 < len(runes) {
+			add(string(runes[i : i+2]))
+		}
+	}
+
+	var norm float64
+	for _, v := range vector {
+		norm += float64(v) * float64(v)
+	}
+	if norm == 0 {
+		return vector
+	}
+	norm = math.Sqrt(norm)
+	for i := range vector {
+		vector[i] = float32(float64(vector[i]) / norm)
+	}
+	return vector
+}
@@ -0,0 +1,238 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"ai-assistant-backend/config"
+)
+
+// OpenAIProvider 兼容OpenAI接口的大模型服务
+type OpenAIProvider struct {
+	baseURL    string
+	apiKey     string
+	httpClient *http.Client
+}
+
+// NewOpenAIProvider 创建OpenAI兼容服务商
+func NewOpenAIProvider(cfg config.LLMProviderConfig) *OpenAIProvider {
+	baseURL := strings.TrimRight(cfg.BaseURL, "/")
+	if baseURL == "" {
+		baseURL = "https://api.openai.com/v1"
+	}
+	return &OpenAIProvider{
+		baseURL:    baseURL,
+		apiKey:     cfg.APIKey,
+		httpClient: &http.Client{},
+	}
+}
+
+type openAIChatRequest struct {
+	Model         string               `json:"model"`
+	Messages      []LLMMessage         `json:"messages"`
+	Temperature   *float64             `json:"temperature,omitempty"`
+	MaxTokens     int                  `json:"max_tokens,omitempty"`
+	Stream        bool                 `json:"stream,omitempty"`
+	StreamOptions *openAIStreamOptions `json:"stream_options,omitempty"`
+}
+
+type openAIStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+type openAIChatResponse struct {
+	Model   string `json:"model"`
+	Choices []struct {
+		Message LLMMessage `json:"message"`
+		Delta   LLMMessage `json:"delta"`
+	} `json:"choices"`
+	Usage *LLMUsage `json:"usage"`
+}
+
+type openAIEmbeddingResponse struct {
+	Model string `json:"model"`
+	Data  []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+	Usage LLMUsage `json:"usage"`
+}
+
+type openAIErrorResponse struct {
+	Error struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// ChatCompletion 对话补全
+func (p *OpenAIProvider) ChatCompletion(ctx context.Context, req ChatCompletionRequest) (*ChatCompletionResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, LLMTimeout())
+	defer cancel()
+
+	resp, err := p.post(ctx, "/chat/completions", openAIChatRequest{
+		Model:       req.Model,
+		Messages:    req.Messages,
+		Temperature: req.Temperature,
+		MaxTokens:   req.MaxTokens,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result openAIChatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("解析大模型响应失败: %v", err)
+	}
+	if len(result.Choices) == 0 {
+		return nil, fmt.Errorf("大模型未返回结果")
+	}
+
+	completion := &ChatCompletionResponse{
+		Model:   result.Model,
+		Content: result.Choices[0].Message.Content,
+	}
+	if result.Usage != nil {
+		completion.Usage = *result.Usage
+	}
+	return completion, nil
+}
+
+// ChatCompletionStream 流式对话补全
+func (p *OpenAIProvider) ChatCompletionStream(ctx context.Context, req ChatCompletionRequest) (<-chan ChatStreamEvent, error) {
+	resp, err := p.post(ctx, "/chat/completions", openAIChatRequest{
+		Model:         req.Model,
+		Messages:      req.Messages,
+		Temperature:   req.Temperature,
+		MaxTokens:     req.MaxTokens,
+		Stream:        true,
+		StreamOptions: &openAIStreamOptions{IncludeUsage: true},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan ChatStreamEvent)
+	go func() {
+		defer close(events)
+		defer resp.Body.Close()
+
+		send := func(event ChatStreamEvent) bool {
+			select {
+			case events <- event:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		scanner := bufio.NewScanner(resp.Body)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if !strings.HasPrefix(line, "data:") {
+				continue
+			}
+			data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+			if data == "[DONE]" {
+				return
+			}
+
+			var chunk openAIChatResponse
+			if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+				send(ChatStreamEvent{Err: fmt.Errorf("解析大模型流式响应失败: %v", err)})
+				return
+			}
+
+			event := ChatStreamEvent{Usage: chunk.Usage}
+			if len(chunk.Choices) > 0 {
+				event.Delta = chunk.Choices[0].Delta.Content
+			}
+			if event.Delta == "" && event.Usage == nil {
+				continue
+			}
+			if !send(event) {
+				return
+			}
+		}
+		if err := scanner.Err(); err != nil && ctx.Err() == nil {
+			send(ChatStreamEvent{Err: fmt.Errorf("读取大模型流式响应失败: %v", err)})
+		}
+	}()
+
+	return events, nil
+}
+
+// Embeddings 文本向量化
+func (p *OpenAIProvider) Embeddings(ctx context.Context, req EmbeddingRequest) (*EmbeddingResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, LLMTimeout())
+	defer cancel()
+
+	resp, err := p.post(ctx, "/embeddings", req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result openAIEmbeddingResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("解析向量化响应失败: %v", err)
+	}
+	if len(result.Data) != len(req.Input) {
+		return nil, fmt.Errorf("向量数量不匹配: 期望%d，实际%d", len(req.Input), len(result.Data))
+	}
+
+	vectors := make([][]float32, len(req.Input))
+	for _, item := range result.Data {
+		if item.Index < 0 || item.Index >= len(vectors) {
+			return nil, fmt.Errorf("向量索引越界: %d", item.Index)
+		}
+		vectors[item.Index] = item.Embedding
+	}
+
+	return &EmbeddingResponse{
+		Model:   result.Model,
+		Vectors: vectors,
+		Usage:   result.Usage,
+	}, nil
+}
+
+// post 发送请求，非2xx响应会被转换为错误
+func (p *OpenAIProvider) post(ctx context.Context, path string, body interface{}) (*http.Response, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if p.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求大模型服务失败: %v", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		var errResp openAIErrorResponse
+		if json.Unmarshal(data, &errResp) == nil && errResp.Error.Message != "" {
+			return nil, fmt.Errorf("大模型服务返回错误(%d): %s", resp.StatusCode, errResp.Error.Message)
+		}
+		return nil, fmt.Errorf("大模型服务返回错误(%d): %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+
+	return resp, nil
+}
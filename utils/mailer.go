@@ -0,0 +1,208 @@
+package utils
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"ai-assistant-backend/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MailMessage 待发送的邮件
+type MailMessage struct {
+	To      string
+	Subject string
+	Body    string // 纯文本正文
+}
+
+// Mailer 邮件发送接口
+type Mailer interface {
+	Send(ctx context.Context, msg MailMessage) error
+}
+
+var (
+	mailerMu      sync.RWMutex
+	defaultMailer Mailer
+)
+
+// InitMailer 根据配置初始化邮件发送器
+func InitMailer() error {
+	if config.GlobalConfig == nil {
+		return errors.New("配置未加载")
+	}
+
+	mailer, err := NewMailer(config.GlobalConfig.Mail)
+	if err != nil {
+		return err
+	}
+	SetMailer(mailer)
+
+	log.Printf("邮件服务初始化成功，发送方式: %s", mailDriver(config.GlobalConfig.Mail))
+	return nil
+}
+
+// NewMailer 根据发送方式创建邮件发送器。邮件中含有邀请、重置密码令牌，
+// 只记录日志的方式仅可在debug模式下使用，其他模式需配置SMTP
+func NewMailer(cfg config.MailConfig) (Mailer, error) {
+	switch mailDriver(cfg) {
+	case "smtp":
+		return NewSMTPMailer(cfg)
+	case "log":
+		if !gin.IsDebugging() {
+			return nil, errors.New("非debug模式下需配置SMTP邮件服务（mail.driver: smtp）")
+		}
+		return NewLogMailer(), nil
+	default:
+		return nil, fmt.Errorf("不支持的邮件发送方式: %s", cfg.Driver)
+	}
+}
+
+// GetMailer 获取当前邮件发送器
+func GetMailer() Mailer {
+	mailerMu.RLock()
+	defer mailerMu.RUnlock()
+	return defaultMailer
+}
+
+// SetMailer 替换邮件发送器，测试时可替换为记录邮件的实现检查发送内容
+func SetMailer(mailer Mailer) {
+	mailerMu.Lock()
+	defer mailerMu.Unlock()
+	defaultMailer = mailer
+}
+
+// SendMail 使用当前邮件发送器发送邮件
+func SendMail(ctx context.Context, msg MailMessage) error {
+	mailer := GetMailer()
+	if mailer == nil {
+		return errors.New("邮件服务未初始化")
+	}
+	return mailer.Send(ctx, msg)
+}
+
+// mailDriver 邮件发送方式，未配置时debug模式下只记录日志
+func mailDriver(cfg config.MailConfig) string {
+	if cfg.Driver == "" && gin.IsDebugging() {
+		return "log"
+	}
+	return cfg.Driver
+}
+
+// SMTPMailer 通过SMTP服务器发送邮件，465端口使用TLS直连，其他端口在服务器支持时使用STARTTLS
+type SMTPMailer struct {
+	cfg  config.MailConfig
+	from *mail.Address
+}
+
+// NewSMTPMailer 创建SMTP邮件发送器
+func NewSMTPMailer(cfg config.MailConfig) (*SMTPMailer, error) {
+	if cfg.Host == "" || cfg.Port == 0 {
+		return nil, errors.New("SMTP服务器地址未配置")
+	}
+	from, err := mail.ParseAddress(cfg.From)
+	if err != nil {
+		return nil, fmt.Errorf("发件人地址无效: %v", err)
+	}
+	return &SMTPMailer{cfg: cfg, from: from}, nil
+}
+
+// Send 发送邮件
+func (m *SMTPMailer) Send(ctx context.Context, msg MailMessage) error {
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return fmt.Errorf("收件人地址无效: %v", err)
+	}
+
+	addr := net.JoinHostPort(m.cfg.Host, strconv.Itoa(m.cfg.Port))
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	var conn net.Conn
+	if m.cfg.Port == 465 {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, &tls.Config{ServerName: m.cfg.Host})
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("连接SMTP服务器失败: %v", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, m.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("连接SMTP服务器失败: %v", err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok && m.cfg.Port != 465 {
+		if err := client.StartTLS(&tls.Config{ServerName: m.cfg.Host}); err != nil {
+			return fmt.Errorf("启用TLS失败: %v", err)
+		}
+	}
+	if m.cfg.Username != "" {
+		auth := smtp.PlainAuth("", m.cfg.Username, m.cfg.Password, m.cfg.Host)
+		if err := client.Auth(auth); err != nil {
+			return fmt.Errorf("SMTP认证失败: %v", err)
+		}
+	}
+
+	if err := client.Mail(m.from.Address); err != nil {
+		return fmt.Errorf("发送邮件失败: %v", err)
+	}
+	if err := client.Rcpt(to.Address); err != nil {
+		return fmt.Errorf("发送邮件失败: %v", err)
+	}
+	writer, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("发送邮件失败: %v", err)
+	}
+	if _, err := writer.Write(buildMail(m.from, to, msg)); err != nil {
+		writer.Close()
+		return fmt.Errorf("发送邮件失败: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("发送邮件失败: %v", err)
+	}
+	return client.Quit()
+}
+
+// buildMail 生成邮件内容，标题与正文使用UTF-8编码
+func buildMail(from, to *mail.Address, msg MailMessage) []byte {
+	var builder strings.Builder
+	builder.WriteString("From: " + from.String() + "\r\n")
+	builder.WriteString("To: " + to.String() + "\r\n")
+	builder.WriteString("Subject: " + mime.BEncoding.Encode("UTF-8", msg.Subject) + "\r\n")
+	builder.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	builder.WriteString("MIME-Version: 1.0\r\n")
+	builder.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	builder.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	builder.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return []byte(builder.String())
+}
+
+// LogMailer 仅将邮件写入日志，用于本地开发，只能在debug模式下启用
+type LogMailer struct{}
+
+// NewLogMailer 创建仅记录日志的邮件发送器
+func NewLogMailer() *LogMailer {
+	return &LogMailer{}
+}
+
+// Send 将邮件写入日志
+func (m *LogMailer) Send(ctx context.Context, msg MailMessage) error {
+	log.Printf("[mail] 收件人: %s 标题: %s\n%s", msg.To, msg.Subject, msg.Body)
+	return nil
+}
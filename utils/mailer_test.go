@@ -0,0 +1,42 @@
+package utils
+
+import (
+	"context"
+	"testing"
+
+	"ai-assistant-backend/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestNewMailerRequiresSMTPOutsideDebug(t *testing.T) {
+	previous := gin.Mode()
+	t.Cleanup(func() { gin.SetMode(previous) })
+
+	gin.SetMode(gin.DebugMode)
+	for _, driver := range []string{"", "log"} {
+		if _, err := NewMailer(config.MailConfig{Driver: driver}); err != nil {
+			t.Errorf("debug模式下 driver=%q 应可使用日志发送: %v", driver, err)
+		}
+	}
+
+	gin.SetMode(gin.ReleaseMode)
+	for _, driver := range []string{"", "log"} {
+		if _, err := NewMailer(config.MailConfig{Driver: driver}); err == nil {
+			t.Errorf("release模式下 driver=%q 应拒绝启动", driver)
+		}
+	}
+	if _, err := NewMailer(config.MailConfig{Driver: "smtp", Host: "smtp.example.com", Port: 587, From: "noreply@example.com"}); err != nil {
+		t.Errorf("release模式下应可使用SMTP: %v", err)
+	}
+}
+
+func TestSendMailWithoutMailer(t *testing.T) {
+	previous := GetMailer()
+	SetMailer(nil)
+	t.Cleanup(func() { SetMailer(previous) })
+
+	if err := SendMail(context.Background(), MailMessage{To: "a@example.com"}); err == nil {
+		t.Error("未初始化邮件服务时应返回错误")
+	}
+}
@@ -0,0 +1,33 @@
+package utils
+
+import (
+	"log"
+	"os"
+	"testing"
+
+	"ai-assistant-backend/config"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+// testRedis 测试使用的内存Redis，可用于快进时间模拟过期
+var testRedis *miniredis.Miniredis
+
+// TestMain 使用miniredis代替Redis
+func TestMain(m *testing.M) {
+	config.GlobalConfig = &config.Config{}
+	config.GlobalConfig.JWT.Secret = "test-secret"
+	config.GlobalConfig.JWT.RefreshTokenDays = 1
+
+	var err error
+	testRedis, err = miniredis.Run()
+	if err != nil {
+		log.Fatal("启动测试Redis失败:", err)
+	}
+	config.RedisClient = redis.NewClient(&redis.Options{Addr: testRedis.Addr()})
+
+	code := m.Run()
+	testRedis.Close()
+	os.Exit(code)
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"mime/multipart"
+	"net/url"
 	"path/filepath"
 	"strings"
 	"time"
@@ -129,6 +130,34 @@ func GenerateObjectName(filename string, prefix string) string {
 	return fmt.Sprintf("%s/%s", dateDir, uniqueName)
 }
 
+// WorkspaceObjectPrefix 工作空间文件的对象名称前缀，各工作空间的文件相互隔离
+func WorkspaceObjectPrefix(workspaceID uint) string {
+	return fmt.Sprintf("workspaces/%d/", workspaceID)
+}
+
+// ObjectInWorkspace 对象是否位于指定工作空间的前缀下
+func ObjectInWorkspace(objectName string, workspaceID uint) bool {
+	return strings.HasPrefix(objectName, WorkspaceObjectPrefix(workspaceID)) && !strings.Contains(objectName, "..")
+}
+
+// ObjectNameFromPath 将文档路径转换为对象名称，兼容带前导斜杠的路径与完整的文件URL
+func ObjectNameFromPath(path string) string {
+	path = strings.TrimSpace(path)
+	if strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://") {
+		if parsed, err := url.Parse(path); err == nil {
+			path = parsed.Path
+		}
+	}
+	path = strings.TrimPrefix(path, "/")
+
+	// 路径式URL中包含bucket名称
+	bucket := config.GlobalConfig.MinIO.Bucket
+	if bucket != "" && strings.HasPrefix(path, bucket+"/") {
+		path = strings.TrimPrefix(path, bucket+"/")
+	}
+	return path
+}
+
 // UploadFileWithValidation 上传文件并验证
 func UploadFileWithValidation(file *multipart.FileHeader, allowedTypes []string, maxSize int64, prefix string) (string, string, error) {
 	// 验证文件大小
@@ -0,0 +1,363 @@
+package utils
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"ai-assistant-backend/config"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	oidcRequestTimeout = 10 * time.Second
+	// oidcJWKSRefreshInterval 遇到未知kid时重新获取公钥的最小间隔
+	oidcJWKSRefreshInterval = time.Minute
+)
+
+// OIDCProvider OIDC身份提供方客户端，端点通过Discovery获取
+type OIDCProvider struct {
+	cfg        config.OIDCConfig
+	httpClient *http.Client
+
+	mu         sync.Mutex
+	discovery  *oidcDiscovery
+	keys       map[string]*rsa.PublicKey
+	keysLoaded time.Time
+}
+
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+type oidcTokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	IDToken          string `json:"id_token"`
+	TokenType        string `json:"token_type"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+type oidcJWKS struct {
+	Keys []struct {
+		Kty string `json:"kty"`
+		Kid string `json:"kid"`
+		Use string `json:"use"`
+		N   string `json:"n"`
+		E   string `json:"e"`
+	} `json:"keys"`
+}
+
+// OIDCIdentity ID令牌中的用户信息
+type OIDCIdentity struct {
+	Subject           string
+	Email             string
+	EmailVerified     *bool // 身份提供方未返回email_verified时为nil
+	Name              string
+	PreferredUsername string
+	Groups            []string
+}
+
+var (
+	oidcMu       sync.Mutex
+	oidcProvider *OIDCProvider
+)
+
+// GetOIDCProvider 获取配置的OIDC身份提供方，未启用时返回错误
+func GetOIDCProvider() (*OIDCProvider, error) {
+	cfg := config.GlobalConfig.OIDC
+	if !cfg.Enabled {
+		return nil, errors.New("未启用单点登录")
+	}
+
+	oidcMu.Lock()
+	defer oidcMu.Unlock()
+	if oidcProvider == nil {
+		oidcProvider = NewOIDCProvider(cfg)
+	}
+	return oidcProvider, nil
+}
+
+// NewOIDCProvider 创建OIDC身份提供方客户端
+func NewOIDCProvider(cfg config.OIDCConfig) *OIDCProvider {
+	cfg.Issuer = strings.TrimRight(cfg.Issuer, "/")
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"openid", "profile", "email"}
+	}
+	if cfg.GroupsClaim == "" {
+		cfg.GroupsClaim = "groups"
+	}
+	return &OIDCProvider{
+		cfg:        cfg,
+		httpClient: &http.Client{Timeout: oidcRequestTimeout},
+	}
+}
+
+// GeneratePKCE 生成PKCE校验码及其S256摘要
+func GeneratePKCE() (verifier, challenge string, err error) {
+	verifier, err = GenerateRandomString(64)
+	if err != nil {
+		return "", "", err
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	return verifier, base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+// AuthCodeURL 生成跳转到身份提供方的授权地址
+func (p *OIDCProvider) AuthCodeURL(ctx context.Context, state, nonce, codeChallenge string) (string, error) {
+	discovery, err := p.getDiscovery(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	query := url.Values{}
+	query.Set("response_type", "code")
+	query.Set("client_id", p.cfg.ClientID)
+	query.Set("redirect_uri", p.cfg.RedirectURL)
+	query.Set("scope", strings.Join(p.cfg.Scopes, " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", codeChallenge)
+	query.Set("code_challenge_method", "S256")
+
+	separator := "?"
+	if strings.Contains(discovery.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return discovery.AuthorizationEndpoint + separator + query.Encode(), nil
+}
+
+// Exchange 使用授权码与PKCE校验码换取ID令牌，校验签名与nonce后返回用户信息
+func (p *OIDCProvider) Exchange(ctx context.Context, code, codeVerifier, nonce string) (*OIDCIdentity, error) {
+	discovery, err := p.getDiscovery(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.cfg.RedirectURL)
+	form.Set("code_verifier", codeVerifier)
+	if p.cfg.ClientSecret == "" {
+		form.Set("client_id", p.cfg.ClientID)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.cfg.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(p.cfg.ClientSecret))
+	}
+
+	var token oidcTokenResponse
+	status, err := p.doJSON(req, &token)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK || token.Error != "" {
+		return nil, fmt.Errorf("换取令牌失败(%d): %s %s", status, token.Error, token.ErrorDescription)
+	}
+	if token.IDToken == "" {
+		return nil, errors.New("身份提供方未返回ID令牌")
+	}
+
+	return p.verifyIDToken(ctx, token.IDToken, nonce)
+}
+
+// verifyIDToken 校验ID令牌的签名、签发方、受众、有效期与nonce
+func (p *OIDCProvider) verifyIDToken(ctx context.Context, rawIDToken, nonce string) (*OIDCIdentity, error) {
+	discovery, err := p.getDiscovery(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := jwt.MapClaims{}
+	_, err = jwt.ParseWithClaims(rawIDToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.publicKey(ctx, kid)
+	},
+		jwt.WithValidMethods([]string{"RS256"}),
+		jwt.WithIssuer(discovery.Issuer),
+		jwt.WithAudience(p.cfg.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("ID令牌校验失败: %v", err)
+	}
+	if value, _ := claims["nonce"].(string); value == "" || value != nonce {
+		return nil, errors.New("ID令牌校验失败: nonce不匹配")
+	}
+
+	identity := &OIDCIdentity{}
+	identity.Subject, _ = claims["sub"].(string)
+	identity.Email, _ = claims["email"].(string)
+	identity.Name, _ = claims["name"].(string)
+	identity.PreferredUsername, _ = claims["preferred_username"].(string)
+	if identity.Subject == "" {
+		return nil, errors.New("ID令牌校验失败: 缺少sub")
+	}
+
+	// 部分身份提供方以字符串形式返回email_verified
+	switch verified := claims["email_verified"].(type) {
+	case bool:
+		identity.EmailVerified = &verified
+	case string:
+		value := verified == "true"
+		identity.EmailVerified = &value
+	}
+
+	switch groups := claims[p.cfg.GroupsClaim].(type) {
+	case []interface{}:
+		for _, group := range groups {
+			if name, ok := group.(string); ok {
+				identity.Groups = append(identity.Groups, name)
+			}
+		}
+	case string:
+		identity.Groups = []string{groups}
+	}
+	return identity, nil
+}
+
+// publicKey 按kid查找签名公钥，找不到时重新获取JWKS（限制频率）
+func (p *OIDCProvider) publicKey(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if key := p.findKey(kid); key != nil {
+		return key, nil
+	}
+	if time.Since(p.keysLoaded) < oidcJWKSRefreshInterval {
+		return nil, fmt.Errorf("未知的签名密钥: %s", kid)
+	}
+
+	keys, err := p.loadKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	p.keys = keys
+	p.keysLoaded = time.Now()
+
+	if key := p.findKey(kid); key != nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("未知的签名密钥: %s", kid)
+}
+
+// findKey kid为空且只有一个公钥时直接使用该公钥
+func (p *OIDCProvider) findKey(kid string) *rsa.PublicKey {
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key
+		}
+	}
+	return p.keys[kid]
+}
+
+func (p *OIDCProvider) loadKeys(ctx context.Context) (map[string]*rsa.PublicKey, error) {
+	if p.discovery == nil {
+		return nil, errors.New("未获取身份提供方配置")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.discovery.JWKSURI, nil)
+	if err != nil {
+		return nil, err
+	}
+	var jwks oidcJWKS
+	status, err := p.doJSON(req, &jwks)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("获取签名公钥失败(%d)", status)
+	}
+
+	keys := map[string]*rsa.PublicKey{}
+	for _, item := range jwks.Keys {
+		if item.Kty != "RSA" || (item.Use != "" && item.Use != "sig") {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(item.N)
+		if err != nil {
+			continue
+		}
+		e, err := base64.RawURLEncoding.DecodeString(item.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			continue
+		}
+		keys[item.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+	return keys, nil
+}
+
+// getDiscovery 获取并缓存身份提供方的端点配置
+func (p *OIDCProvider) getDiscovery(ctx context.Context) (*oidcDiscovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.cfg.Issuer+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, err
+	}
+	var discovery oidcDiscovery
+	status, err := p.doJSON(req, &discovery)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("获取身份提供方配置失败(%d)", status)
+	}
+	if strings.TrimRight(discovery.Issuer, "/") != p.cfg.Issuer {
+		return nil, fmt.Errorf("身份提供方issuer不匹配: %s", discovery.Issuer)
+	}
+	if discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" || discovery.JWKSURI == "" {
+		return nil, errors.New("身份提供方配置缺少必要的端点")
+	}
+
+	p.discovery = &discovery
+	return p.discovery, nil
+}
+
+// doJSON 发送请求并解析JSON响应，返回HTTP状态码
+func (p *OIDCProvider) doJSON(req *http.Request, result interface{}) (int, error) {
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("请求身份提供方失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return resp.StatusCode, err
+	}
+	if err := json.Unmarshal(data, result); err != nil && resp.StatusCode == http.StatusOK {
+		return resp.StatusCode, fmt.Errorf("解析身份提供方响应失败: %v", err)
+	}
+	return resp.StatusCode, nil
+}
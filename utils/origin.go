@@ -0,0 +1,58 @@
+package utils
+
+import (
+	"errors"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var domainPattern = regexp.MustCompile(`^(\*\.)?([a-z0-9]([a-z0-9-]*[a-z0-9])?\.)*[a-z0-9]([a-z0-9-]*[a-z0-9])?$`)
+
+// NormalizeAllowedDomain 规范化允许接入的域名：转为小写，去除协议、端口与路径。
+// 支持 example.com（仅该域名）与 *.example.com（该域名的所有子域名）
+func NormalizeAllowedDomain(domain string) (string, error) {
+	domain = strings.ToLower(strings.TrimSpace(domain))
+	if strings.Contains(domain, "://") {
+		parsed, err := url.Parse(domain)
+		if err != nil || parsed.Host == "" {
+			return "", errors.New("无效的域名: " + domain)
+		}
+		domain = parsed.Host
+	}
+	domain = strings.TrimSuffix(domain, "/")
+	if host, _, found := strings.Cut(domain, ":"); found {
+		domain = host
+	}
+
+	if !domainPattern.MatchString(domain) {
+		return "", errors.New("无效的域名: " + domain)
+	}
+	return domain, nil
+}
+
+// OriginAllowed 判断请求来源Origin是否属于允许的域名，domains为空时不限制
+func OriginAllowed(origin string, domains []string) bool {
+	if len(domains) == 0 {
+		return true
+	}
+
+	parsed, err := url.Parse(origin)
+	if err != nil || parsed.Hostname() == "" {
+		return false
+	}
+	host := strings.ToLower(parsed.Hostname())
+
+	for _, domain := range domains {
+		if suffix, ok := strings.CutPrefix(domain, "*."); ok {
+			if strings.HasSuffix(host, "."+suffix) {
+				return true
+			}
+			continue
+		}
+		if host == domain {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,79 @@
+package utils
+
+import "testing"
+
+func TestNormalizeAllowedDomain(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"example.com", "example.com"},
+		{"  Shop.Example.COM ", "shop.example.com"},
+		{"*.Example.com", "*.example.com"},
+		{"example.com/", "example.com"},
+		{"example.com:8080", "example.com"},
+		{"https://Shop.Example.com", "shop.example.com"},
+		{"https://shop.example.com:8443/path?q=1", "shop.example.com"},
+		{"http://localhost:3000", "localhost"},
+		{"xn--fiqs8s.cn", "xn--fiqs8s.cn"},
+	}
+	for _, tt := range tests {
+		got, err := NormalizeAllowedDomain(tt.input)
+		if err != nil {
+			t.Errorf("NormalizeAllowedDomain(%q) 返回错误: %v", tt.input, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("NormalizeAllowedDomain(%q) = %q，期望 %q", tt.input, got, tt.want)
+		}
+	}
+
+	// 通配符只能作为最左侧的完整标签
+	invalid := []string{"", "*", "*.", "*example.com", "shop.*.example.com", "**.example.com",
+		"example.com/path", "-example.com", "example-.com", "exa mple.com", "例子.cn", "https://", "https://:80"}
+	for _, input := range invalid {
+		if got, err := NormalizeAllowedDomain(input); err == nil {
+			t.Errorf("NormalizeAllowedDomain(%q) = %q，期望返回错误", input, got)
+		}
+	}
+}
+
+func TestOriginAllowed(t *testing.T) {
+	domains := []string{"example.com", "*.shop.com"}
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		{"https://example.com", true},
+		{"http://example.com", true},
+		// 端口不参与匹配
+		{"https://example.com:8443", true},
+		{"https://EXAMPLE.com", true},
+		// 非通配符域名不包含子域名
+		{"https://www.example.com", false},
+		// 通配符匹配任意层级的子域名，但不包含域名本身
+		{"https://www.shop.com", true},
+		{"https://a.b.shop.com:3000", true},
+		{"https://WWW.Shop.Com", true},
+		{"https://shop.com", false},
+		// 后缀相同但不是子域名
+		{"https://evilexample.com", false},
+		{"https://evilshop.com", false},
+		{"https://example.com.evil.com", false},
+		{"https://shop.com.evil.com", false},
+		// 无法解析主机名的来源
+		{"null", false},
+		{"", false},
+		{"example.com", false},
+	}
+	for _, tt := range tests {
+		if got := OriginAllowed(tt.origin, domains); got != tt.want {
+			t.Errorf("OriginAllowed(%q) = %v，期望 %v", tt.origin, got, tt.want)
+		}
+	}
+
+	// 未配置允许域名时不限制来源
+	if !OriginAllowed("https://any.site", nil) {
+		t.Error("未配置允许域名时应允许任意来源")
+	}
+}
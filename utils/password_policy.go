@@ -0,0 +1,158 @@
+package utils
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"ai-assistant-backend/config"
+)
+
+const (
+	defaultPasswordMinLength = 8
+	// passwordMaxLength bcrypt只使用前72个字节
+	passwordMaxLength = 72
+)
+
+// breachedList 已泄露密码列表，按文件路径缓存，只在首次使用时加载
+type breachedList struct {
+	path      string
+	passwords map[string]struct{} // 小写明文
+	hashes    map[string]struct{} // 大写SHA-1摘要
+}
+
+var (
+	breachedMu     sync.Mutex
+	breachedLoaded *breachedList
+)
+
+// PasswordPolicyError 密码不符合密码策略，Message可直接提示给用户
+type PasswordPolicyError struct {
+	Message string
+}
+
+func (e *PasswordPolicyError) Error() string {
+	return e.Message
+}
+
+// ValidatePassword 按密码策略校验密码，username不为空时密码不能包含用户名
+func ValidatePassword(password, username string) error {
+	policy := config.GlobalConfig.Security.PasswordPolicy
+
+	minLength := policy.MinLength
+	if minLength <= 0 {
+		minLength = defaultPasswordMinLength
+	}
+	if utf8.RuneCountInString(password) < minLength {
+		return &PasswordPolicyError{Message: fmt.Sprintf("密码长度不能少于%d个字符", minLength)}
+	}
+	if len(password) > passwordMaxLength {
+		return &PasswordPolicyError{Message: fmt.Sprintf("密码长度不能超过%d个字节", passwordMaxLength)}
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsSpace(r):
+		default:
+			hasSymbol = true
+		}
+	}
+	switch {
+	case policy.RequireUpper && !hasUpper:
+		return &PasswordPolicyError{Message: "密码需要包含大写字母"}
+	case policy.RequireLower && !hasLower:
+		return &PasswordPolicyError{Message: "密码需要包含小写字母"}
+	case policy.RequireDigit && !hasDigit:
+		return &PasswordPolicyError{Message: "密码需要包含数字"}
+	case policy.RequireSymbol && !hasSymbol:
+		return &PasswordPolicyError{Message: "密码需要包含特殊字符"}
+	}
+
+	if username != "" && strings.Contains(strings.ToLower(password), strings.ToLower(username)) {
+		return &PasswordPolicyError{Message: "密码不能包含用户名"}
+	}
+	if isBreachedPassword(policy.BreachedListFile, password) {
+		return &PasswordPolicyError{Message: "该密码已出现在泄露密码列表中，请更换"}
+	}
+	return nil
+}
+
+// isBreachedPassword 检查密码是否在已泄露列表中，明文比较忽略大小写。
+// 列表文件读取失败时记录日志并跳过检查
+func isBreachedPassword(path, password string) bool {
+	if path == "" {
+		return false
+	}
+	list, err := loadBreachedList(path)
+	if err != nil {
+		log.Printf("[password] 读取泄露密码列表失败: %v", err)
+		return false
+	}
+
+	if _, ok := list.passwords[strings.ToLower(password)]; ok {
+		return true
+	}
+	sum := sha1.Sum([]byte(password))
+	_, ok := list.hashes[strings.ToUpper(hex.EncodeToString(sum[:]))]
+	return ok
+}
+
+func loadBreachedList(path string) (*breachedList, error) {
+	breachedMu.Lock()
+	defer breachedMu.Unlock()
+	if breachedLoaded != nil && breachedLoaded.path == path {
+		return breachedLoaded, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	list := &breachedList{path: path, passwords: map[string]struct{}{}, hashes: map[string]struct{}{}}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if hash, ok := parseSHA1Line(line); ok {
+			list.hashes[hash] = struct{}{}
+			continue
+		}
+		list.passwords[strings.ToLower(line)] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	breachedLoaded = list
+	return list, nil
+}
+
+// parseSHA1Line 识别 SHA1摘要 或 SHA1摘要:次数 格式的行
+func parseSHA1Line(line string) (string, bool) {
+	hash, _, _ := strings.Cut(line, ":")
+	if len(hash) != 40 {
+		return "", false
+	}
+	if _, err := hex.DecodeString(hash); err != nil {
+		return "", false
+	}
+	return strings.ToUpper(hash), true
+}
@@ -0,0 +1,90 @@
+package utils
+
+import "ai-assistant-backend/models"
+
+// Permission 操作权限，格式为 资源:操作
+type Permission string
+
+// 权限定义
+const (
+	PermAgentsRead     Permission = "agents:read"
+	PermAgentsWrite    Permission = "agents:write"
+	PermDocumentsRead  Permission = "documents:read"
+	PermDocumentsWrite Permission = "documents:write"
+	PermFAQRead        Permission = "faq:read"
+	PermFAQWrite       Permission = "faq:write"
+	PermUploadsRead    Permission = "uploads:read"
+	PermUploadsWrite   Permission = "uploads:write"
+	PermMembersManage  Permission = "members:manage"
+	PermUsersRead      Permission = "users:read"
+	PermUsersManage    Permission = "users:manage"
+)
+
+// AllPermissions 全部权限，按资源排列
+var AllPermissions = []Permission{
+	PermAgentsRead, PermAgentsWrite,
+	PermDocumentsRead, PermDocumentsWrite,
+	PermFAQRead, PermFAQWrite,
+	PermUploadsRead, PermUploadsWrite,
+	PermMembersManage,
+	PermUsersRead, PermUsersManage,
+}
+
+// APIKeyScopes 可授予API密钥的权限范围，成员与用户管理只能由登录用户操作
+var APIKeyScopes = []Permission{
+	PermAgentsRead, PermAgentsWrite,
+	PermDocumentsRead, PermDocumentsWrite,
+	PermFAQRead, PermFAQWrite,
+	PermUploadsRead, PermUploadsWrite,
+}
+
+// rolePermissions 角色权限矩阵
+var rolePermissions = map[uint8][]Permission{
+	models.RoleSuperAdmin: AllPermissions,
+	models.RoleOwner: {
+		PermAgentsRead, PermAgentsWrite,
+		PermDocumentsRead, PermDocumentsWrite,
+		PermFAQRead, PermFAQWrite,
+		PermUploadsRead, PermUploadsWrite,
+		PermMembersManage,
+	},
+	models.RoleEditor: {
+		PermAgentsRead,
+		PermDocumentsRead, PermDocumentsWrite,
+		PermFAQRead, PermFAQWrite,
+		PermUploadsRead, PermUploadsWrite,
+	},
+	models.RoleViewer: {
+		PermAgentsRead,
+		PermDocumentsRead,
+		PermFAQRead,
+		PermUploadsRead,
+	},
+}
+
+// RoleNames 角色名称
+var RoleNames = map[uint8]string{
+	models.RoleOwner:      "owner",
+	models.RoleSuperAdmin: "super_admin",
+	models.RoleEditor:     "editor",
+	models.RoleViewer:     "viewer",
+}
+
+// HasPermission 判断角色是否拥有指定权限
+func HasPermission(role uint8, permission Permission) bool {
+	for _, item := range rolePermissions[role] {
+		if item == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// RolePermissions 获取角色拥有的全部权限
+func RolePermissions(role uint8) []Permission {
+	permissions := rolePermissions[role]
+	if permissions == nil {
+		return []Permission{}
+	}
+	return permissions
+}
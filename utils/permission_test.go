@@ -0,0 +1,56 @@
+package utils
+
+import (
+	"testing"
+
+	"ai-assistant-backend/models"
+)
+
+func TestHasPermission(t *testing.T) {
+	tests := []struct {
+		role    uint8
+		granted []Permission
+	}{
+		{models.RoleSuperAdmin, AllPermissions},
+		{models.RoleOwner, []Permission{
+			PermAgentsRead, PermAgentsWrite,
+			PermDocumentsRead, PermDocumentsWrite,
+			PermFAQRead, PermFAQWrite,
+			PermUploadsRead, PermUploadsWrite,
+			PermMembersManage,
+		}},
+		{models.RoleEditor, []Permission{
+			PermAgentsRead,
+			PermDocumentsRead, PermDocumentsWrite,
+			PermFAQRead, PermFAQWrite,
+			PermUploadsRead, PermUploadsWrite,
+		}},
+		{models.RoleViewer, []Permission{PermAgentsRead, PermDocumentsRead, PermFAQRead, PermUploadsRead}},
+		// 未知角色没有任何权限
+		{4, nil},
+		{99, nil},
+	}
+	for _, tt := range tests {
+		granted := map[Permission]bool{}
+		for _, permission := range tt.granted {
+			granted[permission] = true
+		}
+		for _, permission := range AllPermissions {
+			if got := HasPermission(tt.role, permission); got != granted[permission] {
+				t.Errorf("HasPermission(%d, %s) = %v，期望 %v", tt.role, permission, got, granted[permission])
+			}
+		}
+		if HasPermission(tt.role, Permission("unknown:read")) {
+			t.Errorf("角色 %d 不应拥有未定义的权限", tt.role)
+		}
+	}
+}
+
+func TestAPIKeyScopesExcludeAccountManagement(t *testing.T) {
+	for _, scope := range APIKeyScopes {
+		switch scope {
+		case PermMembersManage, PermUsersRead, PermUsersManage:
+			t.Errorf("API密钥不应能授予 %s", scope)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"ai-assistant-backend/config"
+
+	"github.com/go-redis/redis/v8"
+)
+
+var (
+	// ErrRefreshTokenInvalid 刷新令牌不存在、已过期或会话已注销
+	ErrRefreshTokenInvalid = errors.New("刷新令牌无效或已过期，请重新登录")
+	// ErrRefreshTokenReused 已轮换的刷新令牌被再次使用，视为令牌泄露
+	ErrRefreshTokenReused = errors.New("刷新令牌已被使用，该登录会话已注销，请重新登录")
+)
+
+// refreshKey 刷新令牌摘要到会话ID的映射。轮换后旧令牌的映射仍保留至会话过期，用于识别重复使用
+func refreshKey(tokenHash string) string {
+	return fmt.Sprintf("refresh:%s", tokenHash)
+}
+
+// RotateRefreshToken 使用刷新令牌换取新的刷新令牌，旧令牌随即作废，会话有效期重新计算。
+// 已作废的令牌再次出现时注销整个会话（令牌家族），持有者需重新登录
+func RotateRefreshToken(refreshToken string) (*Session, string, error) {
+	if config.RedisClient == nil {
+		return nil, "", errors.New("Redis客户端未初始化")
+	}
+
+	ctx := context.Background()
+	tokenHash := HashToken(refreshToken)
+	sessionID, err := config.RedisClient.Get(ctx, refreshKey(tokenHash)).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, "", ErrRefreshTokenInvalid
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("读取刷新令牌失败: %v", err)
+	}
+
+	newToken, err := GenerateRandomString(64)
+	if err != nil {
+		return nil, "", fmt.Errorf("生成刷新令牌失败: %v", err)
+	}
+	newHash := HashToken(newToken)
+
+	session, err := updateSession(sessionID, func(session *Session) error {
+		if session.RefreshHash != tokenHash {
+			return ErrRefreshTokenReused
+		}
+		session.RefreshHash = newHash
+		session.ExpiresAt = time.Now().Add(RefreshTokenLifetime())
+		return nil
+	}, func(pipe redis.Pipeliner, session *Session, ttl time.Duration) {
+		pipe.Set(ctx, refreshKey(newHash), session.ID, ttl)
+		pipe.Expire(ctx, refreshKey(tokenHash), ttl)
+	})
+
+	switch {
+	case errors.Is(err, ErrRefreshTokenReused):
+		log.Printf("[session] 用户 %d 的会话 %s 检测到刷新令牌重复使用，已注销", session.UserID, session.ID)
+		RevokeSession(session.UserID, session.ID)
+		return nil, "", err
+	case errors.Is(err, ErrSessionNotFound), errors.Is(err, redis.TxFailedErr):
+		// 会话已注销，或同一令牌的并发刷新已被另一个请求完成
+		return nil, "", ErrRefreshTokenInvalid
+	case err != nil:
+		return nil, "", err
+	}
+
+	extendSessionIndex(ctx, session.UserID, time.Until(session.ExpiresAt))
+	return session, newToken, nil
+}
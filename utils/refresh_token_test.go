@@ -0,0 +1,112 @@
+package utils
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// createTestSession 创建测试会话，返回会话与首个刷新令牌
+func createTestSession(t *testing.T, userID uint) (*Session, string) {
+	t.Helper()
+	session, token, err := CreateSession(userID, 1, "Mozilla/5.0 (Windows NT 10.0) Chrome/120.0", "127.0.0.1")
+	if err != nil {
+		t.Fatalf("创建会话失败: %v", err)
+	}
+	return session, token
+}
+
+func TestRotateRefreshToken(t *testing.T) {
+	session, first := createTestSession(t, 1)
+
+	rotated, second, err := RotateRefreshToken(first)
+	if err != nil {
+		t.Fatalf("刷新失败: %v", err)
+	}
+	if second == "" || second == first {
+		t.Fatal("刷新后应返回新的刷新令牌")
+	}
+	if rotated.ID != session.ID {
+		t.Errorf("会话ID = %s，期望沿用 %s", rotated.ID, session.ID)
+	}
+	if rotated.RefreshHash != HashToken(second) {
+		t.Error("会话未记录新的刷新令牌")
+	}
+
+	// 新令牌可以继续轮换
+	if _, _, err := RotateRefreshToken(second); err != nil {
+		t.Fatalf("使用新令牌刷新失败: %v", err)
+	}
+	if _, _, err := RotateRefreshToken("unknown-token"); !errors.Is(err, ErrRefreshTokenInvalid) {
+		t.Errorf("未知令牌: err = %v，期望 %v", err, ErrRefreshTokenInvalid)
+	}
+}
+
+func TestRefreshTokenReuseRevokesSession(t *testing.T) {
+	session, first := createTestSession(t, 2)
+	other, otherToken := createTestSession(t, 2)
+
+	_, second, err := RotateRefreshToken(first)
+	if err != nil {
+		t.Fatalf("刷新失败: %v", err)
+	}
+
+	// 已轮换的令牌再次出现，视为泄露并注销整个会话
+	if _, _, err := RotateRefreshToken(first); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("重复使用旧令牌: err = %v，期望 %v", err, ErrRefreshTokenReused)
+	}
+	if _, err := GetSession(session.ID); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("会话未注销: err = %v", err)
+	}
+	// 同一家族中最新的令牌同样失效
+	if _, _, err := RotateRefreshToken(second); !errors.Is(err, ErrRefreshTokenInvalid) {
+		t.Errorf("注销后使用最新令牌: err = %v，期望 %v", err, ErrRefreshTokenInvalid)
+	}
+
+	// 同一用户的其他会话不受影响
+	sessions, err := ListSessions(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 1 || sessions[0].ID != other.ID {
+		t.Errorf("剩余会话 = %d，期望只保留 %s", len(sessions), other.ID)
+	}
+	if _, _, err := RotateRefreshToken(otherToken); err != nil {
+		t.Errorf("其他会话刷新失败: %v", err)
+	}
+}
+
+func TestRefreshTokenRevokedSession(t *testing.T) {
+	session, token := createTestSession(t, 3)
+	if err := RevokeSession(3, session.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := RotateRefreshToken(token); !errors.Is(err, ErrRefreshTokenInvalid) {
+		t.Errorf("已注销的会话: err = %v，期望 %v", err, ErrRefreshTokenInvalid)
+	}
+}
+
+func TestRefreshTokenExpiry(t *testing.T) {
+	lifetime := RefreshTokenLifetime()
+
+	_, expired := createTestSession(t, 4)
+	testRedis.FastForward(lifetime + time.Second)
+	if _, _, err := RotateRefreshToken(expired); !errors.Is(err, ErrRefreshTokenInvalid) {
+		t.Errorf("过期令牌: err = %v，期望 %v", err, ErrRefreshTokenInvalid)
+	}
+
+	// 每次轮换后有效期重新计算
+	session, token := createTestSession(t, 4)
+	testRedis.FastForward(lifetime / 2)
+	_, token, err := RotateRefreshToken(token)
+	if err != nil {
+		t.Fatalf("刷新失败: %v", err)
+	}
+	testRedis.FastForward(lifetime * 3 / 4)
+	if _, err := GetSession(session.ID); err != nil {
+		t.Fatalf("轮换后会话提前过期: %v", err)
+	}
+	if _, _, err := RotateRefreshToken(token); err != nil {
+		t.Errorf("有效期内刷新失败: %v", err)
+	}
+}
@@ -1,7 +1,10 @@
 package utils
 
 import (
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -73,6 +76,20 @@ func NotFound(c *gin.Context, message string) {
 	Error(c, http.StatusNotFound, message)
 }
 
+// TooManyRequests 429 错误响应，通过Retry-After头与retry_after字段告知需要等待的秒数
+func TooManyRequests(c *gin.Context, message string, retryAfter time.Duration) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.JSON(http.StatusTooManyRequests, Response{
+		Code:    http.StatusTooManyRequests,
+		Message: message,
+		Data:    gin.H{"retry_after": seconds},
+	})
+}
+
 // InternalServerError 500 错误响应
 func InternalServerError(c *gin.Context, message string) {
 	Error(c, http.StatusInternalServerError, message)
@@ -0,0 +1,346 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"ai-assistant-backend/config"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	// sessionTouchInterval 最后活跃时间的最小更新间隔，避免每个请求都写Redis
+	sessionTouchInterval = time.Minute
+
+	defaultAccessTokenLifetime  = 15 * time.Minute
+	defaultRefreshTokenLifetime = 30 * 24 * time.Hour
+)
+
+// ErrSessionNotFound 会话不存在、已过期或已被注销
+var ErrSessionNotFound = errors.New("会话已失效")
+
+// Session 登录会话，存储在Redis的 session:<会话ID> 中，
+// 同时在 user_sessions:<用户ID> 集合中按用户索引。
+// 一个会话即一个刷新令牌家族，RefreshHash为当前有效的刷新令牌摘要
+type Session struct {
+	ID          string    `json:"id"`
+	UserID      uint      `json:"user_id"`
+	WorkspaceID uint      `json:"workspace_id"` // 刷新令牌时沿用的工作空间
+	RefreshHash string    `json:"refresh_hash"`
+	UserAgent   string    `json:"user_agent"`
+	Device      string    `json:"device"`
+	IP          string    `json:"ip"`
+	CreatedAt   time.Time `json:"created_at"`
+	LastSeenAt  time.Time `json:"last_seen_at"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+func sessionKey(sessionID string) string {
+	return fmt.Sprintf("session:%s", sessionID)
+}
+
+func userSessionsKey(userID uint) string {
+	return fmt.Sprintf("user_sessions:%d", userID)
+}
+
+// AccessTokenLifetime 访问令牌有效时间
+func AccessTokenLifetime() time.Duration {
+	cfg := config.GlobalConfig.JWT
+	switch {
+	case cfg.AccessTokenMinutes > 0:
+		return time.Duration(cfg.AccessTokenMinutes) * time.Minute
+	case cfg.ExpireHours > 0:
+		return time.Duration(cfg.ExpireHours) * time.Hour
+	default:
+		return defaultAccessTokenLifetime
+	}
+}
+
+// RefreshTokenLifetime 刷新令牌有效时间，即会话在不活跃时的最长保留时间
+func RefreshTokenLifetime() time.Duration {
+	if days := config.GlobalConfig.JWT.RefreshTokenDays; days > 0 {
+		return time.Duration(days) * 24 * time.Hour
+	}
+	return defaultRefreshTokenLifetime
+}
+
+// CreateSession 创建登录会话，返回会话与首个刷新令牌
+func CreateSession(userID, workspaceID uint, userAgent, ip string) (*Session, string, error) {
+	sessionID, err := GenerateRandomString(32)
+	if err != nil {
+		return nil, "", fmt.Errorf("生成会话ID失败: %v", err)
+	}
+	refreshToken, err := GenerateRandomString(64)
+	if err != nil {
+		return nil, "", fmt.Errorf("生成刷新令牌失败: %v", err)
+	}
+
+	now := time.Now()
+	session := &Session{
+		ID:          sessionID,
+		UserID:      userID,
+		WorkspaceID: workspaceID,
+		RefreshHash: HashToken(refreshToken),
+		UserAgent:   userAgent,
+		Device:      DescribeUserAgent(userAgent),
+		IP:          ip,
+		CreatedAt:   now,
+		LastSeenAt:  now,
+		ExpiresAt:   now.Add(RefreshTokenLifetime()),
+	}
+	if err := saveSession(session); err != nil {
+		return nil, "", err
+	}
+
+	ctx := context.Background()
+	if err := config.RedisClient.Set(ctx, refreshKey(session.RefreshHash), session.ID, RefreshTokenLifetime()).Err(); err != nil {
+		return nil, "", fmt.Errorf("保存刷新令牌失败: %v", err)
+	}
+	return session, refreshToken, nil
+}
+
+// saveSession 写入会话并更新用户索引，索引的过期时间不短于该会话
+func saveSession(session *Session) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+
+	ttl := time.Until(session.ExpiresAt)
+	if ttl <= 0 {
+		return ErrSessionNotFound
+	}
+
+	ctx := context.Background()
+	pipe := config.RedisClient.TxPipeline()
+	pipe.Set(ctx, sessionKey(session.ID), data, ttl)
+	pipe.SAdd(ctx, userSessionsKey(session.UserID), session.ID)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("保存会话失败: %v", err)
+	}
+
+	extendSessionIndex(ctx, session.UserID, ttl)
+	return nil
+}
+
+// extendSessionIndex 用户索引的过期时间不短于其中任一会话
+func extendSessionIndex(ctx context.Context, userID uint, ttl time.Duration) {
+	indexKey := userSessionsKey(userID)
+	if indexTTL, err := config.RedisClient.TTL(ctx, indexKey).Result(); err == nil && indexTTL < ttl {
+		config.RedisClient.Expire(ctx, indexKey, ttl)
+	}
+}
+
+// GetSession 获取会话
+func GetSession(sessionID string) (*Session, error) {
+	if config.RedisClient == nil {
+		return nil, errors.New("Redis客户端未初始化")
+	}
+	if sessionID == "" {
+		return nil, ErrSessionNotFound
+	}
+
+	data, err := config.RedisClient.Get(context.Background(), sessionKey(sessionID)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取会话失败: %v", err)
+	}
+
+	var session Session
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, fmt.Errorf("解析会话失败: %v", err)
+	}
+	return &session, nil
+}
+
+// ValidateSession 校验令牌对应的会话仍然有效且属于该用户
+func ValidateSession(claims *Claims) (*Session, error) {
+	session, err := GetSession(claims.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session.UserID != claims.UserID {
+		return nil, ErrSessionNotFound
+	}
+	return session, nil
+}
+
+// updateSession 在乐观锁事务中读取、修改并写回会话，避免并发请求互相覆盖（如刷新令牌与更新活跃时间）。
+// extra可在同一事务中写入其他键
+func updateSession(sessionID string, update func(session *Session) error, extra func(pipe redis.Pipeliner, session *Session, ttl time.Duration)) (*Session, error) {
+	ctx := context.Background()
+	key := sessionKey(sessionID)
+
+	var session *Session
+	err := config.RedisClient.Watch(ctx, func(tx *redis.Tx) error {
+		data, err := tx.Get(ctx, key).Bytes()
+		if errors.Is(err, redis.Nil) {
+			return ErrSessionNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("读取会话失败: %v", err)
+		}
+
+		session = &Session{}
+		if err := json.Unmarshal(data, session); err != nil {
+			return fmt.Errorf("解析会话失败: %v", err)
+		}
+		if err := update(session); err != nil {
+			return err
+		}
+
+		ttl := time.Until(session.ExpiresAt)
+		if ttl <= 0 {
+			return ErrSessionNotFound
+		}
+		if data, err = json.Marshal(session); err != nil {
+			return err
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, key, data, ttl)
+			if extra != nil {
+				extra(pipe, session, ttl)
+			}
+			return nil
+		})
+		return err
+	}, key)
+	if err != nil {
+		return session, err
+	}
+	return session, nil
+}
+
+// TouchSession 记录会话的最后活跃时间与IP，并发更新冲突时忽略本次记录
+func TouchSession(session *Session, ip string) {
+	if time.Since(session.LastSeenAt) < sessionTouchInterval && session.IP == ip {
+		return
+	}
+	updateSession(session.ID, func(session *Session) error {
+		session.LastSeenAt = time.Now()
+		session.IP = ip
+		return nil
+	}, nil)
+}
+
+// SetSessionWorkspace 记录会话当前的工作空间，之后刷新的访问令牌沿用该工作空间
+func SetSessionWorkspace(sessionID string, workspaceID uint) error {
+	_, err := updateSession(sessionID, func(session *Session) error {
+		session.WorkspaceID = workspaceID
+		return nil
+	}, nil)
+	return err
+}
+
+// ListSessions 获取用户的有效会话，按最后活跃时间倒序，顺带清理索引中已过期的会话
+func ListSessions(userID uint) ([]*Session, error) {
+	ctx := context.Background()
+	indexKey := userSessionsKey(userID)
+	sessionIDs, err := config.RedisClient.SMembers(ctx, indexKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("读取会话列表失败: %v", err)
+	}
+
+	sessions := []*Session{}
+	for _, sessionID := range sessionIDs {
+		session, err := GetSession(sessionID)
+		if errors.Is(err, ErrSessionNotFound) {
+			config.RedisClient.SRem(ctx, indexKey, sessionID)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastSeenAt.After(sessions[j].LastSeenAt)
+	})
+	return sessions, nil
+}
+
+// RevokeSession 注销用户的指定会话
+func RevokeSession(userID uint, sessionID string) error {
+	ctx := context.Background()
+	pipe := config.RedisClient.TxPipeline()
+	pipe.Del(ctx, sessionKey(sessionID))
+	pipe.SRem(ctx, userSessionsKey(userID), sessionID)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("注销会话失败: %v", err)
+	}
+	return nil
+}
+
+// RevokeUserSessions 注销用户的全部会话，exceptSessionID不为空时保留该会话，返回注销的数量
+func RevokeUserSessions(userID uint, exceptSessionID string) (int, error) {
+	ctx := context.Background()
+	sessionIDs, err := config.RedisClient.SMembers(ctx, userSessionsKey(userID)).Result()
+	if err != nil {
+		return 0, fmt.Errorf("读取会话列表失败: %v", err)
+	}
+
+	count := 0
+	for _, sessionID := range sessionIDs {
+		if sessionID == exceptSessionID {
+			continue
+		}
+		if err := RevokeSession(userID, sessionID); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+// DescribeUserAgent 从User-Agent中识别浏览器与操作系统，用于会话列表展示
+func DescribeUserAgent(userAgent string) string {
+	if userAgent == "" {
+		return "未知设备"
+	}
+
+	browser := "未知浏览器"
+	for _, item := range []struct{ token, name string }{
+		{"MicroMessenger", "微信"},
+		{"Edg/", "Edge"},
+		{"OPR/", "Opera"},
+		{"Firefox/", "Firefox"},
+		{"Chrome/", "Chrome"},
+		{"Safari/", "Safari"},
+		{"curl/", "curl"},
+		{"PostmanRuntime", "Postman"},
+	} {
+		if strings.Contains(userAgent, item.token) {
+			browser = item.name
+			break
+		}
+	}
+
+	system := ""
+	for _, item := range []struct{ token, name string }{
+		{"iPhone", "iOS"},
+		{"iPad", "iPadOS"},
+		{"Android", "Android"},
+		{"Windows", "Windows"},
+		{"Mac OS X", "macOS"},
+		{"Linux", "Linux"},
+	} {
+		if strings.Contains(userAgent, item.token) {
+			system = item.name
+			break
+		}
+	}
+
+	if system == "" {
+		return browser
+	}
+	return browser + " / " + system
+}
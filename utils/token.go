@@ -0,0 +1,41 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"ai-assistant-backend/config"
+)
+
+// GenerateSignedToken 生成带签名的一次性令牌，格式为 随机串.签名。
+// purpose区分令牌用途，避免一种用途的令牌被用于另一种用途
+func GenerateSignedToken(purpose string) (string, error) {
+	random, err := GenerateRandomString(48)
+	if err != nil {
+		return "", err
+	}
+	return random + "." + signToken(purpose, random), nil
+}
+
+// VerifySignedToken 校验令牌签名，签名不匹配的令牌无需查询数据库即可拒绝
+func VerifySignedToken(purpose, token string) bool {
+	random, signature, ok := strings.Cut(token, ".")
+	if !ok || random == "" {
+		return false
+	}
+	return hmac.Equal([]byte(signature), []byte(signToken(purpose, random)))
+}
+
+// HashToken 计算令牌的SHA-256摘要，数据库只保存摘要
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func signToken(purpose, random string) string {
+	mac := hmac.New(sha256.New, []byte(config.GlobalConfig.JWT.Secret))
+	mac.Write([]byte(purpose + ":" + random))
+	return hex.EncodeToString(mac.Sum(nil))
+}
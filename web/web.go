@@ -0,0 +1,9 @@
+// Package web 嵌入到程序中的对话窗口脚本与托管对话页
+package web
+
+import "embed"
+
+// Assets widget.js 为客户网站引入的悬浮对话窗口脚本，chat.html 为托管对话页模板
+//
+//go:embed widget.js chat.html
+var Assets embed.FS
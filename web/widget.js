@@ -0,0 +1,103 @@
+/*
+ * 智能体悬浮对话窗口
+ *
+ * <script src="https://api.example.com/widget.js" data-app-id="zhaosheng" async></script>
+ *
+ * 读取智能体的公开配置后在页面角落显示悬浮按钮，点击后以iframe打开托管对话页。
+ * 智能体已下线或当前网站未被允许接入时不显示。
+ */
+(function () {
+  'use strict';
+
+  var script = document.currentScript;
+  if (!script) {
+    return;
+  }
+  var appId = script.getAttribute('data-app-id');
+  if (!appId || window.__aiAgentWidgets && window.__aiAgentWidgets[appId]) {
+    return;
+  }
+  window.__aiAgentWidgets = window.__aiAgentWidgets || {};
+  window.__aiAgentWidgets[appId] = true;
+
+  var base = script.src.replace(/\/widget\.js(\?.*)?$/, '');
+  var configURL = base + '/api/public/agents/' + encodeURIComponent(appId) + '/config';
+
+  var chatIcon = '<svg viewBox="0 0 24 24" width="28" height="28" fill="currentColor" aria-hidden="true">' +
+    '<path d="M12 3C6.5 3 2 6.9 2 11.6c0 2.6 1.4 5 3.7 6.6L5 22l4.2-2.3c.9.2 1.8.3 2.8.3 5.5 0 10-3.9 10-8.6S17.5 3 12 3z"/></svg>';
+  var closeIcon = '<svg viewBox="0 0 24 24" width="24" height="24" fill="currentColor" aria-hidden="true">' +
+    '<path d="M18.3 5.7a1 1 0 0 0-1.4 0L12 10.6 7.1 5.7a1 1 0 0 0-1.4 1.4l4.9 4.9-4.9 4.9a1 1 0 1 0 1.4 1.4l4.9-4.9 4.9 4.9a1 1 0 0 0 1.4-1.4L13.4 12l4.9-4.9a1 1 0 0 0 0-1.4z"/></svg>';
+
+  function mount(config) {
+    var side = config.widget_position === 'bottom-left' ? 'left' : 'right';
+    var prefix = 'ai-agent-widget-' + appId.replace(/[^a-zA-Z0-9_-]/g, '');
+
+    var style = document.createElement('style');
+    style.textContent =
+      '.' + prefix + '-button{position:fixed;bottom:24px;' + side + ':24px;z-index:2147483000;width:56px;height:56px;' +
+      'border:none;border-radius:50%;cursor:pointer;display:flex;align-items:center;justify-content:center;' +
+      'box-shadow:0 4px 16px rgba(0,0,0,.2);transition:transform .2s;}' +
+      '.' + prefix + '-button:hover{transform:scale(1.06);}' +
+      '.' + prefix + '-frame{position:fixed;bottom:92px;' + side + ':24px;z-index:2147483000;width:380px;height:600px;' +
+      'max-height:calc(100vh - 116px);border:none;border-radius:12px;background:#fff;' +
+      'box-shadow:0 8px 32px rgba(0,0,0,.2);display:none;}' +
+      '.' + prefix + '-frame.open{display:block;}' +
+      '@media (max-width:480px){.' + prefix + '-frame{bottom:0;' + side + ':0;width:100%;height:100%;max-height:none;border-radius:0;}}';
+    document.head.appendChild(style);
+
+    var button = document.createElement('button');
+    button.type = 'button';
+    button.className = prefix + '-button';
+    button.style.background = config.theme_color;
+    button.style.color = config.theme_text_color;
+    button.setAttribute('aria-label', config.name);
+    button.innerHTML = chatIcon;
+
+    var frame = null;
+    var opened = false;
+
+    function toggle() {
+      if (!frame) {
+        frame = document.createElement('iframe');
+        frame.className = prefix + '-frame';
+        frame.title = config.name;
+        frame.src = base + '/chat/' + encodeURIComponent(appId) + '?embed=1';
+        document.body.appendChild(frame);
+      }
+      opened = !opened;
+      frame.className = prefix + '-frame' + (opened ? ' open' : '');
+      button.innerHTML = opened ? closeIcon : chatIcon;
+    }
+
+    button.addEventListener('click', toggle);
+
+    // 对话页中点击关闭按钮时收起窗口
+    window.addEventListener('message', function (event) {
+      if (frame && opened && event.source === frame.contentWindow &&
+          event.data && event.data.type === 'ai-agent-widget:close') {
+        toggle();
+      }
+    });
+
+    document.body.appendChild(button);
+  }
+
+  function load() {
+    fetch(configURL)
+      .then(function (response) {
+        return response.ok ? response.json() : null;
+      })
+      .then(function (body) {
+        if (body && body.code === 200) {
+          mount(body.data);
+        }
+      })
+      .catch(function () {});
+  }
+
+  if (document.readyState === 'loading') {
+    document.addEventListener('DOMContentLoaded', load);
+  } else {
+    load();
+  }
+})();